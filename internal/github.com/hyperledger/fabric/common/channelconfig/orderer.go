@@ -21,6 +21,7 @@ import (
 	ab "github.com/hyperledger/fabric-protos-go/orderer"
 	"github.com/pkg/errors"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/capabilities"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/protos/bft"
 )
 
 const (
@@ -44,6 +45,9 @@ const (
 	// KafkaBrokersKey is the cb.ConfigItem type key name for the KafkaBrokers message.
 	KafkaBrokersKey = "KafkaBrokers"
 
+	// OrderersKey is the cb.ConfigItem type key name for the Orderers message of BFT ordering services.
+	OrderersKey = "Orderers"
+
 	// EndpointsKey is the cb.COnfigValue key name for the Endpoints message in the OrdererOrgGroup.
 	EndpointsKey = "Endpoints"
 )
@@ -56,6 +60,7 @@ type OrdererProtos struct {
 	KafkaBrokers        *ab.KafkaBrokers
 	ChannelRestrictions *ab.ChannelRestrictions
 	Capabilities        *cb.Capabilities
+	Orderers            *bft.Orderers
 }
 
 // OrdererConfig holds the orderer configuration information.
@@ -183,6 +188,11 @@ func (oc *OrdererConfig) MaxChannelsCount() uint64 {
 	return oc.protos.ChannelRestrictions.MaxCount
 }
 
+// Consenters returns the consenter mapping of a BFT ordering service, or nil for other consensus types.
+func (oc *OrdererConfig) Consenters() []*bft.Consenter {
+	return oc.protos.Orderers.GetConsenterMapping()
+}
+
 // Organizations returns a map of the orgs in the channel.
 func (oc *OrdererConfig) Organizations() map[string]OrdererOrg {
 	return oc.orgs
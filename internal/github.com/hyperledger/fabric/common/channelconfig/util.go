@@ -21,6 +21,7 @@ import (
 	ab "github.com/hyperledger/fabric-protos-go/orderer"
 	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/protos/bft"
 )
 
 const (
@@ -119,6 +120,17 @@ func ConsensusTypeValue(consensusType string, consensusMetadata []byte) *Standar
 	}
 }
 
+// OrderersValue returns the config definition for the consenter mapping of a BFT ordering service.
+// It is a value for the /Channel/Orderer group.
+func OrderersValue(consenters []*bft.Consenter) *StandardConfigValue {
+	return &StandardConfigValue{
+		key: OrderersKey,
+		value: &bft.Orderers{
+			ConsenterMapping: consenters,
+		},
+	}
+}
+
 // BatchSizeValue returns the config definition for the orderer batch size.
 // It is a value for the /Channel/Orderer group.
 func BatchSizeValue(maxMessages, absoluteMaxBytes, preferredMaxBytes uint32) *StandardConfigValue {
@@ -260,3 +272,11 @@ func MarshalEtcdRaftMetadata(md *etcdraft.ConfigMetadata) ([]byte, error) {
 	}
 	return proto.Marshal(copyMd)
 }
+
+// MarshalBFTOptions serializes the SmartBFT options, which are set as the consensus metadata of BFT ordering services
+func MarshalBFTOptions(op *bft.Options) ([]byte, error) {
+	if op == nil {
+		return nil, fmt.Errorf("consenter options are missing")
+	}
+	return proto.Marshal(op)
+}
@@ -39,12 +39,12 @@ func dynamicFrom(dynamicMsg func(underlying proto.Message) (proto.Message, error
 	return uMsg, nil
 }
 
-func dynamicTo(dynamicMsg func(underlying proto.Message) (proto.Message, error), value reflect.Value) (interface{}, error) {
+func dynamicTo(dynamicMsg func(underlying proto.Message) (proto.Message, error), value reflect.Value, opts *marshalOptions) (interface{}, error) {
 	nMsg, err := dynamicMsg(value.Interface().(proto.Message)) // Safe, already checked
 	if err != nil {
 		return nil, err
 	}
-	return recursivelyCreateTreeFromMessage(nMsg)
+	return recursivelyCreateTreeFromMessage(nMsg, opts)
 }
 
 type dynamicFieldFactory struct{}
@@ -74,10 +74,10 @@ func (dff dynamicFieldFactory) NewProtoField(msg proto.Message, fieldName string
 				return dynamicProto.DynamicFieldProto(fieldName, underlying)
 			}, v, dT)
 		},
-		populateTo: func(v reflect.Value) (interface{}, error) {
+		populateTo: func(v reflect.Value, opts *marshalOptions) (interface{}, error) {
 			return dynamicTo(func(underlying proto.Message) (proto.Message, error) {
 				return dynamicProto.DynamicFieldProto(fieldName, underlying)
-			}, v)
+			}, v, opts)
 		},
 	}, nil
 }
@@ -109,10 +109,10 @@ func (dmff dynamicMapFieldFactory) NewProtoField(msg proto.Message, fieldName st
 				return dynamicProto.DynamicMapFieldProto(fieldName, k, underlying)
 			}, v, dT)
 		},
-		populateTo: func(k string, v reflect.Value) (interface{}, error) {
+		populateTo: func(k string, v reflect.Value, opts *marshalOptions) (interface{}, error) {
 			return dynamicTo(func(underlying proto.Message) (proto.Message, error) {
 				return dynamicProto.DynamicMapFieldProto(fieldName, k, underlying)
-			}, v)
+			}, v, opts)
 		},
 	}, nil
 }
@@ -144,10 +144,10 @@ func (dmff dynamicSliceFieldFactory) NewProtoField(msg proto.Message, fieldName
 				return dynamicProto.DynamicSliceFieldProto(fieldName, i, underlying)
 			}, v, dT)
 		},
-		populateTo: func(i int, v reflect.Value) (interface{}, error) {
+		populateTo: func(i int, v reflect.Value, opts *marshalOptions) (interface{}, error) {
 			return dynamicTo(func(underlying proto.Message) (proto.Message, error) {
 				return dynamicProto.DynamicSliceFieldProto(fieldName, i, underlying)
-			}, v)
+			}, v, opts)
 		},
 	}, nil
 }
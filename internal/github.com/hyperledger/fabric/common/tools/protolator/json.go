@@ -61,13 +61,23 @@ type protoField interface {
 	// PopulateTo does not mutate the underlying object, but instead converts it
 	// into the intermediate JSON representation (ie a struct -> map[string]interface{}
 	// or a slice of structs to []map[string]interface{}
-	PopulateTo() (interface{}, error)
+	PopulateTo(opts *marshalOptions) (interface{}, error)
+}
+
+// BytesFilter is applied by DeepMarshalJSONWithBytesFilter to the value of every bytes field which is not
+// expanded into a nested message. If it returns true, the replacement is marshaled instead of the base64
+// encoding of the value.
+type BytesFilter func(value []byte) (replacement interface{}, ok bool)
+
+type marshalOptions struct {
+	bytesFilter BytesFilter
 }
 
 var (
 	protoMsgType           = reflect.TypeOf((*proto.Message)(nil)).Elem()
 	mapStringInterfaceType = reflect.TypeOf(map[string]interface{}{})
 	bytesType              = reflect.TypeOf([]byte{})
+	bytesSliceType         = reflect.TypeOf([][]byte{})
 )
 
 type baseField struct {
@@ -85,7 +95,7 @@ func (bf *baseField) Name() string {
 type plainField struct {
 	baseField
 	populateFrom func(source interface{}, destType reflect.Type) (reflect.Value, error)
-	populateTo   func(source reflect.Value) (interface{}, error)
+	populateTo   func(source reflect.Value, opts *marshalOptions) (interface{}, error)
 }
 
 func (pf *plainField) PopulateFrom(source interface{}) error {
@@ -104,7 +114,7 @@ func (pf *plainField) PopulateFrom(source interface{}) error {
 	return nil
 }
 
-func (pf *plainField) PopulateTo() (interface{}, error) {
+func (pf *plainField) PopulateTo(opts *marshalOptions) (interface{}, error) {
 	if !pf.value.Type().AssignableTo(pf.vType) {
 		return nil, fmt.Errorf("expected field %s for message %T to be assignable to %v but was not. Got %T.", pf.name, pf.msg, pf.fType, pf.value)
 	}
@@ -116,7 +126,7 @@ func (pf *plainField) PopulateTo() (interface{}, error) {
 		return nil, nil
 	}
 
-	value, err := pf.populateTo(pf.value)
+	value, err := pf.populateTo(pf.value, opts)
 	if err != nil {
 		return nil, fmt.Errorf("error in PopulateTo for field %s for message %T: %s", pf.name, pf.msg, err)
 	}
@@ -126,7 +136,7 @@ func (pf *plainField) PopulateTo() (interface{}, error) {
 type mapField struct {
 	baseField
 	populateFrom func(key string, value interface{}, destType reflect.Type) (reflect.Value, error)
-	populateTo   func(key string, value reflect.Value) (interface{}, error)
+	populateTo   func(key string, value reflect.Value, opts *marshalOptions) (interface{}, error)
 }
 
 func (mf *mapField) PopulateFrom(source interface{}) error {
@@ -152,7 +162,7 @@ func (mf *mapField) PopulateFrom(source interface{}) error {
 	return nil
 }
 
-func (mf *mapField) PopulateTo() (interface{}, error) {
+func (mf *mapField) PopulateTo(opts *marshalOptions) (interface{}, error) {
 	result := make(map[string]interface{})
 	keys := mf.value.MapKeys()
 	for _, key := range keys {
@@ -171,7 +181,7 @@ func (mf *mapField) PopulateTo() (interface{}, error) {
 			return nil, fmt.Errorf("expected map field %s with key %s for message %T to be assignable to %v but was not. Got %v.", mf.name, k, mf.msg, mf.vType.Elem(), subValue.Type())
 		}
 
-		value, err := mf.populateTo(k, subValue)
+		value, err := mf.populateTo(k, subValue, opts)
 		if err != nil {
 			return nil, fmt.Errorf("error in PopulateTo for map field %s and key %s for message %T: %s", mf.name, k, mf.msg, err)
 		}
@@ -183,7 +193,7 @@ func (mf *mapField) PopulateTo() (interface{}, error) {
 
 type sliceField struct {
 	baseField
-	populateTo   func(i int, source reflect.Value) (interface{}, error)
+	populateTo   func(i int, source reflect.Value, opts *marshalOptions) (interface{}, error)
 	populateFrom func(i int, source interface{}, destType reflect.Type) (reflect.Value, error)
 }
 
@@ -210,7 +220,7 @@ func (sf *sliceField) PopulateFrom(source interface{}) error {
 	return nil
 }
 
-func (sf *sliceField) PopulateTo() (interface{}, error) {
+func (sf *sliceField) PopulateTo(opts *marshalOptions) (interface{}, error) {
 	result := make([]interface{}, sf.value.Len())
 	for i := range result {
 		subValue := sf.value.Index(i)
@@ -223,7 +233,7 @@ func (sf *sliceField) PopulateTo() (interface{}, error) {
 			return nil, fmt.Errorf("expected slice field %s at index %d for message %T to be assignable to %v but was not. Got %v.", sf.name, i, sf.msg, sf.vType.Elem(), subValue.Type())
 		}
 
-		value, err := sf.populateTo(i, subValue)
+		value, err := sf.populateTo(i, subValue, opts)
 		if err != nil {
 			return nil, fmt.Errorf("error in PopulateTo for slice field %s at index %d for message %T: %s", sf.name, i, sf.msg, err)
 		}
@@ -355,7 +365,7 @@ func protoFields(msg proto.Message, uMsg proto.Message) ([]protoField, error) {
 	return result, nil
 }
 
-func recursivelyCreateTreeFromMessage(msg proto.Message) (tree map[string]interface{}, err error) {
+func recursivelyCreateTreeFromMessage(msg proto.Message, opts *marshalOptions) (tree map[string]interface{}, err error) {
 	defer func() {
 		// Because this function is recursive, it's difficult to determine which level
 		// of the proto the error originated from, this wrapper leaves breadcrumbs for debugging
@@ -385,12 +395,16 @@ func recursivelyCreateTreeFromMessage(msg proto.Message) (tree map[string]interf
 		return nil, err
 	}
 
+	if opts != nil && opts.bytesFilter != nil {
+		filterBytesFields(tree, uMsg, fields, opts.bytesFilter)
+	}
+
 	for _, field := range fields {
 		if _, ok := tree[field.Name()]; !ok {
 			continue
 		}
 		delete(tree, field.Name())
-		tree[field.Name()], err = field.PopulateTo()
+		tree[field.Name()], err = field.PopulateTo(opts)
 		if err != nil {
 			return nil, err
 		}
@@ -404,7 +418,14 @@ func recursivelyCreateTreeFromMessage(msg proto.Message) (tree map[string]interf
 // as the JSON representation of those messages.  This is done so that the JSON representation is as non-binary
 // and human readable as possible.
 func DeepMarshalJSON(w io.Writer, msg proto.Message) error {
-	root, err := recursivelyCreateTreeFromMessage(msg)
+	return DeepMarshalJSONWithBytesFilter(w, msg, nil)
+}
+
+// DeepMarshalJSONWithBytesFilter marshals msg to w as DeepMarshalJSON does, but the bytes fields which are
+// not expanded into nested messages are passed through the filter, e.g. to truncate large values. Unlike
+// the output of DeepMarshalJSON, the output may not be unmarshaled again if the filter replaced any value.
+func DeepMarshalJSONWithBytesFilter(w io.Writer, msg proto.Message, filter BytesFilter) error {
+	root, err := recursivelyCreateTreeFromMessage(msg, &marshalOptions{bytesFilter: filter})
 	if err != nil {
 		return err
 	}
@@ -414,6 +435,43 @@ func DeepMarshalJSON(w io.Writer, msg proto.Message) error {
 	return encoder.Encode(root)
 }
 
+// filterBytesFields replaces the values of the plain bytes fields of the message in the tree by the result
+// of the filter. Special fields are skipped, as they are replaced by their nested messages.
+func filterBytesFields(tree map[string]interface{}, uMsg proto.Message, fields []protoField, filter BytesFilter) {
+	mVal := reflect.ValueOf(uMsg).Elem()
+	for _, prop := range proto.GetProperties(mVal.Type()).Prop {
+		if _, ok := tree[prop.OrigName]; !ok || isSpecialField(prop.OrigName, fields) {
+			continue
+		}
+
+		switch fieldValue := mVal.FieldByName(prop.Name); fieldValue.Type() {
+		case bytesType:
+			if replacement, ok := filter(fieldValue.Bytes()); ok {
+				tree[prop.OrigName] = replacement
+			}
+		case bytesSliceType:
+			values, ok := tree[prop.OrigName].([]interface{})
+			if !ok {
+				continue
+			}
+			for i, value := range fieldValue.Interface().([][]byte) {
+				if replacement, ok := filter(value); ok && i < len(values) {
+					values[i] = replacement
+				}
+			}
+		}
+	}
+}
+
+func isSpecialField(name string, fields []protoField) bool {
+	for _, field := range fields {
+		if field.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
 func recursivelyPopulateMessageFromTree(tree map[string]interface{}, msg proto.Message) (err error) {
 	defer func() {
 		// Because this function is recursive, it's difficult to determine which level
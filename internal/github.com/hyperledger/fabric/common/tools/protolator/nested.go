@@ -37,9 +37,9 @@ func nestedFrom(value interface{}, destType reflect.Type) (reflect.Value, error)
 	return result, nil
 }
 
-func nestedTo(value reflect.Value) (interface{}, error) {
+func nestedTo(value reflect.Value, opts *marshalOptions) (interface{}, error) {
 	nMsg := value.Interface().(proto.Message) // Safe, already checked
-	return recursivelyCreateTreeFromMessage(nMsg)
+	return recursivelyCreateTreeFromMessage(nMsg, opts)
 }
 
 var timestampType = reflect.TypeOf(&timestamp.Timestamp{})
@@ -84,8 +84,8 @@ func (nmff nestedMapFieldFactory) NewProtoField(msg proto.Message, fieldName str
 		populateFrom: func(k string, v interface{}, dT reflect.Type) (reflect.Value, error) {
 			return nestedFrom(v, dT)
 		},
-		populateTo: func(k string, v reflect.Value) (interface{}, error) {
-			return nestedTo(v)
+		populateTo: func(k string, v reflect.Value, opts *marshalOptions) (interface{}, error) {
+			return nestedTo(v, opts)
 		},
 	}, nil
 }
@@ -108,8 +108,8 @@ func (nmff nestedSliceFieldFactory) NewProtoField(msg proto.Message, fieldName s
 		populateFrom: func(i int, v interface{}, dT reflect.Type) (reflect.Value, error) {
 			return nestedFrom(v, dT)
 		},
-		populateTo: func(i int, v reflect.Value) (interface{}, error) {
-			return nestedTo(v)
+		populateTo: func(i int, v reflect.Value, opts *marshalOptions) (interface{}, error) {
+			return nestedTo(v, opts)
 		},
 	}, nil
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric-protos-go/orderer"
 	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/protos/bft"
 )
 
 type DynamicOrdererGroup struct {
@@ -84,6 +85,8 @@ func (ct *ConsensusType) VariablyOpaqueFieldProto(name string) (proto.Message, e
 	switch ct.Type {
 	case "etcdraft":
 		return &etcdraft.ConfigMetadata{}, nil
+	case "BFT":
+		return &bft.Options{}, nil
 	default:
 		return &empty.Empty{}, nil
 	}
@@ -150,6 +153,8 @@ func (docv *DynamicOrdererConfigValue) StaticallyOpaqueFieldProto(name string) (
 		return &orderer.ChannelRestrictions{}, nil
 	case "Capabilities":
 		return &common.Capabilities{}, nil
+	case "Orderers":
+		return &bft.Orderers{}, nil
 	default:
 		return nil, fmt.Errorf("unknown Orderer ConfigValue name: %s", docv.name)
 	}
@@ -42,7 +42,7 @@ func opaqueFrom(opaqueType func() (proto.Message, error), value interface{}, des
 	return reflect.ValueOf(mMsg), nil
 }
 
-func opaqueTo(opaqueType func() (proto.Message, error), value reflect.Value) (interface{}, error) {
+func opaqueTo(opaqueType func() (proto.Message, error), value reflect.Value, opts *marshalOptions) (interface{}, error) {
 	nMsg, err := opaqueType()
 	if err != nil {
 		return nil, err
@@ -51,7 +51,7 @@ func opaqueTo(opaqueType func() (proto.Message, error), value reflect.Value) (in
 	if err = proto.Unmarshal(mMsg, nMsg); err != nil {
 		return nil, err
 	}
-	return recursivelyCreateTreeFromMessage(nMsg)
+	return recursivelyCreateTreeFromMessage(nMsg, opts)
 }
 
 type staticallyOpaqueFieldFactory struct{}
@@ -79,8 +79,8 @@ func (soff staticallyOpaqueFieldFactory) NewProtoField(msg proto.Message, fieldN
 		populateFrom: func(v interface{}, dT reflect.Type) (reflect.Value, error) {
 			return opaqueFrom(func() (proto.Message, error) { return opaqueProto.StaticallyOpaqueFieldProto(fieldName) }, v, dT)
 		},
-		populateTo: func(v reflect.Value) (interface{}, error) {
-			return opaqueTo(func() (proto.Message, error) { return opaqueProto.StaticallyOpaqueFieldProto(fieldName) }, v)
+		populateTo: func(v reflect.Value, opts *marshalOptions) (interface{}, error) {
+			return opaqueTo(func() (proto.Message, error) { return opaqueProto.StaticallyOpaqueFieldProto(fieldName) }, v, opts)
 		},
 	}, nil
 }
@@ -112,10 +112,10 @@ func (soff staticallyOpaqueMapFieldFactory) NewProtoField(msg proto.Message, fie
 				return opaqueProto.StaticallyOpaqueMapFieldProto(fieldName, key)
 			}, v, dT)
 		},
-		populateTo: func(key string, v reflect.Value) (interface{}, error) {
+		populateTo: func(key string, v reflect.Value, opts *marshalOptions) (interface{}, error) {
 			return opaqueTo(func() (proto.Message, error) {
 				return opaqueProto.StaticallyOpaqueMapFieldProto(fieldName, key)
-			}, v)
+			}, v, opts)
 		},
 	}, nil
 }
@@ -147,10 +147,10 @@ func (soff staticallyOpaqueSliceFieldFactory) NewProtoField(msg proto.Message, f
 				return opaqueProto.StaticallyOpaqueSliceFieldProto(fieldName, index)
 			}, v, dT)
 		},
-		populateTo: func(index int, v reflect.Value) (interface{}, error) {
+		populateTo: func(index int, v reflect.Value, opts *marshalOptions) (interface{}, error) {
 			return opaqueTo(func() (proto.Message, error) {
 				return opaqueProto.StaticallyOpaqueSliceFieldProto(fieldName, index)
-			}, v)
+			}, v, opts)
 		},
 	}, nil
 }
@@ -51,8 +51,8 @@ func (soff variablyOpaqueFieldFactory) NewProtoField(msg proto.Message, fieldNam
 		populateFrom: func(v interface{}, dT reflect.Type) (reflect.Value, error) {
 			return opaqueFrom(func() (proto.Message, error) { return opaqueProto.VariablyOpaqueFieldProto(fieldName) }, v, dT)
 		},
-		populateTo: func(v reflect.Value) (interface{}, error) {
-			return opaqueTo(func() (proto.Message, error) { return opaqueProto.VariablyOpaqueFieldProto(fieldName) }, v)
+		populateTo: func(v reflect.Value, opts *marshalOptions) (interface{}, error) {
+			return opaqueTo(func() (proto.Message, error) { return opaqueProto.VariablyOpaqueFieldProto(fieldName) }, v, opts)
 		},
 	}, nil
 }
@@ -84,10 +84,10 @@ func (soff variablyOpaqueMapFieldFactory) NewProtoField(msg proto.Message, field
 				return opaqueProto.VariablyOpaqueMapFieldProto(fieldName, key)
 			}, v, dT)
 		},
-		populateTo: func(key string, v reflect.Value) (interface{}, error) {
+		populateTo: func(key string, v reflect.Value, opts *marshalOptions) (interface{}, error) {
 			return opaqueTo(func() (proto.Message, error) {
 				return opaqueProto.VariablyOpaqueMapFieldProto(fieldName, key)
-			}, v)
+			}, v, opts)
 		},
 	}, nil
 }
@@ -119,10 +119,10 @@ func (soff variablyOpaqueSliceFieldFactory) NewProtoField(msg proto.Message, fie
 				return opaqueProto.VariablyOpaqueSliceFieldProto(fieldName, index)
 			}, v, dT)
 		},
-		populateTo: func(index int, v reflect.Value) (interface{}, error) {
+		populateTo: func(index int, v reflect.Value, opts *marshalOptions) (interface{}, error) {
 			return opaqueTo(func() (proto.Message, error) {
 				return opaqueProto.VariablyOpaqueSliceFieldProto(fieldName, index)
-			}, v)
+			}, v, opts)
 		},
 	}, nil
 }
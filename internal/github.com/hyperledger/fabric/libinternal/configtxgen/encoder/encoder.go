@@ -11,8 +11,13 @@ Please review third_party pinning scripts and patches for more details.
 package encoder
 
 import (
+	"fmt"
+	"io/ioutil"
+	"math"
+
 	"github.com/golang/protobuf/proto"
 	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/pkg/errors"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
@@ -26,6 +31,7 @@ import (
 	flogging "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libpatch/logbridge"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/protos/bft"
 )
 
 const (
@@ -44,6 +50,8 @@ const (
 	ConsensusTypeKafka = "kafka"
 	// ConsensusTypeKafka identifies the Kafka-based consensus implementation.
 	ConsensusTypeEtcdRaft = "etcdraft"
+	// ConsensusTypeBFT identifies the BFT-based consensus implementation.
+	ConsensusTypeBFT = "BFT"
 
 	// BlockValidationPolicyKey TODO
 	BlockValidationPolicyKey = "BlockValidation"
@@ -176,9 +184,12 @@ func NewOrdererGroup(conf *genesisconfig.Orderer) (*cb.ConfigGroup, error) {
 	if err := AddPolicies(ordererGroup, conf.Policies, channelconfig.AdminsPolicyKey); err != nil {
 		return nil, errors.Wrapf(err, "error adding policies to orderer group")
 	}
-	ordererGroup.Policies[BlockValidationPolicyKey] = &cb.ConfigPolicy{
-		Policy:    policies.ImplicitMetaAnyPolicy(channelconfig.WritersPolicyKey).Value(),
-		ModPolicy: channelconfig.AdminsPolicyKey,
+	if _, ok := conf.Policies[BlockValidationPolicyKey]; !ok {
+		// Profiles written for Fabric 1.x do not define a BlockValidation policy
+		ordererGroup.Policies[BlockValidationPolicyKey] = &cb.ConfigPolicy{
+			Policy:    policies.ImplicitMetaAnyPolicy(channelconfig.WritersPolicyKey).Value(),
+			ModPolicy: channelconfig.AdminsPolicyKey,
+		}
 	}
 	addValue(ordererGroup, channelconfig.BatchSizeValue(
 		conf.BatchSize.MaxMessageCount,
@@ -203,6 +214,17 @@ func NewOrdererGroup(conf *genesisconfig.Orderer) (*cb.ConfigGroup, error) {
 		if consensusMetadata, err = channelconfig.MarshalEtcdRaftMetadata(conf.EtcdRaft); err != nil {
 			return nil, errors.Errorf("cannot marshal metadata for orderer type %s: %s", ConsensusTypeEtcdRaft, err)
 		}
+	case ConsensusTypeBFT:
+		consenterProtos, err := consenterProtosFromConfig(conf.ConsenterMapping)
+		if err != nil {
+			return nil, errors.Errorf("cannot load consenter config for orderer type %s: %s", ConsensusTypeBFT, err)
+		}
+		addValue(ordererGroup, channelconfig.OrderersValue(consenterProtos), channelconfig.AdminsPolicyKey)
+		if consensusMetadata, err = channelconfig.MarshalBFTOptions(conf.SmartBFT); err != nil {
+			return nil, errors.Errorf("consenter options read failed with error %s for orderer type %s", err, ConsensusTypeBFT)
+		}
+		// Blocks of a BFT ordering service must be signed by a quorum of the consenters
+		encodeBFTBlockVerificationPolicy(consenterProtos, ordererGroup)
 	default:
 		return nil, errors.Errorf("unknown orderer type: %s", conf.OrdererType)
 	}
@@ -221,6 +243,94 @@ func NewOrdererGroup(conf *genesisconfig.Orderer) (*cb.ConfigGroup, error) {
 	return ordererGroup, nil
 }
 
+func consenterProtosFromConfig(consenterMapping []*genesisconfig.Consenter) ([]*bft.Consenter, error) {
+	if len(consenterMapping) == 0 {
+		return nil, errors.New("no consenters defined")
+	}
+
+	var consenterProtos []*bft.Consenter
+	for _, consenter := range consenterMapping {
+		c := &bft.Consenter{
+			Id:    consenter.ID,
+			Host:  consenter.Host,
+			Port:  consenter.Port,
+			MspId: consenter.MSPID,
+		}
+		// Expect the user to set the config value for client/server certs or identity to the
+		// path where they are persisted locally, then load these files to memory.
+		if consenter.ClientTLSCert != "" {
+			clientCert, err := ioutil.ReadFile(consenter.ClientTLSCert)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load client cert for consenter %s:%d: %s", c.GetHost(), c.GetPort(), err)
+			}
+			c.ClientTlsCert = clientCert
+		}
+
+		if consenter.ServerTLSCert != "" {
+			serverCert, err := ioutil.ReadFile(consenter.ServerTLSCert)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load server cert for consenter %s:%d: %s", c.GetHost(), c.GetPort(), err)
+			}
+			c.ServerTlsCert = serverCert
+		}
+
+		if consenter.Identity != "" {
+			identity, err := ioutil.ReadFile(consenter.Identity)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load identity for consenter %s:%d: %s", c.GetHost(), c.GetPort(), err)
+			}
+			c.Identity = identity
+		}
+
+		consenterProtos = append(consenterProtos, c)
+	}
+	return consenterProtos, nil
+}
+
+// encodeBFTBlockVerificationPolicy sets the BlockValidation policy of the orderer group to require the signatures
+// of a quorum of the given consenters
+func encodeBFTBlockVerificationPolicy(consenterProtos []*bft.Consenter, ordererGroup *cb.ConfigGroup) {
+	n := len(consenterProtos)
+	f := (n - 1) / 3
+
+	var identities []*mb.MSPPrincipal
+	var pols []*cb.SignaturePolicy
+	for i, consenter := range consenterProtos {
+		pols = append(pols, cauthdsl.SignedBy(int32(i)))
+		identities = append(identities, &mb.MSPPrincipal{
+			PrincipalClassification: mb.MSPPrincipal_IDENTITY,
+			Principal:               protoutil.MarshalOrPanic(&mb.SerializedIdentity{Mspid: consenter.MspId, IdBytes: consenter.Identity}),
+		})
+	}
+
+	sp := &cb.SignaturePolicyEnvelope{
+		Rule:       cauthdsl.NOutOf(int32(computeBFTQuorum(n, f)), pols),
+		Identities: identities,
+	}
+	ordererGroup.Policies[BlockValidationPolicyKey] = &cb.ConfigPolicy{
+		Policy:    &cb.Policy{Type: int32(cb.Policy_SIGNATURE), Value: protoutil.MarshalOrPanic(sp)},
+		ModPolicy: channelconfig.AdminsPolicyKey,
+	}
+}
+
+// computeBFTQuorum returns the number of consenters which form a quorum, given the total number of consenters
+// and the number of faulty consenters which are tolerated
+func computeBFTQuorum(totalNodes, faultyNodes int) int {
+	return int(math.Ceil(float64(totalNodes+faultyNodes+1) / 2))
+}
+
+// orgMSPConfig returns the MSP configuration of an organization, which is the one built by the caller if it is set
+// and is loaded from its MSP directory otherwise. An in-memory MSP definition must have been built by the caller.
+func orgMSPConfig(conf *genesisconfig.Organization) (*mb.MSPConfig, error) {
+	if conf.MSPConfig != nil {
+		return conf.MSPConfig, nil
+	}
+	if conf.MSP != nil {
+		return nil, errors.New("the MSP configuration of the in-memory MSP definition was not built")
+	}
+	return msp.GetVerifyingMspConfig(conf.MSPDir, conf.ID, conf.MSPType)
+}
+
 // NewConsortiumsGroup returns an org component of the channel configuration.  It defines the crypto material for the
 // organization (its MSP).  It sets the mod_policy of all elements to "Admins".
 func NewConsortiumOrgGroup(conf *genesisconfig.Organization) (*cb.ConfigGroup, error) {
@@ -231,7 +341,7 @@ func NewConsortiumOrgGroup(conf *genesisconfig.Organization) (*cb.ConfigGroup, e
 		return consortiumsOrgGroup, nil
 	}
 
-	mspConfig, err := msp.GetVerifyingMspConfig(conf.MSPDir, conf.ID, conf.MSPType)
+	mspConfig, err := orgMSPConfig(conf)
 	if err != nil {
 		return nil, errors.Wrapf(err, "1 - Error loading MSP configuration for org: %s", conf.Name)
 	}
@@ -255,7 +365,7 @@ func NewOrdererOrgGroup(conf *genesisconfig.Organization) (*cb.ConfigGroup, erro
 		return ordererOrgGroup, nil
 	}
 
-	mspConfig, err := msp.GetVerifyingMspConfig(conf.MSPDir, conf.ID, conf.MSPType)
+	mspConfig, err := orgMSPConfig(conf)
 	if err != nil {
 		return nil, errors.Wrapf(err, "1 - Error loading MSP configuration for org: %s", conf.Name)
 	}
@@ -311,7 +421,7 @@ func NewApplicationOrgGroup(conf *genesisconfig.Organization) (*cb.ConfigGroup,
 		return applicationOrgGroup, nil
 	}
 
-	mspConfig, err := msp.GetVerifyingMspConfig(conf.MSPDir, conf.ID, conf.MSPType)
+	mspConfig, err := orgMSPConfig(conf)
 	if err != nil {
 		return nil, errors.Wrapf(err, "1 - Error loading MSP configuration for org %s", conf.Name)
 	}
@@ -12,18 +12,21 @@ package localconfig
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
+	mb "github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
 	"github.com/spf13/viper"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/viperutil"
 	flogging "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libpatch/logbridge"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/protos/bft"
 )
 
 const (
@@ -32,6 +35,9 @@ const (
 
 	// The type key for etcd based RAFT consensus.
 	EtcdRaft = "etcdraft"
+
+	// The type key for BFT consensus.
+	BFT = "BFT"
 )
 
 var logger = flogging.MustGetLogger("common.tools.configtxgen.localconfig")
@@ -143,6 +149,12 @@ type Organization struct {
 	MSPType  string             `yaml:"MSPType"`
 	Policies map[string]*Policy `yaml:"Policies"`
 
+	// MSP is the in-memory alternative to MSPDir
+	MSP *MSPDefinition `yaml:"MSP"`
+
+	// MSPConfig is an MSP configuration built by the caller, e.g. from MSP, which the encoder uses instead of reading MSPDir
+	MSPConfig *mb.MSPConfig `yaml:"-" json:"-"`
+
 	// Note: Viper deserialization does not seem to care for
 	// embedding of types, so we use one organization struct
 	// for both orderers and applications.
@@ -159,6 +171,36 @@ type Organization struct {
 	SkipAsForeign bool
 }
 
+// MSPDefinition holds the MSP material of an organization in memory. When it is set on an organization,
+// the MSP configuration is built from it and the MSPDir of the organization is not read. Certificates
+// and revocation lists are PEM encoded.
+type MSPDefinition struct {
+	RootCerts                     [][]byte        `yaml:"RootCerts"`
+	IntermediateCerts             [][]byte        `yaml:"IntermediateCerts"`
+	Admins                        [][]byte        `yaml:"Admins"`
+	RevocationList                [][]byte        `yaml:"RevocationList"`
+	TLSRootCerts                  [][]byte        `yaml:"TLSRootCerts"`
+	TLSIntermediateCerts          [][]byte        `yaml:"TLSIntermediateCerts"`
+	OrganizationalUnitIdentifiers []*OUIdentifier `yaml:"OrganizationalUnitIdentifiers"`
+	NodeOUs                       *NodeOUs        `yaml:"NodeOUs"`
+}
+
+// OUIdentifier identifies an organizational unit by the certificate of the CA which issues
+// its identities and the name of the unit.
+type OUIdentifier struct {
+	Certificate                  []byte `yaml:"Certificate"`
+	OrganizationalUnitIdentifier string `yaml:"OrganizationalUnitIdentifier"`
+}
+
+// NodeOUs defines the organizational units which classify the identities of an MSP.
+type NodeOUs struct {
+	Enable              bool          `yaml:"Enable"`
+	ClientOUIdentifier  *OUIdentifier `yaml:"ClientOUIdentifier"`
+	PeerOUIdentifier    *OUIdentifier `yaml:"PeerOUIdentifier"`
+	AdminOUIdentifier   *OUIdentifier `yaml:"AdminOUIdentifier"`
+	OrdererOUIdentifier *OUIdentifier `yaml:"OrdererOUIdentifier"`
+}
+
 // AnchorPeer encodes the necessary fields to identify an anchor peer.
 type AnchorPeer struct {
 	Host string `yaml:"Host"`
@@ -178,6 +220,22 @@ type Orderer struct {
 	MaxChannels   uint64                   `yaml:"MaxChannels"`
 	Capabilities  map[string]bool          `yaml:"Capabilities"`
 	Policies      map[string]*Policy       `yaml:"Policies"`
+
+	// ConsenterMapping and SmartBFT are only used by the BFT consensus type
+	ConsenterMapping []*Consenter `yaml:"ConsenterMapping"`
+	SmartBFT         *bft.Options `yaml:"SmartBFT"`
+}
+
+// Consenter represents a consenting node of a BFT ordering service. The certificates and the identity are the
+// paths of the PEM files which contain them.
+type Consenter struct {
+	ID            uint32 `yaml:"ID"`
+	Host          string `yaml:"Host"`
+	Port          uint32 `yaml:"Port"`
+	MSPID         string `yaml:"MSPID"`
+	ClientTLSCert string `yaml:"ClientTLSCert"`
+	ServerTLSCert string `yaml:"ServerTLSCert"`
+	Identity      string `yaml:"Identity"`
 }
 
 // BatchSize contains configuration affecting the size of batches.
@@ -214,6 +272,25 @@ var genesisDefaults = TopLevel{
 				SnapshotIntervalSize: 20 * 1024 * 1024, // 20 MB
 			},
 		},
+		SmartBFT: &bft.Options{
+			RequestBatchMaxCount:      100,
+			RequestBatchMaxBytes:      10 * 1024 * 1024, // 10 MB
+			RequestBatchMaxInterval:   "50ms",
+			IncomingMessageBufferSize: 200,
+			RequestPoolSize:           400,
+			RequestForwardTimeout:     "2s",
+			RequestComplainTimeout:    "20s",
+			RequestAutoRemoveTimeout:  "3m",
+			ViewChangeResendInterval:  "5s",
+			ViewChangeTimeout:         "20s",
+			LeaderHeartbeatTimeout:    "1m0s",
+			LeaderHeartbeatCount:      10,
+			CollectTimeout:            "1s",
+			LeaderRotation:            bft.Options_ROTATION_ON,
+			DecisionsPerLeader:        3,
+			RequestMaxBytes:           10 * 1024, // 10 KB
+			RequestPoolSubmitTimeout:  "5s",
+		},
 	},
 }
 
@@ -252,7 +329,9 @@ func LoadTopLevel(configPaths ...string) (*TopLevel, error) {
 		return nil, errors.WithMessage(err, "Error unmarshaling config into struct: ")
 	}
 
-	(&uconf).completeInitialization(filepath.Dir(config.ConfigFileUsed()))
+	if err := (&uconf).completeInitialization(filepath.Dir(config.ConfigFileUsed())); err != nil {
+		return nil, errors.WithMessage(err, "Error initializing config: ")
+	}
 
 	logger.Infof("Loaded configuration: %s", config.ConfigFileUsed())
 
@@ -293,29 +372,119 @@ func Load(profile string, configPaths ...string) (*Profile, error) {
 		return nil, errors.WithMessage(err, "Error unmarshaling config into struct: ")
 	}
 
-	result, ok := uconf.Profiles[profile]
+	result, ok := uconf.lookupProfile(profile)
 	if !ok {
 		return nil, errors.Errorf("Could not find profile: %v", profile)
 	}
 
-	result.completeInitialization(filepath.Dir(config.ConfigFileUsed()))
+	if err := result.completeInitialization(filepath.Dir(config.ConfigFileUsed())); err != nil {
+		return nil, errors.WithMessage(err, "Error initializing config: ")
+	}
 
 	logger.Infof("Loaded configuration: %s", config.ConfigFileUsed())
 
 	return result, nil
 }
 
-func (t *TopLevel) completeInitialization(configDir string) {
+// LoadTopLevelFromReader loads the configtx.yaml content read from r into the structs above and completes their
+// initialization. Unlike LoadTopLevel, the profiles are initialized as well, so an invalid profile fails the load.
+// Relative paths, e.g. MSP directories and TLS certificates, are resolved against configDir.
+// Environment overrides (CONFIGTX_ prefixed variables) are only applied if envOverrides is set.
+func LoadTopLevelFromReader(r io.Reader, configDir string, envOverrides bool) (*TopLevel, error) {
+	config, err := readConfig(r, envOverrides, strings.NewReplacer(".", "_"))
+	if err != nil {
+		return nil, err
+	}
+
+	var uconf TopLevel
+	err = viperutil.EnhancedExactUnmarshal(config, &uconf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Error unmarshaling config into struct: ")
+	}
+
+	if err := (&uconf).completeInitialization(configDir); err != nil {
+		return nil, errors.WithMessage(err, "Error initializing config: ")
+	}
+
+	for name, profile := range uconf.Profiles {
+		if err := profile.completeInitialization(configDir); err != nil {
+			return nil, errors.WithMessagef(err, "Error initializing profile %s: ", name)
+		}
+	}
+
+	return &uconf, nil
+}
+
+// LoadFromReader returns the orderer/application config combination that corresponds to a given profile of the
+// configtx.yaml content read from r. Relative paths are resolved against configDir. Environment overrides
+// (CONFIGTX_ prefixed variables, relative to the profile) are only applied if envOverrides is set.
+func LoadFromReader(profile string, r io.Reader, configDir string, envOverrides bool) (*Profile, error) {
+	replacer := strings.NewReplacer(strings.ToUpper(fmt.Sprintf("profiles.%s.", profile)), "", ".", "_")
+	config, err := readConfig(r, envOverrides, replacer)
+	if err != nil {
+		return nil, err
+	}
+
+	var uconf TopLevel
+	err = viperutil.EnhancedExactUnmarshal(config, &uconf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Error unmarshaling config into struct: ")
+	}
+
+	result, ok := uconf.lookupProfile(profile)
+	if !ok {
+		return nil, errors.Errorf("Could not find profile: %v", profile)
+	}
+
+	if err := result.completeInitialization(configDir); err != nil {
+		return nil, errors.WithMessage(err, "Error initializing config: ")
+	}
+
+	return result, nil
+}
+
+// lookupProfile returns the profile with the given name. Viper lowercases the keys of the config, so the
+// lowercased name is looked up as well.
+func (t *TopLevel) lookupProfile(profile string) (*Profile, bool) {
+	if result, ok := t.Profiles[profile]; ok {
+		return result, true
+	}
+	result, ok := t.Profiles[strings.ToLower(profile)]
+	return result, ok
+}
+
+func readConfig(r io.Reader, envOverrides bool, replacer *strings.Replacer) (*viper.Viper, error) {
+	config := viper.New()
+	config.SetConfigType("yaml")
+
+	if envOverrides {
+		config.SetEnvPrefix(Prefix)
+		config.AutomaticEnv()
+		config.SetEnvKeyReplacer(replacer)
+	}
+
+	if err := config.ReadConfig(r); err != nil {
+		return nil, errors.WithMessage(err, "Error reading configuration: ")
+	}
+
+	return config, nil
+}
+
+func (t *TopLevel) completeInitialization(configDir string) error {
 	for _, org := range t.Organizations {
 		org.completeInitialization(configDir)
 	}
 
 	if t.Orderer != nil {
-		t.Orderer.completeInitialization(configDir)
+		if err := t.Orderer.completeInitialization(configDir); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
-func (p *Profile) completeInitialization(configDir string) {
+func (p *Profile) completeInitialization(configDir string) error {
 	if p.Application != nil {
 		for _, org := range p.Application.Organizations {
 			org.completeInitialization(configDir)
@@ -338,8 +507,9 @@ func (p *Profile) completeInitialization(configDir string) {
 			org.completeInitialization(configDir)
 		}
 		// Some profiles will not define orderer parameters
-		p.Orderer.completeInitialization(configDir)
+		return p.Orderer.completeInitialization(configDir)
 	}
+	return nil
 }
 
 func (r *Resources) completeInitialization() {
@@ -448,7 +618,7 @@ loop:
 
 		// validate the specified members for Options
 		if ord.EtcdRaft.Options.ElectionTick <= ord.EtcdRaft.Options.HeartbeatTick {
-			logger.Panicf("election tick must be greater than heartbeat tick")
+			return errors.Errorf("election tick must be greater than heartbeat tick")
 		}
 
 		for _, c := range ord.EtcdRaft.GetConsenters() {
@@ -471,6 +641,35 @@ loop:
 			translatePathInPlace(configDir, &serverCertPath)
 			c.ServerTlsCert = []byte(serverCertPath)
 		}
+	case BFT:
+		if ord.SmartBFT == nil {
+			logger.Infof("Orderer.SmartBFT unset, setting to %v", genesisDefaults.Orderer.SmartBFT)
+			ord.SmartBFT = genesisDefaults.Orderer.SmartBFT
+		}
+
+		if len(ord.ConsenterMapping) == 0 {
+			return errors.Errorf("%s configuration did not specify any consenter", BFT)
+		}
+
+		for _, c := range ord.ConsenterMapping {
+			switch {
+			case c.Host == "":
+				return errors.Errorf("consenter info in %s configuration did not specify host", BFT)
+			case c.Port == 0:
+				return errors.Errorf("consenter info in %s configuration did not specify port", BFT)
+			case c.ClientTLSCert == "":
+				return errors.Errorf("consenter info in %s configuration did not specify client TLS cert", BFT)
+			case c.ServerTLSCert == "":
+				return errors.Errorf("consenter info in %s configuration did not specify server TLS cert", BFT)
+			case c.MSPID == "":
+				return errors.Errorf("consenter info in %s configuration did not specify MSP ID", BFT)
+			case c.Identity == "":
+				return errors.Errorf("consenter info in %s configuration did not specify identity certificate", BFT)
+			}
+			translatePathInPlace(configDir, &c.ClientTLSCert)
+			translatePathInPlace(configDir, &c.ServerTLSCert)
+			translatePathInPlace(configDir, &c.Identity)
+		}
 	default:
 		return errors.Errorf("unknown orderer type: %s", ord.OrdererType)
 	}
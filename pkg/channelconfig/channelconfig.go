@@ -0,0 +1,479 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channelconfig
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	ab "github.com/hyperledger/fabric-protos-go/orderer"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/pkg/errors"
+	fabricchannelconfig "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/common/logging"
+)
+
+var logger = logging.NewLogger("fablibgoext")
+
+// ChannelConfig is a typed, read-only view of a channel config
+type ChannelConfig struct {
+	channelID   string
+	sequence    uint64
+	protos      *fabricchannelconfig.ChannelProtos
+	application *ApplicationConfig
+	orderer     *OrdererConfig
+	consortiums map[string]*ConsortiumConfig
+}
+
+// NewFromBlock returns a view of the channel config contained in the given config block
+func NewFromBlock(block *cb.Block) (*ChannelConfig, error) {
+	if block == nil {
+		return nil, errors.New("missing block")
+	}
+
+	env, err := protoutil.ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not extract envelope from config block")
+	}
+
+	configEnv := &cb.ConfigEnvelope{}
+	chdr, err := protoutil.UnmarshalEnvelopeOfType(env, cb.HeaderType_CONFIG, configEnv)
+	if err != nil {
+		return nil, errors.WithMessage(err, "block is not a config block")
+	}
+
+	cc, err := New(configEnv.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.channelID = chdr.ChannelId
+
+	return cc, nil
+}
+
+// New returns a view of the given channel config. The channel ID is not part of the config
+// and is therefore empty.
+func New(config *cb.Config) (*ChannelConfig, error) {
+	if config == nil || config.ChannelGroup == nil {
+		return nil, errors.New("config has no channel group")
+	}
+
+	cc := &ChannelConfig{
+		sequence: config.Sequence,
+		protos:   &fabricchannelconfig.ChannelProtos{},
+	}
+
+	channelGroup := config.ChannelGroup
+	if err := fabricchannelconfig.DeserializeProtoValuesFromGroup(channelGroup, cc.protos); err != nil {
+		return nil, errors.WithMessage(err, "failed to deserialize channel values")
+	}
+
+	for groupName, group := range channelGroup.Groups {
+		var err error
+		switch groupName {
+		case fabricchannelconfig.ApplicationGroupKey:
+			cc.application, err = newApplicationConfig(group)
+		case fabricchannelconfig.OrdererGroupKey:
+			cc.orderer, err = newOrdererConfig(group)
+		case fabricchannelconfig.ConsortiumsGroupKey:
+			cc.consortiums, err = newConsortiumsConfig(group)
+		default:
+			err = errors.Errorf("disallowed channel group: %s", groupName)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cc, nil
+}
+
+// ChannelID returns the ID of the channel, if the view was created from a config block
+func (cc *ChannelConfig) ChannelID() string {
+	return cc.channelID
+}
+
+// Sequence returns the sequence number of the config
+func (cc *ChannelConfig) Sequence() uint64 {
+	return cc.sequence
+}
+
+// HashingAlgorithm returns the name of the hashing algorithm of the channel, e.g. SHA256
+func (cc *ChannelConfig) HashingAlgorithm() string {
+	return cc.protos.HashingAlgorithm.Name
+}
+
+// BlockDataHashingStructureWidth returns the width to use when forming the block data hashing structure
+func (cc *ChannelConfig) BlockDataHashingStructureWidth() uint32 {
+	return cc.protos.BlockDataHashingStructure.Width
+}
+
+// OrdererAddresses returns the global orderer addresses of the channel. Orderer orgs may define their
+// own endpoints, see OrdererConfig.Endpoints.
+func (cc *ChannelConfig) OrdererAddresses() []string {
+	return cc.protos.OrdererAddresses.Addresses
+}
+
+// ConsortiumName returns the name of the consortium this channel was created under
+func (cc *ChannelConfig) ConsortiumName() string {
+	return cc.protos.Consortium.Name
+}
+
+// Capabilities returns the sorted names of the channel capabilities
+func (cc *ChannelConfig) Capabilities() []string {
+	return capabilityNames(cc.protos.Capabilities)
+}
+
+// ApplicationConfig returns the application config, or nil if the channel has no application group
+func (cc *ChannelConfig) ApplicationConfig() *ApplicationConfig {
+	return cc.application
+}
+
+// OrdererConfig returns the orderer config, or nil if the channel has no orderer group
+func (cc *ChannelConfig) OrdererConfig() *OrdererConfig {
+	return cc.orderer
+}
+
+// Consortiums returns the consortiums by name. Only the system channel has consortiums.
+func (cc *ChannelConfig) Consortiums() map[string]*ConsortiumConfig {
+	return cc.consortiums
+}
+
+// MSPIDs returns the sorted MSP IDs of all application and orderer orgs
+func (cc *ChannelConfig) MSPIDs() []string {
+	ids := make(map[string]struct{})
+
+	if cc.application != nil {
+		for _, org := range cc.application.orgs {
+			ids[org.MSPID()] = struct{}{}
+		}
+	}
+
+	if cc.orderer != nil {
+		for _, org := range cc.orderer.orgs {
+			ids[org.MSPID()] = struct{}{}
+		}
+	}
+
+	var result []string
+	for id := range ids {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// ApplicationConfig is a view of the application group of a channel config
+type ApplicationConfig struct {
+	protos *fabricchannelconfig.ApplicationProtos
+	orgs   map[string]*ApplicationOrg
+}
+
+func newApplicationConfig(group *cb.ConfigGroup) (*ApplicationConfig, error) {
+	ac := &ApplicationConfig{
+		protos: &fabricchannelconfig.ApplicationProtos{},
+		orgs:   make(map[string]*ApplicationOrg),
+	}
+
+	if err := fabricchannelconfig.DeserializeProtoValuesFromGroup(group, ac.protos); err != nil {
+		return nil, errors.WithMessage(err, "failed to deserialize application values")
+	}
+
+	for orgName, orgGroup := range group.Groups {
+		org := &ApplicationOrg{protos: &fabricchannelconfig.ApplicationOrgProtos{}}
+
+		var err error
+		if org.Organization, err = newOrganization(orgName, orgGroup, org.protos); err != nil {
+			return nil, errors.WithMessagef(err, "invalid application org %s", orgName)
+		}
+
+		ac.orgs[orgName] = org
+	}
+
+	return ac, nil
+}
+
+// Organizations returns the application orgs by name
+func (ac *ApplicationConfig) Organizations() map[string]*ApplicationOrg {
+	return ac.orgs
+}
+
+// Capabilities returns the sorted names of the application capabilities
+func (ac *ApplicationConfig) Capabilities() []string {
+	return capabilityNames(ac.protos.Capabilities)
+}
+
+// ACLs returns the absolute policy reference of each resource. Relative policy references
+// are resolved in the application group, e.g. Readers becomes /Channel/Application/Readers.
+func (ac *ApplicationConfig) ACLs() map[string]string {
+	acls := make(map[string]string)
+	for resource, acl := range ac.protos.ACLs.Acls {
+		if len(acl.PolicyRef) > 0 && acl.PolicyRef[0] == '/' {
+			acls[resource] = acl.PolicyRef
+		} else {
+			acls[resource] = "/" + fabricchannelconfig.ChannelGroupKey + "/" + fabricchannelconfig.ApplicationGroupKey + "/" + acl.PolicyRef
+		}
+	}
+	return acls
+}
+
+// ApplicationOrg is a view of an application org
+type ApplicationOrg struct {
+	*Organization
+	protos *fabricchannelconfig.ApplicationOrgProtos
+}
+
+// AnchorPeers returns the anchor peers of the org
+func (ao *ApplicationOrg) AnchorPeers() []*pb.AnchorPeer {
+	return ao.protos.AnchorPeers.AnchorPeers
+}
+
+// OrdererConfig is a view of the orderer group of a channel config
+type OrdererConfig struct {
+	protos       *fabricchannelconfig.OrdererProtos
+	batchTimeout time.Duration
+	orgs         map[string]*OrdererOrg
+}
+
+func newOrdererConfig(group *cb.ConfigGroup) (*OrdererConfig, error) {
+	oc := &OrdererConfig{
+		protos: &fabricchannelconfig.OrdererProtos{},
+		orgs:   make(map[string]*OrdererOrg),
+	}
+
+	if err := fabricchannelconfig.DeserializeProtoValuesFromGroup(group, oc.protos); err != nil {
+		return nil, errors.WithMessage(err, "failed to deserialize orderer values")
+	}
+
+	if timeout := oc.protos.BatchTimeout.Timeout; timeout != "" {
+		var err error
+		if oc.batchTimeout, err = time.ParseDuration(timeout); err != nil {
+			logger.Warnf("Ignoring invalid batch timeout %s: %s", timeout, err)
+		}
+	}
+
+	for orgName, orgGroup := range group.Groups {
+		org := &OrdererOrg{protos: &fabricchannelconfig.OrdererOrgProtos{}}
+
+		var err error
+		if org.Organization, err = newOrganization(orgName, orgGroup, org.protos); err != nil {
+			return nil, errors.WithMessagef(err, "invalid orderer org %s", orgName)
+		}
+
+		oc.orgs[orgName] = org
+	}
+
+	return oc, nil
+}
+
+// ConsensusType returns the consensus type, e.g. etcdraft
+func (oc *OrdererConfig) ConsensusType() string {
+	return oc.protos.ConsensusType.Type
+}
+
+// ConsensusMetadata returns the metadata associated with the consensus type
+func (oc *OrdererConfig) ConsensusMetadata() []byte {
+	return oc.protos.ConsensusType.Metadata
+}
+
+// ConsensusState returns the consensus type state
+func (oc *OrdererConfig) ConsensusState() ab.ConsensusType_State {
+	return oc.protos.ConsensusType.State
+}
+
+// BatchSize returns the batch size parameters of the orderer
+func (oc *OrdererConfig) BatchSize() *ab.BatchSize {
+	return oc.protos.BatchSize
+}
+
+// BatchTimeout returns the amount of time to wait before creating a batch, or zero if the configured timeout is invalid
+func (oc *OrdererConfig) BatchTimeout() time.Duration {
+	return oc.batchTimeout
+}
+
+// KafkaBrokers returns the addresses of the bootstrap Kafka brokers
+func (oc *OrdererConfig) KafkaBrokers() []string {
+	return oc.protos.KafkaBrokers.Brokers
+}
+
+// MaxChannelsCount returns the maximum count of channels the orderer supports
+func (oc *OrdererConfig) MaxChannelsCount() uint64 {
+	return oc.protos.ChannelRestrictions.MaxCount
+}
+
+// Capabilities returns the sorted names of the orderer capabilities
+func (oc *OrdererConfig) Capabilities() []string {
+	return capabilityNames(oc.protos.Capabilities)
+}
+
+// Organizations returns the orderer orgs by name
+func (oc *OrdererConfig) Organizations() map[string]*OrdererOrg {
+	return oc.orgs
+}
+
+// Endpoints returns the org specific endpoints of all orderer orgs, sorted by org name
+func (oc *OrdererConfig) Endpoints() []string {
+	var names []string
+	for name := range oc.orgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var endpoints []string
+	for _, name := range names {
+		endpoints = append(endpoints, oc.orgs[name].Endpoints()...)
+	}
+	return endpoints
+}
+
+// OrdererOrg is a view of an orderer org
+type OrdererOrg struct {
+	*Organization
+	protos *fabricchannelconfig.OrdererOrgProtos
+}
+
+// Endpoints returns the org specific orderer endpoints
+func (oo *OrdererOrg) Endpoints() []string {
+	return oo.protos.Endpoints.Addresses
+}
+
+// ConsortiumConfig is a view of a consortium of the system channel
+type ConsortiumConfig struct {
+	protos *fabricchannelconfig.ConsortiumProtos
+	orgs   map[string]*Organization
+}
+
+func newConsortiumsConfig(group *cb.ConfigGroup) (map[string]*ConsortiumConfig, error) {
+	consortiums := make(map[string]*ConsortiumConfig)
+
+	for name, consortiumGroup := range group.Groups {
+		cc := &ConsortiumConfig{
+			protos: &fabricchannelconfig.ConsortiumProtos{},
+			orgs:   make(map[string]*Organization),
+		}
+
+		if err := fabricchannelconfig.DeserializeProtoValuesFromGroup(consortiumGroup, cc.protos); err != nil {
+			return nil, errors.WithMessagef(err, "failed to deserialize values of consortium %s", name)
+		}
+
+		for orgName, orgGroup := range consortiumGroup.Groups {
+			org, err := newOrganization(orgName, orgGroup)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid org %s of consortium %s", orgName, name)
+			}
+			cc.orgs[orgName] = org
+		}
+
+		consortiums[name] = cc
+	}
+
+	return consortiums, nil
+}
+
+// Organizations returns the orgs of the consortium by name
+func (cc *ConsortiumConfig) Organizations() map[string]*Organization {
+	return cc.orgs
+}
+
+// ChannelCreationPolicy returns the policy which must be satisfied to create a channel in the consortium
+func (cc *ConsortiumConfig) ChannelCreationPolicy() *cb.Policy {
+	return cc.protos.ChannelCreationPolicy
+}
+
+// Organization is a view of the MSP definition of an org
+type Organization struct {
+	name   string
+	mspID  string
+	protos *fabricchannelconfig.OrganizationProtos
+}
+
+func newOrganization(name string, group *cb.ConfigGroup, protosStructs ...interface{}) (*Organization, error) {
+	if len(group.Groups) > 0 {
+		return nil, errors.New("organizations do not support sub-groups")
+	}
+
+	org := &Organization{
+		name:   name,
+		protos: &fabricchannelconfig.OrganizationProtos{},
+	}
+
+	if err := fabricchannelconfig.DeserializeProtoValuesFromGroup(group, append([]interface{}{org.protos}, protosStructs...)...); err != nil {
+		return nil, errors.WithMessage(err, "failed to deserialize values")
+	}
+
+	var err error
+	if org.mspID, err = mspID(org.protos.MSP); err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// Name returns the name this org is referred to in config
+func (o *Organization) Name() string {
+	return o.name
+}
+
+// MSPID returns the MSP ID of the org
+func (o *Organization) MSPID() string {
+	return o.mspID
+}
+
+// MSPConfig returns the MSP definition of the org
+func (o *Organization) MSPConfig() *mb.MSPConfig {
+	return o.protos.MSP
+}
+
+// FabricMSPConfig returns the MSP definition of the org if it is an X.509 based MSP, or nil otherwise
+func (o *Organization) FabricMSPConfig() *mb.FabricMSPConfig {
+	if msp.ProviderType(o.protos.MSP.Type) != msp.FABRIC {
+		return nil
+	}
+
+	fabricMSPConfig := &mb.FabricMSPConfig{}
+	if err := proto.Unmarshal(o.protos.MSP.Config, fabricMSPConfig); err != nil {
+		// The config was already unmarshalled successfully by newOrganization
+		return nil
+	}
+	return fabricMSPConfig
+}
+
+// mspID returns the name of the MSP, which is the MSP ID
+func mspID(mspConfig *mb.MSPConfig) (string, error) {
+	switch msp.ProviderType(mspConfig.Type) {
+	case msp.FABRIC:
+		fabricMSPConfig := &mb.FabricMSPConfig{}
+		if err := proto.Unmarshal(mspConfig.Config, fabricMSPConfig); err != nil {
+			return "", errors.Wrap(err, "could not unmarshal fabric MSP config")
+		}
+		return fabricMSPConfig.Name, nil
+	case msp.IDEMIX:
+		idemixMSPConfig := &mb.IdemixMSPConfig{}
+		if err := proto.Unmarshal(mspConfig.Config, idemixMSPConfig); err != nil {
+			return "", errors.Wrap(err, "could not unmarshal idemix MSP config")
+		}
+		return idemixMSPConfig.Name, nil
+	default:
+		return "", errors.Errorf("unsupported MSP type %d", mspConfig.Type)
+	}
+}
+
+func capabilityNames(capabilities *cb.Capabilities) []string {
+	var names []string
+	for name := range capabilities.Capabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
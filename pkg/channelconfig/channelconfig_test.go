@@ -0,0 +1,202 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channelconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	ab "github.com/hyperledger/fabric-protos-go/orderer"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/require"
+	fabricchannelconfig "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestNewFromBlock(t *testing.T) {
+	cc, err := NewFromBlock(mockConfigBlock())
+	require.NoError(t, err)
+
+	require.Equal(t, "mychannel", cc.ChannelID())
+	require.Equal(t, uint64(0), cc.Sequence())
+	require.Equal(t, []string{"orderer:7050"}, cc.OrdererAddresses())
+	require.Equal(t, []string{"V1_4_3"}, cc.Capabilities())
+	require.Equal(t, []string{"OrdererMSP", "Org1MSP", "Org2MSP"}, cc.MSPIDs())
+	require.Empty(t, cc.Consortiums())
+
+	ac := cc.ApplicationConfig()
+	require.NotNil(t, ac)
+	require.Len(t, ac.Organizations(), 2)
+	require.Equal(t, "Org1MSP", ac.Organizations()["Org1MSP"].MSPID())
+	require.Equal(t, "Org1MSP", ac.Organizations()["Org1MSP"].Name())
+	require.Empty(t, ac.Organizations()["Org1MSP"].AnchorPeers())
+	require.Equal(t, []string{"V1_4_2"}, ac.Capabilities())
+	require.Equal(t, map[string]string{
+		"Readers":                 "/Channel/Application/Readers",
+		"/Channel/Orderer/Admins": "/Channel/Orderer/Admins",
+	}, ac.ACLs())
+
+	fabricMSPConfig := ac.Organizations()["Org2MSP"].FabricMSPConfig()
+	require.NotNil(t, fabricMSPConfig)
+	require.Equal(t, "Org2MSP", fabricMSPConfig.Name)
+
+	oc := cc.OrdererConfig()
+	require.NotNil(t, oc)
+	require.Equal(t, "kafka", oc.ConsensusType())
+	require.Equal(t, ab.ConsensusType_STATE_NORMAL, oc.ConsensusState())
+	require.Equal(t, uint32(10), oc.BatchSize().MaxMessageCount)
+	require.Equal(t, []string{"kafkabroker"}, oc.KafkaBrokers())
+	require.Equal(t, uint64(200), oc.MaxChannelsCount())
+	require.Equal(t, []string{"V1_4_2"}, oc.Capabilities())
+	require.Empty(t, oc.Endpoints())
+
+	// The mock block has an invalid batch timeout
+	require.Equal(t, time.Duration(0), oc.BatchTimeout())
+}
+
+func TestNew(t *testing.T) {
+	config := mockConfig(t)
+
+	org1 := config.ChannelGroup.Groups[fabricchannelconfig.ApplicationGroupKey].Groups["Org1MSP"]
+	org1.Values[fabricchannelconfig.AnchorPeersKey] = &cb.ConfigValue{
+		Value: protoutil.MarshalOrPanic(&pb.AnchorPeers{AnchorPeers: []*pb.AnchorPeer{{Host: "peer0.org1", Port: 7051}}}),
+	}
+
+	ordererGroup := config.ChannelGroup.Groups[fabricchannelconfig.OrdererGroupKey]
+	ordererGroup.Groups["OrdererMSP"].Values[fabricchannelconfig.EndpointsKey] = &cb.ConfigValue{
+		Value: protoutil.MarshalOrPanic(&cb.OrdererAddresses{Addresses: []string{"orderer0:7050", "orderer1:7050"}}),
+	}
+	ordererGroup.Values[fabricchannelconfig.BatchTimeoutKey] = &cb.ConfigValue{
+		Value: protoutil.MarshalOrPanic(&ab.BatchTimeout{Timeout: "2s"}),
+	}
+
+	cc, err := New(config)
+	require.NoError(t, err)
+	require.Empty(t, cc.ChannelID())
+
+	anchorPeers := cc.ApplicationConfig().Organizations()["Org1MSP"].AnchorPeers()
+	require.Len(t, anchorPeers, 1)
+	require.Equal(t, "peer0.org1", anchorPeers[0].Host)
+	require.Equal(t, int32(7051), anchorPeers[0].Port)
+
+	require.Equal(t, []string{"orderer0:7050", "orderer1:7050"}, cc.OrdererConfig().Endpoints())
+	require.Equal(t, []string{"orderer0:7050", "orderer1:7050"}, cc.OrdererConfig().Organizations()["OrdererMSP"].Endpoints())
+	require.Equal(t, 2*time.Second, cc.OrdererConfig().BatchTimeout())
+
+	t.Run("Idemix org", func(t *testing.T) {
+		config := mockConfig(t)
+		config.ChannelGroup.Groups[fabricchannelconfig.ApplicationGroupKey].Groups["Org2MSP"].Values[fabricchannelconfig.MSPKey] = &cb.ConfigValue{
+			Value: protoutil.MarshalOrPanic(&mb.MSPConfig{
+				Type:   1,
+				Config: protoutil.MarshalOrPanic(&mb.IdemixMSPConfig{Name: "IdemixOrg"}),
+			}),
+		}
+
+		cc, err := New(config)
+		require.NoError(t, err)
+
+		org := cc.ApplicationConfig().Organizations()["Org2MSP"]
+		require.Equal(t, "IdemixOrg", org.MSPID())
+		require.Nil(t, org.FabricMSPConfig())
+		require.Equal(t, int32(1), org.MSPConfig().Type)
+	})
+
+	t.Run("Unknown value", func(t *testing.T) {
+		config := mockConfig(t)
+		config.ChannelGroup.Values["Unknown"] = &cb.ConfigValue{}
+
+		_, err := New(config)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to deserialize channel values")
+	})
+
+	t.Run("Unknown group", func(t *testing.T) {
+		config := mockConfig(t)
+		config.ChannelGroup.Groups["Unknown"] = &cb.ConfigGroup{}
+
+		_, err := New(config)
+		require.EqualError(t, err, "disallowed channel group: Unknown")
+	})
+
+	t.Run("Org with sub-groups", func(t *testing.T) {
+		config := mockConfig(t)
+		config.ChannelGroup.Groups[fabricchannelconfig.OrdererGroupKey].Groups["OrdererMSP"].Groups = map[string]*cb.ConfigGroup{"Sub": {}}
+
+		_, err := New(config)
+		require.EqualError(t, err, "invalid orderer org OrdererMSP: organizations do not support sub-groups")
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		_, err := New(nil)
+		require.EqualError(t, err, "config has no channel group")
+
+		_, err = NewFromBlock(nil)
+		require.EqualError(t, err, "missing block")
+
+		_, err = NewFromBlock(mocks.NewSimpleMockBlock())
+		require.Error(t, err)
+	})
+}
+
+func TestConsortiums(t *testing.T) {
+	config := mockConfig(t)
+	delete(config.ChannelGroup.Groups, fabricchannelconfig.ApplicationGroupKey)
+
+	consortiumGroup := &cb.ConfigGroup{
+		Groups: map[string]*cb.ConfigGroup{
+			"Org1MSP": proto.Clone(config.ChannelGroup.Groups[fabricchannelconfig.OrdererGroupKey].Groups["OrdererMSP"]).(*cb.ConfigGroup),
+		},
+		Values: map[string]*cb.ConfigValue{
+			fabricchannelconfig.ChannelCreationPolicyKey: {
+				Value: protoutil.MarshalOrPanic(&cb.Policy{Type: int32(cb.Policy_IMPLICIT_META)}),
+			},
+		},
+	}
+	config.ChannelGroup.Groups[fabricchannelconfig.ConsortiumsGroupKey] = &cb.ConfigGroup{
+		Groups: map[string]*cb.ConfigGroup{"SampleConsortium": consortiumGroup},
+	}
+
+	cc, err := New(config)
+	require.NoError(t, err)
+	require.Nil(t, cc.ApplicationConfig())
+
+	consortium, ok := cc.Consortiums()["SampleConsortium"]
+	require.True(t, ok)
+	require.Equal(t, int32(cb.Policy_IMPLICIT_META), consortium.ChannelCreationPolicy().Type)
+	require.Equal(t, "OrdererMSP", consortium.Organizations()["Org1MSP"].MSPID())
+}
+
+func mockConfigBlock() *cb.Block {
+	builder := &mocks.MockConfigBlockBuilder{
+		MockConfigGroupBuilder: mocks.MockConfigGroupBuilder{
+			ModPolicy:               "Admins",
+			MSPNames:                []string{"Org1MSP", "Org2MSP"},
+			OrdererAddress:          "orderer:7050",
+			ChannelCapabilities:     []string{"V1_4_3"},
+			OrdererCapabilities:     []string{"V1_4_2"},
+			ApplicationCapabilities: []string{"V1_4_2"},
+			PolicyRefs:              []string{"Readers", "/Channel/Orderer/Admins"},
+		},
+		ChannelID: "mychannel",
+	}
+	return builder.Build()
+}
+
+func mockConfig(t *testing.T) *cb.Config {
+	env, err := protoutil.ExtractEnvelope(mockConfigBlock(), 0)
+	require.NoError(t, err)
+
+	configEnv := &cb.ConfigEnvelope{}
+	_, err = protoutil.UnmarshalEnvelopeOfType(env, cb.HeaderType_CONFIG, configEnv)
+	require.NoError(t, err)
+
+	return configEnv.Config
+}
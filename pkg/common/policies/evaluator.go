@@ -0,0 +1,417 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"crypto/x509"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+)
+
+// maxPrincipalSets bounds the number of principal combinations which are enumerated for a policy
+const maxPrincipalSets = 100000
+
+// default NodeOU identifiers, as generated by cryptogen and the Fabric CA
+const (
+	defaultClientOU  = "client"
+	defaultPeerOU    = "peer"
+	defaultAdminOU   = "admin"
+	defaultOrdererOU = "orderer"
+)
+
+// EvaluationIdentity is an identity which is evaluated against a signature policy
+type EvaluationIdentity struct {
+	// Identity is the serialized identity
+	Identity *mb.SerializedIdentity
+	// Data is the data signed by the identity. It is only used if Signature is set.
+	Data []byte
+	// Signature is an optional signature of Data. If it is set, the identity is only taken
+	// into account if the signature is valid.
+	Signature []byte
+}
+
+// RejectedIdentity is an identity which was not taken into account by the evaluation
+type RejectedIdentity struct {
+	// Index is the index of the identity in the evaluated identities
+	Index int
+	// Err explains why the identity was rejected
+	Err error
+}
+
+// RuleEvaluation is the result of evaluating a rule of a signature policy
+type RuleEvaluation struct {
+	// Rule is the evaluated rule
+	Rule *cb.SignaturePolicy
+	// Satisfied is true if the rule is satisfied
+	Satisfied bool
+	// Principal is the principal required by a SignedBy rule
+	Principal *mb.MSPPrincipal
+	// Identity is the index of the identity which satisfied a SignedBy rule, or -1 if none did.
+	// As in Fabric, the identity is only consumed if all the enclosing rules are satisfied.
+	Identity int
+	// Rules are the evaluations of the sub-rules of an NOutOf rule
+	Rules []*RuleEvaluation
+}
+
+// SignaturePolicyEvaluation is the result of evaluating a signature policy against a set of identities
+type SignaturePolicyEvaluation struct {
+	// Satisfied is true if the identities satisfy the policy
+	Satisfied bool
+	// Rule is the evaluation of the rule of the policy
+	Rule *RuleEvaluation
+	// MissingPrincipals is a minimal set of principals whose identities, added to the evaluated ones, satisfy
+	// the policy. It is empty if the policy is satisfied or cannot be satisfied by any set of identities. It is
+	// also empty if the identities satisfy the principals of the policy, but not in the order in which Fabric
+	// assigns identities to principals, or if the policy has too many combinations of principals to compute it.
+	MissingPrincipals []*mb.MSPPrincipal
+	// Rejected are the identities which could not be deserialized, are not valid or carry an invalid signature
+	Rejected []*RejectedIdentity
+}
+
+// EvaluateOption configures the evaluation of a signature policy
+type EvaluateOption func(opts *evaluateOptions)
+
+type evaluateOptions struct {
+	mspManager *MSPManager
+	err        error
+}
+
+// WithMSPConfigs validates and classifies the identities with the given MSP configs. Without MSP configs,
+// identities are not validated and are classified by their certificate alone, i.e. admin, client, peer and
+// orderer roles are granted according to the default NodeOU identifiers of their certificates.
+func WithMSPConfigs(configs ...*mb.MSPConfig) EvaluateOption {
+	return func(opts *evaluateOptions) {
+		opts.mspManager, opts.err = NewMSPManager(configs...)
+	}
+}
+
+// WithMSPManager validates and classifies the identities with the given MSP manager, e.g. the one returned
+// by NewMSPManagerFromConfig
+func WithMSPManager(mspManager *MSPManager) EvaluateOption {
+	return func(opts *evaluateOptions) {
+		opts.mspManager = mspManager
+	}
+}
+
+// EvaluateSignaturePolicy evaluates the signature policy envelope against the given identities, following Fabric's
+// rules for assigning identities to principals. Identities which cannot be used are reported in the result rather
+// than failing the evaluation; an error is only returned if the envelope or the MSP configs are invalid.
+func EvaluateSignaturePolicy(envelope *cb.SignaturePolicyEnvelope, identities []*EvaluationIdentity, opts ...EvaluateOption) (*SignaturePolicyEvaluation, error) {
+	if envelope == nil || envelope.Rule == nil {
+		return nil, errors.New("signature policy envelope has no rule")
+	}
+
+	if envelope.Version != 0 {
+		return nil, errors.Errorf("unsupported signature policy version %d", envelope.Version)
+	}
+
+	if err := checkRule(envelope.Rule, len(envelope.Identities)); err != nil {
+		return nil, err
+	}
+
+	options := &evaluateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.err != nil {
+		return nil, options.err
+	}
+
+	result := &SignaturePolicyEvaluation{}
+
+	var valid []*Identity
+	var indexes []int
+	var seen [][]byte
+	for i, ei := range identities {
+		id, err := options.identity(ei)
+		if err != nil {
+			result.Rejected = append(result.Rejected, &RejectedIdentity{Index: i, Err: err})
+			continue
+		}
+
+		if containsBytes(seen, id.Cert.Raw) {
+			logger.Debugf("Ignoring duplicate identity %d", i)
+			continue
+		}
+
+		seen = append(seen, id.Cert.Raw)
+		valid = append(valid, id)
+		indexes = append(indexes, i)
+	}
+
+	used := make([]bool, len(valid))
+	result.Rule = evaluateRule(envelope.Rule, envelope.Identities, valid, used)
+	result.Satisfied = result.Rule.Satisfied
+	result.Rule.mapIdentities(indexes)
+
+	if result.Satisfied {
+		return result, nil
+	}
+
+	sets, err := principalSets(envelope.Rule)
+	if err != nil {
+		logger.Warnf("Could not compute the missing principals of the policy: %s", err)
+		return result, nil
+	}
+
+	result.MissingPrincipals = minimalMissingPrincipals(sets, envelope.Identities, valid)
+
+	return result, nil
+}
+
+func (opts *evaluateOptions) identity(ei *EvaluationIdentity) (*Identity, error) {
+	if ei == nil || ei.Identity == nil {
+		return nil, errors.New("identity is nil")
+	}
+
+	var id *Identity
+	if opts.mspManager != nil {
+		var err error
+		id, err = opts.mspManager.deserialize(ei.Identity)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		cert, err := parseCert(ei.Identity.IdBytes)
+		if err != nil {
+			return nil, err
+		}
+		id = &Identity{
+			MSPID: ei.Identity.Mspid,
+			Cert:  cert,
+			chain: []*x509.Certificate{cert},
+			mgr: &fabricMSP{
+				name: ei.Identity.Mspid,
+				nodeOUs: &nodeOUs{
+					client:  defaultClientOU,
+					peer:    defaultPeerOU,
+					admin:   defaultAdminOU,
+					orderer: defaultOrdererOU,
+				},
+			},
+		}
+	}
+
+	if len(ei.Signature) > 0 {
+		if err := id.Verify(ei.Data, ei.Signature); err != nil {
+			return nil, err
+		}
+	}
+
+	return id, nil
+}
+
+// evaluateRule evaluates the rule as Fabric does: the sub-rules of an NOutOf rule are evaluated in order and
+// each identity satisfies at most one SignedBy rule of the satisfied sub-rules
+func evaluateRule(rule *cb.SignaturePolicy, principals []*mb.MSPPrincipal, identities []*Identity, used []bool) *RuleEvaluation {
+	result := &RuleEvaluation{Rule: rule, Identity: -1}
+
+	switch t := rule.Type.(type) {
+	case *cb.SignaturePolicy_SignedBy:
+		result.Principal = principals[t.SignedBy]
+		for i, id := range identities {
+			if used[i] {
+				continue
+			}
+			if err := id.SatisfiesPrincipal(result.Principal); err == nil {
+				used[i] = true
+				result.Identity = i
+				result.Satisfied = true
+				break
+			}
+		}
+	case *cb.SignaturePolicy_NOutOf_:
+		verified := int32(0)
+		_used := make([]bool, len(used))
+		for _, sub := range t.NOutOf.Rules {
+			copy(_used, used)
+			subResult := evaluateRule(sub, principals, identities, _used)
+			if subResult.Satisfied {
+				verified++
+				copy(used, _used)
+			}
+			result.Rules = append(result.Rules, subResult)
+		}
+		result.Satisfied = verified >= t.NOutOf.N
+	}
+
+	return result
+}
+
+func (r *RuleEvaluation) mapIdentities(indexes []int) {
+	if r.Identity >= 0 {
+		r.Identity = indexes[r.Identity]
+	}
+	for _, sub := range r.Rules {
+		sub.mapIdentities(indexes)
+	}
+}
+
+// principalSets returns the combinations of principals, as indexes into the identities of the envelope,
+// which satisfy the rule. A principal occurs in a combination once for every SignedBy rule referencing it.
+func principalSets(rule *cb.SignaturePolicy) ([][]int32, error) {
+	switch t := rule.Type.(type) {
+	case *cb.SignaturePolicy_SignedBy:
+		return [][]int32{{t.SignedBy}}, nil
+	case *cb.SignaturePolicy_NOutOf_:
+		var subSets [][][]int32
+		for _, sub := range t.NOutOf.Rules {
+			sets, err := principalSets(sub)
+			if err != nil {
+				return nil, err
+			}
+			subSets = append(subSets, sets)
+		}
+
+		var result [][]int32
+		var err error
+		combinations(len(subSets), int(t.NOutOf.N), func(combination []int) bool {
+			if len(result)+productSize(combination, func(i int) int { return len(subSets[i]) }) > maxPrincipalSets {
+				err = errors.Errorf("policy is satisfied by more than %d combinations of principals", maxPrincipalSets)
+				return false
+			}
+
+			sets := [][]int32{{}}
+			for _, i := range combination {
+				var product [][]int32
+				for _, set := range sets {
+					for _, subSet := range subSets[i] {
+						product = append(product, append(append([]int32{}, set...), subSet...))
+					}
+				}
+				sets = product
+			}
+			result = append(result, sets...)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	default:
+		return nil, errors.Errorf("unknown signature policy type: %T", t)
+	}
+}
+
+// productSize returns the number of sets in the product of the principal sets of the combination, given the number
+// of sets of each index, without building the product. Any size above maxPrincipalSets is returned as maxPrincipalSets+1.
+func productSize(combination []int, size func(int) int) int {
+	for _, i := range combination {
+		if size(i) == 0 {
+			return 0
+		}
+	}
+
+	n := 1
+	for _, i := range combination {
+		n *= size(i)
+		if n > maxPrincipalSets {
+			return maxPrincipalSets + 1
+		}
+	}
+	return n
+}
+
+// combinations calls f with every combination of k out of n indexes, until f returns false.
+// There are no combinations if k > n.
+func combinations(n, k int, f func([]int) bool) {
+	if k < 0 {
+		k = 0
+	}
+	if k > n {
+		return
+	}
+
+	combination := make([]int, k)
+	for i := range combination {
+		combination[i] = i
+	}
+
+	for {
+		if !f(combination) {
+			return
+		}
+
+		i := k - 1
+		for i >= 0 && combination[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+
+		combination[i]++
+		for j := i + 1; j < k; j++ {
+			combination[j] = combination[j-1] + 1
+		}
+	}
+}
+
+// minimalMissingPrincipals returns the principals of the combination which needs the fewest principals in addition
+// to the ones satisfied by the identities, each identity satisfying at most one principal
+func minimalMissingPrincipals(sets [][]int32, principals []*mb.MSPPrincipal, identities []*Identity) []*mb.MSPPrincipal {
+	satisfies := make([][]bool, len(principals))
+	for i, principal := range principals {
+		satisfies[i] = make([]bool, len(identities))
+		for j, id := range identities {
+			satisfies[i][j] = id.SatisfiesPrincipal(principal) == nil
+		}
+	}
+
+	var missing []int32
+	found := false
+	for _, set := range sets {
+		m := unmatchedPrincipals(set, satisfies, len(identities))
+		if !found || len(m) < len(missing) {
+			missing = m
+			found = true
+		}
+		if len(missing) == 0 {
+			break
+		}
+	}
+
+	var result []*mb.MSPPrincipal
+	for _, i := range missing {
+		result = append(result, principals[i])
+	}
+	return result
+}
+
+// unmatchedPrincipals computes a maximum matching between the principals of the set and the identities
+// and returns the principals which are left unmatched
+func unmatchedPrincipals(set []int32, satisfies [][]bool, numIdentities int) []int32 {
+	owner := make([]int, numIdentities)
+	for i := range owner {
+		owner[i] = -1
+	}
+
+	var match func(p int, visited []bool) bool
+	match = func(p int, visited []bool) bool {
+		for j := 0; j < numIdentities; j++ {
+			if !satisfies[set[p]][j] || visited[j] {
+				continue
+			}
+			visited[j] = true
+			if owner[j] < 0 || match(owner[j], visited) {
+				owner[j] = p
+				return true
+			}
+		}
+		return false
+	}
+
+	var unmatched []int32
+	for p := range set {
+		if !match(p, make([]bool, numIdentities)) {
+			unmatched = append(unmatched, set[p])
+		}
+	}
+	return unmatched
+}
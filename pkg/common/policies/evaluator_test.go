@@ -0,0 +1,178 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestEvaluateSignaturePolicy(t *testing.T) {
+	org1CA := mocks.NewMockCA("Org1MSP")
+	org1Admin := org1CA.NewIdentity("admin@org1", "admin")
+	org1Peer := org1CA.NewIdentity("peer0.org1", "peer")
+
+	org2CA := mocks.NewMockCA("Org2MSP")
+	org2Admin := org2CA.NewIdentity("admin@org2", "admin")
+	org2Client := org2CA.NewIdentity("user@org2", "client")
+
+	org3CA := mocks.NewMockCA("Org3MSP")
+	org3Admin := org3CA.NewIdentity("admin@org3", "admin")
+
+	envelope, err := cauthdsl.FromString("AND(OR('Org1MSP.admin', 'Org2MSP.admin'), OutOf(2, 'Org1MSP.peer', 'Org2MSP.admin', 'Org3MSP.admin'))")
+	require.NoError(t, err)
+
+	t.Run("Satisfied", func(t *testing.T) {
+		result, err := EvaluateSignaturePolicy(envelope, evaluationIdentities(t, org1Admin, org1Peer, org3Admin))
+		require.NoError(t, err)
+		require.True(t, result.Satisfied)
+		require.Empty(t, result.MissingPrincipals)
+		require.Empty(t, result.Rejected)
+
+		// the OR is evaluated first by the parser and is the first rule of the AND
+		or := result.Rule.Rules[0]
+		require.True(t, or.Satisfied)
+		require.Equal(t, 0, or.Rules[0].Identity)
+		require.Equal(t, -1, or.Rules[1].Identity)
+
+		outOf := result.Rule.Rules[1]
+		require.True(t, outOf.Satisfied)
+		require.Equal(t, 1, outOf.Rules[0].Identity)
+		require.False(t, outOf.Rules[1].Satisfied)
+		require.Equal(t, 2, outOf.Rules[2].Identity)
+	})
+
+	t.Run("Identities are assigned in Fabric's order", func(t *testing.T) {
+		// both admins are consumed by the OR, so the OutOf lacks Org2MSP.admin
+		result, err := EvaluateSignaturePolicy(envelope, evaluationIdentities(t, org1Admin, org1Peer, org2Admin))
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+		require.Equal(t, 0, result.Rule.Rules[0].Rules[0].Identity)
+		require.Equal(t, 2, result.Rule.Rules[0].Rules[1].Identity)
+		require.Empty(t, result.MissingPrincipals)
+	})
+
+	t.Run("Missing principals", func(t *testing.T) {
+		result, err := EvaluateSignaturePolicy(envelope, evaluationIdentities(t, org1Admin, org2Client))
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+		require.True(t, result.Rule.Rules[0].Satisfied)
+		require.False(t, result.Rule.Rules[1].Satisfied)
+		require.Len(t, result.MissingPrincipals, 2)
+		require.Equal(t, "Org1MSP.peer", PrincipalString(result.MissingPrincipals[0]))
+		require.Equal(t, "Org2MSP.admin", PrincipalString(result.MissingPrincipals[1]))
+
+		result, err = EvaluateSignaturePolicy(envelope, evaluationIdentities(t, org2Admin, org3Admin))
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+		require.Len(t, result.MissingPrincipals, 1)
+		require.Equal(t, "Org1MSP.admin", PrincipalString(result.MissingPrincipals[0]))
+
+		result, err = EvaluateSignaturePolicy(cauthdsl.SignedByNOutOfGivenRole(3, mb.MSPRole_ADMIN, []string{"Org1MSP", "Org2MSP"}), nil)
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+		require.Empty(t, result.MissingPrincipals)
+	})
+
+	t.Run("Too many combinations of principals", func(t *testing.T) {
+		var mspIDs []string
+		for i := 0; i < 25; i++ {
+			mspIDs = append(mspIDs, fmt.Sprintf("Org%dMSP", i+1))
+		}
+
+		result, err := EvaluateSignaturePolicy(cauthdsl.SignedByNOutOfGivenRole(10, mb.MSPRole_MEMBER, mspIDs), evaluationIdentities(t, org1Admin))
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+		require.Len(t, result.Rule.Rules, 25)
+		require.Empty(t, result.MissingPrincipals)
+	})
+
+	t.Run("Wide AND of ORs", func(t *testing.T) {
+		// 8^8 combinations of principals, which are rejected before any of them is built
+		var ors []string
+		for i := 0; i < 8; i++ {
+			var principals []string
+			for j := 0; j < 8; j++ {
+				principals = append(principals, fmt.Sprintf("'Org%dMSP.member'", i*8+j+1))
+			}
+			ors = append(ors, "OR("+strings.Join(principals, ", ")+")")
+		}
+		envelope, err := cauthdsl.FromString("AND(" + strings.Join(ors, ", ") + ")")
+		require.NoError(t, err)
+
+		_, err = SignaturePolicyPrincipalSets(envelope)
+		require.EqualError(t, err, "policy is satisfied by more than 100000 combinations of principals")
+
+		result, err := EvaluateSignaturePolicy(envelope, evaluationIdentities(t, org1Admin))
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+		require.Empty(t, result.MissingPrincipals)
+	})
+
+	t.Run("Signatures and MSP configs", func(t *testing.T) {
+		identities := evaluationIdentities(t, org1Admin, org2Admin, org3Admin)
+		identities[0].Data = []byte("data")
+		identities[0].Signature, err = org1Admin.Sign([]byte("data"))
+		require.NoError(t, err)
+		identities[1].Data = []byte("data")
+		identities[1].Signature, err = org2Admin.Sign([]byte("other data"))
+		require.NoError(t, err)
+
+		result, err := EvaluateSignaturePolicy(envelope, identities, WithMSPConfigs(org1CA.MSPConfig(true), org2CA.MSPConfig(true)))
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+		require.Len(t, result.Rejected, 2)
+		require.Equal(t, 1, result.Rejected[0].Index)
+		require.Contains(t, result.Rejected[0].Err.Error(), "signature is not valid")
+		require.Equal(t, 2, result.Rejected[1].Index)
+		require.EqualError(t, result.Rejected[1].Err, "MSP Org3MSP is unknown")
+
+		// without NodeOUs, only the admins of the MSP config are admins
+		result, err = EvaluateSignaturePolicy(cauthdsl.SignedByMspAdmin("Org1MSP"), evaluationIdentities(t, org1Admin), WithMSPConfigs(org1CA.MSPConfig(false)))
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+
+		mspManager, err := NewMSPManager(org1CA.MSPConfig(false, org1Admin))
+		require.NoError(t, err)
+		result, err = EvaluateSignaturePolicy(cauthdsl.SignedByMspAdmin("Org1MSP"), evaluationIdentities(t, org1Admin), WithMSPManager(mspManager))
+		require.NoError(t, err)
+		require.True(t, result.Satisfied)
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		_, err := EvaluateSignaturePolicy(nil, nil)
+		require.EqualError(t, err, "signature policy envelope has no rule")
+
+		_, err = EvaluateSignaturePolicy(cauthdsl.Envelope(cauthdsl.SignedBy(1), nil), nil)
+		require.EqualError(t, err, "identity index out of range, requested 1, but identities length is 0")
+
+		_, err = EvaluateSignaturePolicy(envelope, nil, WithMSPConfigs(&mb.MSPConfig{Config: []byte("invalid")}))
+		require.Error(t, err)
+
+		result, err := EvaluateSignaturePolicy(envelope, []*EvaluationIdentity{nil, {Identity: &mb.SerializedIdentity{Mspid: "Org1MSP"}}})
+		require.NoError(t, err)
+		require.Len(t, result.Rejected, 2)
+		require.EqualError(t, result.Rejected[0].Err, "identity is nil")
+		require.EqualError(t, result.Rejected[1].Err, "could not decode PEM certificate")
+	})
+}
+
+func evaluationIdentities(t *testing.T, signers ...*mocks.MockSigningIdentity) []*EvaluationIdentity {
+	var result []*EvaluationIdentity
+	for _, signer := range signers {
+		result = append(result, &EvaluationIdentity{
+			Identity: &mb.SerializedIdentity{Mspid: signer.MSPID, IdBytes: signer.CertPEM},
+		})
+	}
+	return result
+}
@@ -0,0 +1,479 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+)
+
+var (
+	rolePrincipalRegex     = regexp.MustCompile(`^([[:alnum:].-]+)\.([[:alpha:]]+)$`)
+	ouPrincipalRegex       = regexp.MustCompile(`^([[:alnum:].-]+)\.OU:(.+)$`)
+	identityPrincipalRegex = regexp.MustCompile(`^([[:alnum:].-]+)\.id:(.*)$`)
+)
+
+var expressionRoles = map[string]mb.MSPRole_MSPRoleType{
+	cauthdsl.RoleMember:  mb.MSPRole_MEMBER,
+	cauthdsl.RoleAdmin:   mb.MSPRole_ADMIN,
+	cauthdsl.RoleClient:  mb.MSPRole_CLIENT,
+	cauthdsl.RolePeer:    mb.MSPRole_PEER,
+	cauthdsl.RoleOrderer: mb.MSPRole_ORDERER,
+}
+
+// ExpressionType is the type of a node of a policy expression
+type ExpressionType int
+
+const (
+	// GateExpression requires N of its rules to be satisfied
+	GateExpression ExpressionType = iota
+	// PrincipalExpression requires a signature from an identity satisfying a role or organizational unit principal
+	PrincipalExpression
+	// IdentityExpression requires a signature from the identity with the given certificate hash
+	IdentityExpression
+	// ReferenceExpression requires another policy of the channel config to be satisfied
+	ReferenceExpression
+)
+
+// PolicyExpression is a node of a policy expression parsed by ParsePolicyExpression
+type PolicyExpression struct {
+	// Type is the type of the node
+	Type ExpressionType
+	// Column is the column of the policy string at which the node starts, starting with 1
+	Column int
+	// N is the number of rules of a gate which must be satisfied
+	N int
+	// Rules are the rules of a gate
+	Rules []*PolicyExpression
+	// Principal is the role or organizational unit principal of a principal node
+	Principal *mb.MSPPrincipal
+	// MSPID is the MSP of an identity node
+	MSPID string
+	// CertHash is the SHA256 hash of the DER encoded certificate of an identity node
+	CertHash []byte
+	// PolicyPath is the absolute path of the policy referenced by a reference node, e.g. /Channel/Application/Admins
+	PolicyPath string
+	// ImplicitMeta is the implicit meta rule of a reference node which references the sub-policies of
+	// the sub-groups, e.g. "MAJORITY Admins"
+	ImplicitMeta *cb.ImplicitMetaPolicy
+}
+
+// ParseError is returned when a policy string cannot be parsed
+type ParseError struct {
+	// Column is the column of the policy string at which the problem was found, starting with 1
+	Column int
+	// Msg describes the problem
+	Msg string
+}
+
+// Error returns the error message
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+// ParsePolicyExpression parses a policy string in an extension of the language of SignaturePolicyFromString:
+//
+//	GATE(P[, P...]) or OutOf(N, P[, P...])
+//
+// where GATE is AND or OR (in any case) and P is a nested gate or one of the quoted principals:
+//   - 'MSP.ROLE', where ROLE is member, admin, client, peer or orderer
+//   - 'MSP.OU:NAME', which is satisfied by identities of the MSP with the organizational unit NAME
+//   - 'MSP.id:HASH', which is satisfied by the identity of the MSP whose DER encoded certificate has the hex
+//     encoded SHA256 hash HASH
+//   - '/Channel/...', which is satisfied if the policy with the absolute path is satisfied
+//   - 'ANY|ALL|MAJORITY NAME', which is satisfied as the implicit meta policy over the sub-groups
+//
+// A principal may also be used on its own. Parse errors are returned as a *ParseError.
+func ParsePolicyExpression(policy string) (*PolicyExpression, error) {
+	p := &expressionParser{input: policy}
+	p.next()
+
+	expr, err := p.parseRule()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s after the end of the policy", p.tok)
+	}
+
+	return expr, nil
+}
+
+// HasReferences returns true if the expression references other policies
+func (e *PolicyExpression) HasReferences() bool {
+	if e.Type == ReferenceExpression {
+		return true
+	}
+	for _, rule := range e.Rules {
+		if rule.HasReferences() {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the expression as a policy string which parses into an equal expression, apart from columns
+func (e *PolicyExpression) String() string {
+	switch e.Type {
+	case GateExpression:
+		rules := make([]string, len(e.Rules))
+		for i, rule := range e.Rules {
+			rules[i] = rule.String()
+		}
+		switch {
+		case len(rules) > 0 && e.N == 1:
+			return "OR(" + strings.Join(rules, ", ") + ")"
+		case len(rules) > 0 && e.N == len(rules):
+			return "AND(" + strings.Join(rules, ", ") + ")"
+		default:
+			return cauthdsl.GateOutOf + "(" + strings.Join(append([]string{strconv.Itoa(e.N)}, rules...), ", ") + ")"
+		}
+	case PrincipalExpression:
+		return "'" + PrincipalString(e.Principal) + "'"
+	case IdentityExpression:
+		return "'" + e.MSPID + ".id:" + hex.EncodeToString(e.CertHash) + "'"
+	case ReferenceExpression:
+		if e.ImplicitMeta != nil {
+			return "'" + e.ImplicitMeta.Rule.String() + " " + e.ImplicitMeta.SubPolicy + "'"
+		}
+		return "'" + e.PolicyPath + "'"
+	default:
+		return fmt.Sprintf("invalid expression type %d", e.Type)
+	}
+}
+
+// SignaturePolicyEnvelope compiles the expression into a signature policy envelope. Identity principals are
+// resolved by the hash of their certificate from the given identities. Expressions which reference other
+// policies cannot be compiled, since signature policies cannot reference other policies.
+func (e *PolicyExpression) SignaturePolicyEnvelope(identities ...*mb.SerializedIdentity) (*cb.SignaturePolicyEnvelope, error) {
+	c := &envelopeCompiler{identities: identities}
+
+	rule, err := c.compile(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cb.SignaturePolicyEnvelope{
+		Version:    0,
+		Rule:       rule,
+		Identities: c.principals,
+	}, nil
+}
+
+type envelopeCompiler struct {
+	identities []*mb.SerializedIdentity
+	principals []*mb.MSPPrincipal
+	// allowUnresolved compiles identity principals which cannot be resolved into principals without certificate,
+	// which no identity satisfies
+	allowUnresolved bool
+}
+
+func (c *envelopeCompiler) compile(e *PolicyExpression) (*cb.SignaturePolicy, error) {
+	switch e.Type {
+	case GateExpression:
+		var rules []*cb.SignaturePolicy
+		for _, rule := range e.Rules {
+			compiled, err := c.compile(rule)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, compiled)
+		}
+		return cauthdsl.NOutOf(int32(e.N), rules), nil
+	case PrincipalExpression:
+		return c.signedBy(e.Principal), nil
+	case IdentityExpression:
+		sid := resolveIdentity(e, c.identities)
+		if sid == nil && c.allowUnresolved {
+			sid = &mb.SerializedIdentity{Mspid: e.MSPID}
+		}
+		if sid == nil {
+			return nil, &ParseError{Column: e.Column, Msg: fmt.Sprintf("no certificate of MSP %s has hash %x", e.MSPID, e.CertHash)}
+		}
+		return c.signedBy(&mb.MSPPrincipal{
+			PrincipalClassification: mb.MSPPrincipal_IDENTITY,
+			Principal:               protoutil.MarshalOrPanic(sid),
+		}), nil
+	case ReferenceExpression:
+		return nil, &ParseError{Column: e.Column, Msg: fmt.Sprintf("policy reference %s cannot be expressed in a signature policy", e)}
+	default:
+		return nil, &ParseError{Column: e.Column, Msg: fmt.Sprintf("invalid expression type %d", e.Type)}
+	}
+}
+
+// signedBy returns a rule which requires the principal, reusing the identity of an equal principal
+func (c *envelopeCompiler) signedBy(principal *mb.MSPPrincipal) *cb.SignaturePolicy {
+	for i, p := range c.principals {
+		if proto.Equal(p, principal) {
+			return cauthdsl.SignedBy(int32(i))
+		}
+	}
+	c.principals = append(c.principals, principal)
+	return cauthdsl.SignedBy(int32(len(c.principals) - 1))
+}
+
+// resolveIdentity returns the identity of the MSP of the identity node whose certificate has its hash
+func resolveIdentity(e *PolicyExpression, identities []*mb.SerializedIdentity) *mb.SerializedIdentity {
+	for _, sid := range identities {
+		if sid.Mspid != e.MSPID {
+			continue
+		}
+		cert, err := parseCert(sid.IdBytes)
+		if err != nil {
+			continue
+		}
+		hash := sha256.Sum256(cert.Raw)
+		if string(hash[:]) == string(e.CertHash) {
+			return sid
+		}
+	}
+	return nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokLParen
+	tokRParen
+	tokComma
+	tokInvalid
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	column int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of policy"
+	}
+	return "'" + t.text + "'"
+}
+
+type expressionParser struct {
+	input string
+	pos   int
+	tok   token
+}
+
+func (p *expressionParser) errorf(format string, args ...interface{}) *ParseError {
+	return &ParseError{Column: p.tok.column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// expected returns an error for the current token, which is not the expected one
+func (p *expressionParser) expected(what string) *ParseError {
+	if p.tok.kind == tokInvalid {
+		return p.errorf("%s", p.tok.text)
+	}
+	return p.errorf("expected %s but found %s", what, p.tok)
+}
+
+// next scans the next token. An unterminated string or an unexpected character is returned as an invalid token.
+func (p *expressionParser) next() {
+	for p.pos < len(p.input) && strings.ContainsRune(" \t\r\n", rune(p.input[p.pos])) {
+		p.pos++
+	}
+
+	start := p.pos
+	p.tok = token{column: start + 1}
+	if p.pos >= len(p.input) {
+		p.tok.kind = tokEOF
+		return
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		p.tok.kind, p.tok.text = tokLParen, "("
+	case c == ')':
+		p.pos++
+		p.tok.kind, p.tok.text = tokRParen, ")"
+	case c == ',':
+		p.pos++
+		p.tok.kind, p.tok.text = tokComma, ","
+	case c == '\'' || c == '"':
+		end := strings.IndexByte(p.input[p.pos+1:], c)
+		if end < 0 {
+			p.pos = len(p.input)
+			p.tok.kind, p.tok.text = tokInvalid, "unterminated string"
+			return
+		}
+		p.tok.kind, p.tok.text = tokString, p.input[p.pos+1:p.pos+1+end]
+		p.pos += end + 2
+	case c >= '0' && c <= '9':
+		for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+			p.pos++
+		}
+		p.tok.kind, p.tok.text = tokNumber, p.input[start:p.pos]
+	case isLetter(c):
+		for p.pos < len(p.input) && (isLetter(p.input[p.pos]) || (p.input[p.pos] >= '0' && p.input[p.pos] <= '9')) {
+			p.pos++
+		}
+		p.tok.kind, p.tok.text = tokIdent, p.input[start:p.pos]
+	default:
+		p.pos++
+		p.tok.kind, p.tok.text = tokInvalid, fmt.Sprintf("unexpected character '%c'", c)
+	}
+}
+
+func (p *expressionParser) parseRule() (*PolicyExpression, error) {
+	switch p.tok.kind {
+	case tokIdent:
+		return p.parseGate()
+	case tokString:
+		expr, err := parsePrincipal(p.tok.text, p.tok.column)
+		if err != nil {
+			return nil, err
+		}
+		p.next()
+		return expr, nil
+	default:
+		return nil, p.expected("a gate or a quoted principal")
+	}
+}
+
+func (p *expressionParser) parseGate() (*PolicyExpression, error) {
+	gate := p.tok
+	expr := &PolicyExpression{Type: GateExpression, Column: gate.column}
+
+	name := strings.ToLower(gate.text)
+	switch name {
+	case strings.ToLower(cauthdsl.GateAnd), strings.ToLower(cauthdsl.GateOr), strings.ToLower(cauthdsl.GateOutOf):
+	default:
+		return nil, p.errorf("unknown gate '%s', expected AND, OR or OutOf", gate.text)
+	}
+
+	p.next()
+	if p.tok.kind != tokLParen {
+		return nil, p.expected("'(' after " + gate.text)
+	}
+	p.next()
+
+	if name == strings.ToLower(cauthdsl.GateOutOf) {
+		if p.tok.kind != tokNumber {
+			return nil, p.expected("the number of rules of " + gate.text + " which must be satisfied")
+		}
+		n, err := strconv.Atoi(p.tok.text)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", p.tok.text)
+		}
+		expr.N = n
+		p.next()
+	} else if p.tok.kind == tokRParen {
+		return nil, p.errorf("%s requires at least one rule", gate.text)
+	}
+
+	first := name != strings.ToLower(cauthdsl.GateOutOf)
+	for p.tok.kind != tokRParen {
+		if !first {
+			if p.tok.kind != tokComma {
+				return nil, p.expected("',' or ')'")
+			}
+			p.next()
+		}
+		first = false
+
+		rule, err := p.parseRule()
+		if err != nil {
+			return nil, err
+		}
+		expr.Rules = append(expr.Rules, rule)
+	}
+	p.next()
+
+	switch name {
+	case strings.ToLower(cauthdsl.GateAnd):
+		expr.N = len(expr.Rules)
+	case strings.ToLower(cauthdsl.GateOr):
+		expr.N = 1
+	default:
+		if expr.N > len(expr.Rules) {
+			return nil, &ParseError{Column: gate.column, Msg: fmt.Sprintf("%s requires %d rules to be satisfied, but has only %d", gate.text, expr.N, len(expr.Rules))}
+		}
+	}
+
+	return expr, nil
+}
+
+func parsePrincipal(s string, column int) (*PolicyExpression, error) {
+	errorf := func(format string, args ...interface{}) error {
+		return &ParseError{Column: column, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	switch {
+	case strings.HasPrefix(s, policies.PathSeparator):
+		if len(s) == 1 || strings.HasSuffix(s, policies.PathSeparator) || strings.Contains(s, policies.PathSeparator+policies.PathSeparator) {
+			return nil, errorf("invalid policy path '%s'", s)
+		}
+		return &PolicyExpression{Type: ReferenceExpression, Column: column, PolicyPath: s}, nil
+	case strings.Contains(s, " "):
+		implicitMeta, err := policies.ImplicitMetaFromString(s)
+		if err != nil {
+			return nil, errorf("invalid implicit meta policy '%s': %s", s, err)
+		}
+		return &PolicyExpression{Type: ReferenceExpression, Column: column, ImplicitMeta: implicitMeta}, nil
+	}
+
+	if m := ouPrincipalRegex.FindStringSubmatch(s); m != nil {
+		return &PolicyExpression{
+			Type:   PrincipalExpression,
+			Column: column,
+			Principal: &mb.MSPPrincipal{
+				PrincipalClassification: mb.MSPPrincipal_ORGANIZATION_UNIT,
+				Principal:               protoutil.MarshalOrPanic(&mb.OrganizationUnit{MspIdentifier: m[1], OrganizationalUnitIdentifier: m[2]}),
+			},
+		}, nil
+	}
+
+	if m := identityPrincipalRegex.FindStringSubmatch(s); m != nil {
+		hash, err := hex.DecodeString(m[2])
+		if err != nil || len(hash) != sha256.Size {
+			return nil, errorf("invalid certificate hash '%s' in principal '%s', expected %d hex encoded bytes", m[2], s, sha256.Size)
+		}
+		return &PolicyExpression{Type: IdentityExpression, Column: column, MSPID: m[1], CertHash: hash}, nil
+	}
+
+	if m := rolePrincipalRegex.FindStringSubmatch(s); m != nil {
+		role, ok := expressionRoles[m[2]]
+		if !ok {
+			return nil, errorf("unknown role '%s' in principal '%s', expected member, admin, client, peer or orderer", m[2], s)
+		}
+		return &PolicyExpression{
+			Type:   PrincipalExpression,
+			Column: column,
+			Principal: &mb.MSPPrincipal{
+				PrincipalClassification: mb.MSPPrincipal_ROLE,
+				Principal:               protoutil.MarshalOrPanic(&mb.MSPRole{MspIdentifier: m[1], Role: role}),
+			},
+		}, nil
+	}
+
+	return nil, errorf("invalid principal '%s', expected 'MSP.ROLE', 'MSP.OU:NAME', 'MSP.id:HASH', a policy path or an implicit meta policy", s)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
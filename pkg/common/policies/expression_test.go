@@ -0,0 +1,221 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestParsePolicyExpression(t *testing.T) {
+	org1CA := mocks.NewMockCA("Org1MSP")
+	org1Admin := org1CA.NewIdentity("admin@org1", "admin")
+	hash := sha256.Sum256(org1Admin.Cert.Raw)
+	hexHash := hex.EncodeToString(hash[:])
+
+	t.Run("Principals", func(t *testing.T) {
+		expr, err := ParsePolicyExpression("or('Org1MSP.member', \"Org2.MSP.OU:finance\", 'Org1MSP.id:" + hexHash + "', '/Channel/Application/Admins', 'MAJORITY Admins')")
+		require.NoError(t, err)
+		require.Equal(t, GateExpression, expr.Type)
+		require.Equal(t, 1, expr.N)
+		require.Len(t, expr.Rules, 5)
+		require.True(t, expr.HasReferences())
+
+		require.Equal(t, PrincipalExpression, expr.Rules[0].Type)
+		require.Equal(t, 4, expr.Rules[0].Column)
+		require.Equal(t, "Org1MSP.member", PrincipalString(expr.Rules[0].Principal))
+
+		require.Equal(t, PrincipalExpression, expr.Rules[1].Type)
+		require.Equal(t, mb.MSPPrincipal_ORGANIZATION_UNIT, expr.Rules[1].Principal.PrincipalClassification)
+		require.Equal(t, "Org2.MSP.OU:finance", PrincipalString(expr.Rules[1].Principal))
+
+		require.Equal(t, IdentityExpression, expr.Rules[2].Type)
+		require.Equal(t, "Org1MSP", expr.Rules[2].MSPID)
+		require.Equal(t, hash[:], expr.Rules[2].CertHash)
+
+		require.Equal(t, ReferenceExpression, expr.Rules[3].Type)
+		require.Equal(t, "/Channel/Application/Admins", expr.Rules[3].PolicyPath)
+
+		require.Equal(t, ReferenceExpression, expr.Rules[4].Type)
+		require.Equal(t, cb.ImplicitMetaPolicy_MAJORITY, expr.Rules[4].ImplicitMeta.Rule)
+		require.Equal(t, "Admins", expr.Rules[4].ImplicitMeta.SubPolicy)
+
+		require.Equal(t, "OR('Org1MSP.member', 'Org2.MSP.OU:finance', 'Org1MSP.id:"+hexHash+"', '/Channel/Application/Admins', 'MAJORITY Admins')", expr.String())
+
+		expr, err = ParsePolicyExpression("'Org1MSP.admin'")
+		require.NoError(t, err)
+		require.Equal(t, PrincipalExpression, expr.Type)
+		require.False(t, expr.HasReferences())
+	})
+
+	t.Run("Gates", func(t *testing.T) {
+		expr, err := ParsePolicyExpression("AND('Org1MSP.peer', OutOf(2, 'Org1MSP.admin', 'Org2MSP.admin', 'Org3MSP.admin'), OutOf(0))")
+		require.NoError(t, err)
+		require.Equal(t, 3, expr.N)
+		require.Equal(t, 2, expr.Rules[1].N)
+		require.Equal(t, 21, expr.Rules[1].Column)
+		require.Equal(t, "AND('Org1MSP.peer', OutOf(2, 'Org1MSP.admin', 'Org2MSP.admin', 'Org3MSP.admin'), OutOf(0))", expr.String())
+	})
+
+	t.Run("Parse errors", func(t *testing.T) {
+		tests := []struct {
+			policy string
+			err    string
+		}{
+			{"", "column 1: expected a gate or a quoted principal but found end of policy"},
+			{"XOR('Org1MSP.member')", "column 1: unknown gate 'XOR', expected AND, OR or OutOf"},
+			{"AND 'Org1MSP.member'", "column 5: expected '(' after AND but found 'Org1MSP.member'"},
+			{"AND()", "column 5: AND requires at least one rule"},
+			{"AND('Org1MSP.member' 'Org2MSP.member')", "column 22: expected ',' or ')' but found 'Org2MSP.member'"},
+			{"AND('Org1MSP.member', 'Org2MSP.member'", "column 39: expected ',' or ')' but found end of policy"},
+			{"AND('Org1MSP.member', 'Org2MSP.member)", "column 23: unterminated string"},
+			{"AND('Org1MSP.member'; 'Org2MSP.member')", "column 21: unexpected character ';'"},
+			{"OR('Org1MSP.member', 'Org2MSP.boss')", "column 22: unknown role 'boss' in principal 'Org2MSP.boss', expected member, admin, client, peer or orderer"},
+			{"OR('Org1MSP')", "column 4: invalid principal 'Org1MSP', expected 'MSP.ROLE', 'MSP.OU:NAME', 'MSP.id:HASH', a policy path or an implicit meta policy"},
+			{"OR('Org1MSP.id:abc')", "column 4: invalid certificate hash 'abc' in principal 'Org1MSP.id:abc', expected 32 hex encoded bytes"},
+			{"OR('SOME Admins')", "column 4: invalid implicit meta policy 'SOME Admins': unknown rule type 'SOME', expected ALL, ANY, or MAJORITY"},
+			{"OR('/Channel/')", "column 4: invalid policy path '/Channel/'"},
+			{"OutOf('Org1MSP.member')", "column 7: expected the number of rules of OutOf which must be satisfied but found 'Org1MSP.member'"},
+			{"OutOf(2, 'Org1MSP.member')", "column 1: OutOf requires 2 rules to be satisfied, but has only 1"},
+			{"OR('Org1MSP.member') 'Org2MSP.member'", "column 22: unexpected 'Org2MSP.member' after the end of the policy"},
+		}
+
+		for _, test := range tests {
+			_, err := ParsePolicyExpression(test.policy)
+			require.EqualError(t, err, test.err, test.policy)
+			_, ok := err.(*ParseError)
+			require.True(t, ok)
+		}
+	})
+
+	t.Run("Signature policy envelope", func(t *testing.T) {
+		expr, err := ParsePolicyExpression("OR(AND('Org1MSP.OU:finance', 'Org1MSP.id:" + hexHash + "'), 'Org1MSP.OU:finance')")
+		require.NoError(t, err)
+
+		_, err = expr.SignaturePolicyEnvelope()
+		require.EqualError(t, err, "column 30: no certificate of MSP Org1MSP has hash "+hexHash)
+
+		sid := &mb.SerializedIdentity{Mspid: "Org1MSP", IdBytes: org1Admin.CertPEM}
+		envelope, err := expr.SignaturePolicyEnvelope(sid)
+		require.NoError(t, err)
+		require.Len(t, envelope.Identities, 2)
+		require.Equal(t, mb.MSPPrincipal_IDENTITY, envelope.Identities[1].PrincipalClassification)
+		require.Equal(t, int32(0), envelope.Rule.GetNOutOf().Rules[1].GetSignedBy())
+
+		expr, err = ParsePolicyExpression("OR('Org1MSP.admin', 'ANY Admins')")
+		require.NoError(t, err)
+		_, err = expr.SignaturePolicyEnvelope()
+		require.EqualError(t, err, "column 21: policy reference 'ANY Admins' cannot be expressed in a signature policy")
+	})
+}
+
+func TestExpressionPolicy(t *testing.T) {
+	org1CA := mocks.NewMockCA("Org1MSP")
+	org1Admin := org1CA.NewIdentity("admin@org1", "admin")
+	org1Finance := org1CA.NewIdentity("finance@org1", "client", "finance")
+	org2CA := mocks.NewMockCA("Org2MSP")
+	org2Admin := org2CA.NewIdentity("admin@org2", "admin")
+	org3CA := mocks.NewMockCA("Org3MSP")
+	org3Admin := org3CA.NewIdentity("admin@org3", "admin")
+
+	config := &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				channelconfig.ApplicationGroupKey: {
+					Groups: map[string]*cb.ConfigGroup{
+						"Org1MSP": orgGroup(org1CA),
+						"Org2MSP": orgGroup(org2CA),
+						"Org3MSP": orgGroup(org3CA),
+					},
+					Policies: map[string]*cb.ConfigPolicy{
+						"Admins": configPolicy(policies.ImplicitMetaMajorityPolicy("Admins").Value()),
+					},
+				},
+			},
+		},
+	}
+
+	mspManager, err := NewMSPManagerFromConfig(config)
+	require.NoError(t, err)
+	manager, err := NewManager(channelconfig.ChannelGroupKey, config.ChannelGroup, mspManager)
+	require.NoError(t, err)
+
+	data := []byte("data")
+	hash := sha256.Sum256(org3Admin.Cert.Raw)
+
+	expr, err := ParsePolicyExpression("OR(AND('Org1MSP.OU:finance', 'ALL Admins'), '/Channel/Application/Admins', 'Org3MSP.id:" + hex.EncodeToString(hash[:]) + "')")
+	require.NoError(t, err)
+
+	policy, err := NewExpressionPolicy("/Channel/Application/Custom", expr, manager, mspManager)
+	require.NoError(t, err)
+	require.Equal(t, expr, policy.Expression())
+
+	require.NoError(t, policy.Evaluate(signedData(t, data, org1Finance, org1Admin, org2Admin, org3Admin)))
+	require.NoError(t, policy.Evaluate(signedData(t, data, org1Admin, org2Admin)))
+	require.NoError(t, policy.Evaluate(signedData(t, data, org3Admin)))
+
+	err = policy.Evaluate(signedData(t, data, org1Finance, org2Admin))
+	require.Error(t, err)
+	evalErr, ok := errors.Cause(err).(*EvaluationError)
+	require.True(t, ok)
+	require.Equal(t, "/Channel/Application/Custom", evalErr.Policy)
+	require.NotEmpty(t, evalErr.MissingPrincipals)
+
+	t.Run("Siblings of references", func(t *testing.T) {
+		expr, err := ParsePolicyExpression("AND('Org1MSP.member', 'Org1MSP.member', '/Channel/Application/Admins')")
+		require.NoError(t, err)
+		policy, err := NewExpressionPolicy("/Channel/Application/Custom", expr, manager, mspManager)
+		require.NoError(t, err)
+
+		// an identity satisfies at most one of the principals, as without the reference
+		err = policy.Evaluate(signedData(t, data, org1Admin, org2Admin))
+		require.Error(t, err)
+		_, ok := errors.Cause(err).(*EvaluationError)
+		require.True(t, ok)
+
+		require.NoError(t, policy.Evaluate(signedData(t, data, org1Admin, org1Finance, org2Admin)))
+	})
+
+	t.Run("Reference error", func(t *testing.T) {
+		expr, err := ParsePolicyExpression("OR('Org1MSP.member', '/Channel/Application/Admins')")
+		require.NoError(t, err)
+		policy, err := NewExpressionPolicy("/Channel/Application/Custom", expr, manager, mspManager)
+		require.NoError(t, err)
+		policy.references[expr.Rules[1]] = &failingPolicy{}
+
+		err = policy.Evaluate(signedData(t, data, org2Admin))
+		require.EqualError(t, err, "could not evaluate policy /Channel/Application/Custom: policy failure")
+	})
+
+	t.Run("Invalid references", func(t *testing.T) {
+		expr, err := ParsePolicyExpression("OR('Org1MSP.admin', '/Channel/Orderer/Admins')")
+		require.NoError(t, err)
+		_, err = NewExpressionPolicy("/Channel/Application/Custom", expr, manager, mspManager)
+		require.EqualError(t, err, "column 21: policy /Channel/Orderer/Admins does not exist")
+
+		_, err = NewExpressionPolicy("/Channel/Orderer/Custom", expr, manager, mspManager)
+		require.EqualError(t, err, "the group of policy /Channel/Orderer/Custom does not exist")
+
+		_, err = NewExpressionPolicy("Custom", expr, manager, mspManager)
+		require.EqualError(t, err, "policy name Custom is not an absolute path in /Channel/")
+	})
+}
+
+type failingPolicy struct{}
+
+func (p *failingPolicy) Evaluate([]*SignedData) error {
+	return errors.New("policy failure")
+}
@@ -0,0 +1,208 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+)
+
+// ExpressionPolicy evaluates a policy expression. The rules of the expression which do not reference other
+// policies are evaluated as a signature policy, i.e. each identity satisfies at most one of their principals.
+// Referenced policies are evaluated independently of each other, as the sub-policies of an implicit meta policy are.
+type ExpressionPolicy struct {
+	name       string
+	expression *PolicyExpression
+	mspManager *MSPManager
+	references map[*PolicyExpression]Policy
+}
+
+// NewExpressionPolicy returns a policy which evaluates the expression. The name is the absolute path of the
+// policy, e.g. /Channel/Application/MyPolicy. Policy paths are resolved by the given manager, which manages the
+// channel group, and implicit meta policies by the sub-groups of the group of the policy.
+func NewExpressionPolicy(name string, expression *PolicyExpression, manager *ManagerImpl, mspManager *MSPManager) (*ExpressionPolicy, error) {
+	prefix := policies.PathSeparator + manager.Path() + policies.PathSeparator
+	if !strings.HasPrefix(name, prefix) {
+		return nil, errors.Errorf("policy name %s is not an absolute path in %s", name, prefix)
+	}
+
+	groupPath := strings.Split(name[len(prefix):], policies.PathSeparator)
+	group, ok := manager.SubManager(groupPath[:len(groupPath)-1])
+	if !ok {
+		return nil, errors.Errorf("the group of policy %s does not exist", name)
+	}
+
+	p := &ExpressionPolicy{
+		name:       name,
+		expression: expression,
+		mspManager: mspManager,
+		references: make(map[*PolicyExpression]Policy),
+	}
+
+	if err := p.resolve(expression, manager, group); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *ExpressionPolicy) resolve(e *PolicyExpression, manager, group *ManagerImpl) error {
+	if e.Type != ReferenceExpression {
+		for _, rule := range e.Rules {
+			if err := p.resolve(rule, manager, group); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if e.ImplicitMeta != nil {
+		var names []string
+		for name := range group.managers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var children []*ManagerImpl
+		for _, name := range names {
+			children = append(children, group.managers[name])
+		}
+
+		p.references[e] = NewImplicitMetaPolicy(fmt.Sprintf("%s[%s]", p.name, e), e.ImplicitMeta, children)
+		return nil
+	}
+
+	policy, ok := manager.GetPolicy(e.PolicyPath)
+	if !ok {
+		return &ParseError{Column: e.Column, Msg: fmt.Sprintf("policy %s does not exist", e.PolicyPath)}
+	}
+	p.references[e] = policy
+
+	return nil
+}
+
+// Expression returns the evaluated expression
+func (p *ExpressionPolicy) Expression() *PolicyExpression {
+	return p.expression
+}
+
+// Evaluate returns nil if the signature set satisfies the expression. Otherwise an *EvaluationError
+// listing the principals missing from the unsatisfied rules is returned.
+func (p *ExpressionPolicy) Evaluate(signatureSet []*SignedData) error {
+	satisfied, missing, err := p.evaluate(p.expression, signatureSet)
+	if err != nil {
+		return errors.WithMessagef(err, "could not evaluate policy %s", p.name)
+	}
+
+	if satisfied {
+		return nil
+	}
+
+	return &EvaluationError{
+		Policy:            p.name,
+		MissingPrincipals: missing,
+	}
+}
+
+func (p *ExpressionPolicy) evaluate(e *PolicyExpression, signatureSet []*SignedData) (bool, []*mb.MSPPrincipal, error) {
+	if !e.HasReferences() {
+		return p.evaluateSignaturePolicy(e, signatureSet)
+	}
+
+	if e.Type == ReferenceExpression {
+		err := p.references[e].Evaluate(signatureSet)
+		if err == nil {
+			return true, nil, nil
+		}
+		if evalErr, ok := errors.Cause(err).(*EvaluationError); ok {
+			return false, evalErr.MissingPrincipals, nil
+		}
+		return false, nil, err
+	}
+
+	// Rules with references are evaluated independently. The other rules are evaluated together as a single
+	// signature policy which requires the rest of the threshold, so that an identity satisfies at most one of them.
+	satisfied := 0
+	var missing []*mb.MSPPrincipal
+	var signatureRules []*PolicyExpression
+	for _, rule := range e.Rules {
+		if !rule.HasReferences() {
+			signatureRules = append(signatureRules, rule)
+			continue
+		}
+		ok, m, err := p.evaluate(rule, signatureSet)
+		if err != nil {
+			return false, nil, err
+		}
+		if ok {
+			satisfied++
+			continue
+		}
+		missing = appendPrincipals(missing, m...)
+	}
+
+	if required := e.N - satisfied; required > 0 {
+		if len(signatureRules) == 0 {
+			return false, missing, nil
+		}
+
+		n := required
+		if n > len(signatureRules) {
+			n = len(signatureRules)
+		}
+		gate := &PolicyExpression{Type: GateExpression, Column: e.Column, N: n, Rules: signatureRules}
+		ok, m, err := p.evaluateSignaturePolicy(gate, signatureSet)
+		if err != nil {
+			return false, nil, err
+		}
+		if !ok || required > len(signatureRules) {
+			return false, appendPrincipals(missing, m...), nil
+		}
+	}
+
+	return true, nil, nil
+}
+
+// evaluateSignaturePolicy evaluates an expression without references as a signature policy. Identity principals
+// are resolved from the identities of the signature set, since only those can satisfy them.
+func (p *ExpressionPolicy) evaluateSignaturePolicy(e *PolicyExpression, signatureSet []*SignedData) (bool, []*mb.MSPPrincipal, error) {
+	var identities []*mb.SerializedIdentity
+	for _, sd := range signatureSet {
+		sid := &mb.SerializedIdentity{}
+		if err := proto.Unmarshal(sd.Identity, sid); err == nil {
+			identities = append(identities, sid)
+		}
+	}
+
+	c := &envelopeCompiler{identities: identities, allowUnresolved: true}
+	rule, err := c.compile(e)
+	if err != nil {
+		return false, nil, err
+	}
+	envelope := &cb.SignaturePolicyEnvelope{Rule: rule, Identities: c.principals}
+
+	policy, err := NewSignaturePolicy(p.name, envelope, p.mspManager)
+	if err != nil {
+		return false, nil, err
+	}
+
+	err = policy.Evaluate(signatureSet)
+	if err == nil {
+		return true, nil, nil
+	}
+	if evalErr, ok := errors.Cause(err).(*EvaluationError); ok {
+		return false, evalErr.MissingPrincipals, nil
+	}
+	return false, nil, err
+}
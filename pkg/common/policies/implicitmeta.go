@@ -0,0 +1,112 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+)
+
+// ImplicitMetaPolicy is satisfied when a threshold of the sub-policies with the same name
+// in the child groups are satisfied
+type ImplicitMetaPolicy struct {
+	name        string
+	definition  *cb.ImplicitMetaPolicy
+	subPolicies []Policy
+	threshold   int
+}
+
+// NewImplicitMetaPolicy returns an ImplicitMetaPolicy which evaluates the named sub-policy of each of the given child managers
+func NewImplicitMetaPolicy(name string, definition *cb.ImplicitMetaPolicy, children []*ManagerImpl) *ImplicitMetaPolicy {
+	p := &ImplicitMetaPolicy{
+		name:       name,
+		definition: definition,
+	}
+
+	for _, child := range children {
+		policy, _ := child.GetPolicy(definition.SubPolicy)
+		p.subPolicies = append(p.subPolicies, policy)
+	}
+
+	p.threshold = implicitMetaThreshold(definition.Rule, len(p.subPolicies))
+
+	return p
+}
+
+// Definition returns the implicit meta policy definition
+func (p *ImplicitMetaPolicy) Definition() *cb.ImplicitMetaPolicy {
+	return p.definition
+}
+
+// Evaluate returns nil if the signature set satisfies the threshold of sub-policies. Otherwise an
+// *EvaluationError listing the principals missing from the unsatisfied sub-policies is returned.
+func (p *ImplicitMetaPolicy) Evaluate(signatureSet []*SignedData) error {
+	satisfied := 0
+	var missing []*mb.MSPPrincipal
+
+	for _, policy := range p.subPolicies {
+		err := policy.Evaluate(signatureSet)
+		if err == nil {
+			satisfied++
+			continue
+		}
+
+		if evalErr, ok := errors.Cause(err).(*EvaluationError); ok {
+			missing = appendPrincipals(missing, evalErr.MissingPrincipals...)
+		}
+	}
+
+	if satisfied >= p.threshold {
+		return nil
+	}
+
+	return &EvaluationError{
+		Policy:            p.name,
+		MissingPrincipals: missing,
+		Reason: fmt.Sprintf("%d sub-policies were satisfied, but this policy requires %d of the '%s' sub-policies to be satisfied",
+			satisfied, p.threshold, p.definition.SubPolicy),
+	}
+}
+
+// implicitMetaThreshold returns the number of the given number of sub-policies which must be satisfied
+func implicitMetaThreshold(rule cb.ImplicitMetaPolicy_Rule, subPolicies int) int {
+	// In the special case that there are no policies, consider 0 to be a majority or any
+	if subPolicies == 0 {
+		return 0
+	}
+
+	switch rule {
+	case cb.ImplicitMetaPolicy_ANY:
+		return 1
+	case cb.ImplicitMetaPolicy_ALL:
+		return subPolicies
+	case cb.ImplicitMetaPolicy_MAJORITY:
+		return subPolicies/2 + 1
+	default:
+		return 0
+	}
+}
+
+func appendPrincipals(principals []*mb.MSPPrincipal, add ...*mb.MSPPrincipal) []*mb.MSPPrincipal {
+	for _, p := range add {
+		found := false
+		for _, existing := range principals {
+			if proto.Equal(existing, p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			principals = append(principals, p)
+		}
+	}
+	return principals
+}
@@ -0,0 +1,164 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+)
+
+// Manager resolves policies by path
+type Manager = policies.Manager
+
+// ManagerImpl resolves and evaluates the policies of a config group and its sub-groups
+type ManagerImpl struct {
+	path     string
+	policies map[string]Policy
+	managers map[string]*ManagerImpl
+}
+
+// NewManagerFromConfig returns the policy manager for the channel group of the given config.
+// Identities are validated against the MSPs defined in the config.
+func NewManagerFromConfig(config *cb.Config) (*ManagerImpl, error) {
+	mspManager, err := NewMSPManagerFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewManager(channelconfig.ChannelGroupKey, config.ChannelGroup, mspManager)
+}
+
+// NewManager returns the policy manager for the given config group, where path is the name of the group
+func NewManager(path string, group *cb.ConfigGroup, mspManager *MSPManager) (*ManagerImpl, error) {
+	if group == nil {
+		return nil, errors.New("config group is nil")
+	}
+
+	if strings.Contains(path, policies.PathSeparator) {
+		return nil, errors.Errorf("path cannot contain %s but was %s", policies.PathSeparator, path)
+	}
+
+	return newManager(path, group, mspManager)
+}
+
+func newManager(path string, group *cb.ConfigGroup, mspManager *MSPManager) (*ManagerImpl, error) {
+	m := &ManagerImpl{
+		path:     path,
+		policies: make(map[string]Policy),
+		managers: make(map[string]*ManagerImpl),
+	}
+
+	var children []*ManagerImpl
+	for _, name := range sortedGroupNames(group) {
+		child, err := newManager(path+policies.PathSeparator+name, group.Groups[name], mspManager)
+		if err != nil {
+			return nil, err
+		}
+		m.managers[name] = child
+		children = append(children, child)
+	}
+
+	for name, configPolicy := range group.Policies {
+		fullName := policies.PathSeparator + path + policies.PathSeparator + name
+		policy, err := newPolicy(fullName, configPolicy.Policy, children, mspManager)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "could not create policy %s", fullName)
+		}
+		m.policies[name] = policy
+	}
+
+	for name, child := range m.managers {
+		for policyName, policy := range child.policies {
+			m.policies[name+policies.PathSeparator+policyName] = policy
+		}
+	}
+
+	return m, nil
+}
+
+func newPolicy(name string, policy *cb.Policy, children []*ManagerImpl, mspManager *MSPManager) (Policy, error) {
+	if policy == nil {
+		return &rejectPolicy{name: name, reason: "policy is not defined"}, nil
+	}
+
+	switch policy.Type {
+	case int32(cb.Policy_SIGNATURE):
+		return NewSignaturePolicyFromBytes(name, policy.Value, mspManager)
+	case int32(cb.Policy_IMPLICIT_META):
+		definition := &cb.ImplicitMetaPolicy{}
+		if err := proto.Unmarshal(policy.Value, definition); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal implicit meta policy")
+		}
+		return NewImplicitMetaPolicy(name, definition, children), nil
+	default:
+		logger.Warnf("Policy %s has unsupported type %d and can never be satisfied", name, policy.Type)
+		return &rejectPolicy{name: name, reason: "unsupported policy type " + cb.Policy_PolicyType(policy.Type).String()}, nil
+	}
+}
+
+// GetPolicy returns the policy with the given ID and true if it exists. The ID is either relative to this
+// manager, e.g. "Application/Admins", or absolute, e.g. "/Channel/Application/Admins". If the policy does not
+// exist, a policy which rejects all signatures and false are returned.
+func (m *ManagerImpl) GetPolicy(id string) (Policy, bool) {
+	relpath := id
+	if strings.HasPrefix(id, policies.PathSeparator) {
+		prefix := policies.PathSeparator + m.path + policies.PathSeparator
+		if !strings.HasPrefix(id, prefix) {
+			return &rejectPolicy{name: id, reason: "policy does not exist"}, false
+		}
+		relpath = id[len(prefix):]
+	}
+
+	policy, ok := m.policies[relpath]
+	if !ok {
+		return &rejectPolicy{name: id, reason: "policy does not exist"}, false
+	}
+
+	return policy, true
+}
+
+// Manager returns the sub-manager for the given path relative to this manager and true if it exists
+func (m *ManagerImpl) Manager(path []string) (Manager, bool) {
+	sub, ok := m.SubManager(path)
+	if !ok {
+		return nil, false
+	}
+	return sub, true
+}
+
+// SubManager returns the sub-manager for the given path relative to this manager and true if it exists
+func (m *ManagerImpl) SubManager(path []string) (*ManagerImpl, bool) {
+	current := m
+	for _, name := range path {
+		next, ok := current.managers[name]
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+// Path returns the path of the group managed by this manager, e.g. "Channel/Application"
+func (m *ManagerImpl) Path() string {
+	return m.path
+}
+
+func sortedGroupNames(group *cb.ConfigGroup) []string {
+	var names []string
+	for name := range group.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
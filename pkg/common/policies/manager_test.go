@@ -0,0 +1,144 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"testing"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestManager(t *testing.T) {
+	org1CA := mocks.NewMockCA("Org1MSP")
+	org1Admin := org1CA.NewIdentity("admin@org1", "admin")
+	org2CA := mocks.NewMockCA("Org2MSP")
+	org2Admin := org2CA.NewIdentity("admin@org2", "admin")
+	org3CA := mocks.NewMockCA("Org3MSP")
+	org3Admin := org3CA.NewIdentity("admin@org3", "admin")
+
+	config := &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				channelconfig.ApplicationGroupKey: {
+					Groups: map[string]*cb.ConfigGroup{
+						"Org1MSP": orgGroup(org1CA),
+						"Org2MSP": orgGroup(org2CA),
+						"Org3MSP": orgGroup(org3CA),
+					},
+					Policies: map[string]*cb.ConfigPolicy{
+						"Admins":  configPolicy(policies.ImplicitMetaMajorityPolicy("Admins").Value()),
+						"Writers": configPolicy(policies.ImplicitMetaAnyPolicy("Writers").Value()),
+						"Unknown": configPolicy(&cb.Policy{}),
+					},
+				},
+			},
+			Policies: map[string]*cb.ConfigPolicy{
+				"Admins": configPolicy(policies.ImplicitMetaMajorityPolicy("Admins").Value()),
+				"All":    configPolicy(policies.ImplicitMetaAllPolicy("Admins").Value()),
+			},
+		},
+	}
+
+	manager, err := NewManagerFromConfig(config)
+	require.NoError(t, err)
+	require.Equal(t, channelconfig.ChannelGroupKey, manager.Path())
+
+	data := []byte("data")
+
+	t.Run("GetPolicy", func(t *testing.T) {
+		_, ok := manager.GetPolicy("Admins")
+		require.True(t, ok)
+		_, ok = manager.GetPolicy("Application/Org1MSP/Admins")
+		require.True(t, ok)
+		_, ok = manager.GetPolicy("/Channel/Application/Admins")
+		require.True(t, ok)
+
+		policy, ok := manager.GetPolicy("/Other/Application/Admins")
+		require.False(t, ok)
+		require.Error(t, policy.Evaluate(signedData(t, data, org1Admin)))
+
+		_, ok = manager.GetPolicy("Application/Org9MSP/Admins")
+		require.False(t, ok)
+
+		sub, ok := manager.Manager([]string{channelconfig.ApplicationGroupKey, "Org1MSP"})
+		require.True(t, ok)
+		_, ok = sub.GetPolicy("/Channel/Application/Org1MSP/Admins")
+		require.True(t, ok)
+
+		_, ok = manager.Manager([]string{"Orderer"})
+		require.False(t, ok)
+	})
+
+	t.Run("Implicit meta majority", func(t *testing.T) {
+		policy, ok := manager.GetPolicy(policies.ChannelApplicationAdmins)
+		require.True(t, ok)
+
+		require.NoError(t, policy.Evaluate(signedData(t, data, org1Admin, org2Admin)))
+
+		err := policy.Evaluate(signedData(t, data, org1Admin))
+		require.Error(t, err)
+		evalErr, ok := errors.Cause(err).(*EvaluationError)
+		require.True(t, ok)
+		require.Len(t, evalErr.MissingPrincipals, 2)
+		require.Contains(t, err.Error(), "1 sub-policies were satisfied, but this policy requires 2 of the 'Admins' sub-policies to be satisfied")
+	})
+
+	t.Run("Nested implicit meta", func(t *testing.T) {
+		policy, ok := manager.GetPolicy("Admins")
+		require.True(t, ok)
+		require.NoError(t, policy.Evaluate(signedData(t, data, org1Admin, org3Admin)))
+
+		// The channel has a single child group, the application group, which requires a majority of its orgs
+		policy, ok = manager.GetPolicy("All")
+		require.True(t, ok)
+		require.NoError(t, policy.Evaluate(signedData(t, data, org2Admin, org3Admin)))
+		require.Error(t, policy.Evaluate(signedData(t, data, org3Admin)))
+	})
+
+	t.Run("Implicit meta with missing sub-policies", func(t *testing.T) {
+		policy, ok := manager.GetPolicy("Application/Writers")
+		require.True(t, ok)
+		require.Error(t, policy.Evaluate(nil))
+	})
+
+	t.Run("Unknown policy type", func(t *testing.T) {
+		policy, ok := manager.GetPolicy("Application/Unknown")
+		require.True(t, ok)
+		require.EqualError(t, policy.Evaluate(signedData(t, data, org1Admin)),
+			"signature set did not satisfy policy /Channel/Application/Unknown: unsupported policy type UNKNOWN")
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		_, err := NewManagerFromConfig(&cb.Config{})
+		require.EqualError(t, err, "config has no channel group")
+
+		_, err = NewManager("Channel/Application", &cb.ConfigGroup{}, nil)
+		require.EqualError(t, err, "path cannot contain / but was Channel/Application")
+	})
+}
+
+func orgGroup(ca *mocks.MockCA) *cb.ConfigGroup {
+	return &cb.ConfigGroup{
+		Values: map[string]*cb.ConfigValue{
+			channelconfig.MSPKey: {Value: marshalOrPanic(ca.MSPConfig(true))},
+		},
+		Policies: map[string]*cb.ConfigPolicy{
+			"Admins":  configPolicy(policies.SignaturePolicy("Admins", cauthdsl.SignedByMspAdmin(ca.MSPID)).Value()),
+			"Readers": configPolicy(policies.SignaturePolicy("Readers", cauthdsl.SignedByMspMember(ca.MSPID)).Value()),
+		},
+	}
+}
+
+func configPolicy(policy *cb.Policy) *cb.ConfigPolicy {
+	return &cb.ConfigPolicy{Policy: policy, ModPolicy: "Admins"}
+}
@@ -0,0 +1,562 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"hash"
+	"math/big"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
+	"golang.org/x/crypto/sha3"
+)
+
+// sha3HashFamily identifies the SHA3 signature hash family of an MSP crypto config
+const sha3HashFamily = "SHA3"
+
+// Identity is an X.509 identity which was deserialized and validated by an MSPManager
+type Identity struct {
+	// MSPID is the identifier of the MSP which issued the identity
+	MSPID string
+	// Cert is the certificate of the identity
+	Cert *x509.Certificate
+	// chain is the validation chain of the certificate, starting with the certificate itself
+	chain []*x509.Certificate
+	mgr   *fabricMSP
+}
+
+// MSPManager deserializes and validates the identities of the X.509 (bccsp) MSPs of a channel.
+// Identities of other MSP types, e.g. Idemix, are not supported and fail to deserialize.
+type MSPManager struct {
+	msps map[string]*fabricMSP
+}
+
+type fabricMSP struct {
+	name          string
+	newHash       func() hash.Hash
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	admins        []*x509.Certificate
+	crls          []*crl
+	ouIdentifiers map[string][][]byte
+	nodeOUs       *nodeOUs
+}
+
+type nodeOUs struct {
+	client  string
+	peer    string
+	admin   string
+	orderer string
+}
+
+type crl struct {
+	issuer  []byte
+	serials map[string]bool
+}
+
+// NewMSPManager returns an MSPManager for the given MSP configs. Configs of MSP types other than
+// X.509 (bccsp) are ignored.
+func NewMSPManager(configs ...*mb.MSPConfig) (*MSPManager, error) {
+	m := &MSPManager{msps: make(map[string]*fabricMSP)}
+
+	for _, config := range configs {
+		if config.Type != int32(msp.FABRIC) {
+			logger.Debugf("Ignoring MSP config of type %d", config.Type)
+			continue
+		}
+
+		fabricConfig := &mb.FabricMSPConfig{}
+		if err := proto.Unmarshal(config.Config, fabricConfig); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal fabric MSP config")
+		}
+
+		fm, err := newFabricMSP(fabricConfig)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid config for MSP %s", fabricConfig.Name)
+		}
+
+		m.msps[fm.name] = fm
+	}
+
+	return m, nil
+}
+
+// NewMSPManagerFromConfig returns an MSPManager for the MSPs of all organizations in the channel config
+func NewMSPManagerFromConfig(config *cb.Config) (*MSPManager, error) {
+	if config == nil || config.ChannelGroup == nil {
+		return nil, errors.New("config has no channel group")
+	}
+
+	var configs []*mb.MSPConfig
+	if err := collectMSPConfigs(config.ChannelGroup, &configs); err != nil {
+		return nil, err
+	}
+
+	return NewMSPManager(configs...)
+}
+
+// MSPIDs returns the IDs of the MSPs known to the manager
+func (m *MSPManager) MSPIDs() []string {
+	var ids []string
+	for id := range m.msps {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// DeserializeIdentity deserializes and validates a serialized identity
+func (m *MSPManager) DeserializeIdentity(serializedIdentity []byte) (*Identity, error) {
+	sid := &mb.SerializedIdentity{}
+	if err := proto.Unmarshal(serializedIdentity, sid); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal serialized identity")
+	}
+
+	return m.deserialize(sid)
+}
+
+func (m *MSPManager) deserialize(sid *mb.SerializedIdentity) (*Identity, error) {
+	fm, ok := m.msps[sid.Mspid]
+	if !ok {
+		return nil, errors.Errorf("MSP %s is unknown", sid.Mspid)
+	}
+
+	cert, err := parseCert(sid.IdBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := fm.validate(cert)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "could not validate identity of MSP %s", sid.Mspid)
+	}
+
+	return &Identity{
+		MSPID: sid.Mspid,
+		Cert:  cert,
+		chain: chain,
+		mgr:   fm,
+	}, nil
+}
+
+// Verify checks that the signature over msg was created by the identity. As with Fabric MSPs, the message is
+// hashed with the signature hash family of the MSP, only ECDSA signatures are supported and their S value
+// must be in the lower half of the order of the curve.
+func (id *Identity) Verify(msg, signature []byte) error {
+	pk, ok := id.Cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.Errorf("unsupported public key type %T", id.Cert.PublicKey)
+	}
+
+	sig := &struct{ R, S *big.Int }{}
+	if _, err := asn1.Unmarshal(signature, sig); err != nil {
+		return errors.Wrap(err, "could not unmarshal ECDSA signature")
+	}
+	if sig.R == nil || sig.S == nil || sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return errors.New("invalid ECDSA signature: R and S must be positive")
+	}
+
+	halfOrder := new(big.Int).Rsh(pk.Params().N, 1)
+	if sig.S.Cmp(halfOrder) > 0 {
+		return errors.New("invalid ECDSA signature: S must be smaller than half the order")
+	}
+
+	h := id.mgr.newHash()
+	h.Write(msg)
+	if !ecdsa.Verify(pk, h.Sum(nil), sig.R, sig.S) {
+		return errors.New("signature is not valid")
+	}
+
+	return nil
+}
+
+// SatisfiesPrincipal checks whether the identity matches the given principal
+func (id *Identity) SatisfiesPrincipal(principal *mb.MSPPrincipal) error {
+	switch principal.PrincipalClassification {
+	case mb.MSPPrincipal_ROLE:
+		role := &mb.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, role); err != nil {
+			return errors.Wrap(err, "could not unmarshal MSP role")
+		}
+		if role.MspIdentifier != id.MSPID {
+			return errors.Errorf("identity is a member of %s, not %s", id.MSPID, role.MspIdentifier)
+		}
+		return id.hasRole(role.Role)
+	case mb.MSPPrincipal_ORGANIZATION_UNIT:
+		ou := &mb.OrganizationUnit{}
+		if err := proto.Unmarshal(principal.Principal, ou); err != nil {
+			return errors.Wrap(err, "could not unmarshal organization unit")
+		}
+		if ou.MspIdentifier != id.MSPID {
+			return errors.Errorf("identity is a member of %s, not %s", id.MSPID, ou.MspIdentifier)
+		}
+		if len(ou.CertifiersIdentifier) > 0 && !bytes.Equal(ou.CertifiersIdentifier, chainIdentifier(id.chain[1:])) {
+			return errors.New("identity is not certified by the required certifiers")
+		}
+		if !hasOU(id.Cert, ou.OrganizationalUnitIdentifier) {
+			return errors.Errorf("identity does not have organizational unit %s", ou.OrganizationalUnitIdentifier)
+		}
+		return nil
+	case mb.MSPPrincipal_IDENTITY:
+		sid := &mb.SerializedIdentity{}
+		if err := proto.Unmarshal(principal.Principal, sid); err != nil {
+			return errors.Wrap(err, "could not unmarshal serialized identity")
+		}
+		cert, err := parseCert(sid.IdBytes)
+		if err != nil {
+			return err
+		}
+		if sid.Mspid != id.MSPID || !cert.Equal(id.Cert) {
+			return errors.New("identity does not match the principal")
+		}
+		return nil
+	case mb.MSPPrincipal_ANONYMITY:
+		anonymity := &mb.MSPIdentityAnonymity{}
+		if err := proto.Unmarshal(principal.Principal, anonymity); err != nil {
+			return errors.Wrap(err, "could not unmarshal identity anonymity")
+		}
+		if anonymity.AnonymityType != mb.MSPIdentityAnonymity_NOMINAL {
+			return errors.New("X.509 identities are not anonymous")
+		}
+		return nil
+	case mb.MSPPrincipal_COMBINED:
+		combined := &mb.CombinedPrincipal{}
+		if err := proto.Unmarshal(principal.Principal, combined); err != nil {
+			return errors.Wrap(err, "could not unmarshal combined principal")
+		}
+		if len(combined.Principals) == 0 {
+			return errors.New("combined principal is empty")
+		}
+		for _, p := range combined.Principals {
+			if err := id.SatisfiesPrincipal(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errors.Errorf("invalid principal classification %d", principal.PrincipalClassification)
+	}
+}
+
+func (id *Identity) hasRole(role mb.MSPRole_MSPRoleType) error {
+	fm := id.mgr
+	switch role {
+	case mb.MSPRole_MEMBER:
+		return nil
+	case mb.MSPRole_ADMIN:
+		for _, admin := range fm.admins {
+			if admin.Equal(id.Cert) {
+				return nil
+			}
+		}
+		if fm.nodeOUs != nil && fm.nodeOUs.admin != "" && hasOU(id.Cert, fm.nodeOUs.admin) {
+			return nil
+		}
+		return errors.New("identity is not an admin")
+	case mb.MSPRole_CLIENT:
+		return id.hasNodeOU(fm.nodeOUs.clientOU(), role)
+	case mb.MSPRole_PEER:
+		return id.hasNodeOU(fm.nodeOUs.peerOU(), role)
+	case mb.MSPRole_ORDERER:
+		return id.hasNodeOU(fm.nodeOUs.ordererOU(), role)
+	default:
+		return errors.Errorf("invalid MSP role type %d", role)
+	}
+}
+
+func (id *Identity) hasNodeOU(ou string, role mb.MSPRole_MSPRoleType) error {
+	if ou == "" {
+		return errors.Errorf("NodeOUs are not enabled for role %s in MSP %s", role, id.MSPID)
+	}
+	if !hasOU(id.Cert, ou) {
+		return errors.Errorf("identity is not a %s", role)
+	}
+	return nil
+}
+
+func (n *nodeOUs) clientOU() string {
+	if n == nil {
+		return ""
+	}
+	return n.client
+}
+
+func (n *nodeOUs) peerOU() string {
+	if n == nil {
+		return ""
+	}
+	return n.peer
+}
+
+func (n *nodeOUs) ordererOU() string {
+	if n == nil {
+		return ""
+	}
+	return n.orderer
+}
+
+func newFabricMSP(config *mb.FabricMSPConfig) (*fabricMSP, error) {
+	if config.Name == "" {
+		return nil, errors.New("MSP name is required")
+	}
+
+	newHash, err := signatureHash(config.CryptoConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	fm := &fabricMSP{
+		name:          config.Name,
+		newHash:       newHash,
+		roots:         x509.NewCertPool(),
+		intermediates: x509.NewCertPool(),
+		ouIdentifiers: make(map[string][][]byte),
+	}
+
+	if len(config.RootCerts) == 0 {
+		return nil, errors.New("expected at least one root certificate")
+	}
+
+	for _, pemBytes := range config.RootCerts {
+		cert, err := parseCert(pemBytes)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid root certificate")
+		}
+		fm.roots.AddCert(cert)
+	}
+
+	for _, pemBytes := range config.IntermediateCerts {
+		cert, err := parseCert(pemBytes)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid intermediate certificate")
+		}
+		fm.intermediates.AddCert(cert)
+	}
+
+	for _, pemBytes := range config.Admins {
+		cert, err := parseCert(pemBytes)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid admin certificate")
+		}
+		fm.admins = append(fm.admins, cert)
+	}
+
+	for _, pemBytes := range config.RevocationList {
+		c, err := parseCRL(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		fm.crls = append(fm.crls, c)
+	}
+
+	for _, ouIdentifier := range config.OrganizationalUnitIdentifiers {
+		certifiers, err := fm.certifiersIdentifier(ouIdentifier.Certificate)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid certificate for organizational unit %s", ouIdentifier.OrganizationalUnitIdentifier)
+		}
+		fm.ouIdentifiers[ouIdentifier.OrganizationalUnitIdentifier] = append(fm.ouIdentifiers[ouIdentifier.OrganizationalUnitIdentifier], certifiers)
+	}
+
+	if config.FabricNodeOus != nil && config.FabricNodeOus.Enable {
+		fm.nodeOUs = &nodeOUs{
+			client:  ouName(config.FabricNodeOus.ClientOuIdentifier),
+			peer:    ouName(config.FabricNodeOus.PeerOuIdentifier),
+			admin:   ouName(config.FabricNodeOus.AdminOuIdentifier),
+			orderer: ouName(config.FabricNodeOus.OrdererOuIdentifier),
+		}
+	}
+
+	return fm, nil
+}
+
+// signatureHash returns the hash function of the signature hash family of the crypto config. As with Fabric MSPs,
+// SHA2 is used if the crypto config or its hash family is missing.
+func signatureHash(config *mb.FabricCryptoConfig) (func() hash.Hash, error) {
+	switch family := config.GetSignatureHashFamily(); family {
+	case "", msp.SHA2:
+		return sha256.New, nil
+	case sha3HashFamily:
+		return sha3.New256, nil
+	default:
+		return nil, errors.Errorf("unsupported signature hash family %s", family)
+	}
+}
+
+// validate verifies the certificate against the MSP's trusted certificates and returns its validation chain.
+// As with Fabric MSPs, the expiry of the certificate is not taken into account.
+func (fm *fabricMSP) validate(cert *x509.Certificate) ([]*x509.Certificate, error) {
+	if cert.IsCA {
+		return nil, errors.New("a CA certificate cannot be used as an identity")
+	}
+
+	chain, err := fm.validationChain(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range fm.crls {
+		if c.revokes(chain) {
+			return nil, errors.New("the certificate has been revoked")
+		}
+	}
+
+	if len(fm.ouIdentifiers) > 0 && !fm.hasValidOU(cert, chain) {
+		return nil, errors.New("the certificate does not have a valid organizational unit")
+	}
+
+	if fm.nodeOUs != nil {
+		count := 0
+		for _, ou := range []string{fm.nodeOUs.client, fm.nodeOUs.peer, fm.nodeOUs.admin, fm.nodeOUs.orderer} {
+			if ou != "" && hasOU(cert, ou) {
+				count++
+			}
+		}
+		if count != 1 {
+			return nil, errors.Errorf("the certificate must carry exactly one node organizational unit, found %d", count)
+		}
+	}
+
+	return chain, nil
+}
+
+func (fm *fabricMSP) validationChain(cert *x509.Certificate) ([]*x509.Certificate, error) {
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:         fm.roots,
+		Intermediates: fm.intermediates,
+		CurrentTime:   cert.NotBefore.Add(time.Second),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "the supplied identity is not valid")
+	}
+
+	return chains[0], nil
+}
+
+func (fm *fabricMSP) hasValidOU(cert *x509.Certificate, chain []*x509.Certificate) bool {
+	certifiers := chainIdentifier(chain[1:])
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		for _, id := range fm.ouIdentifiers[ou] {
+			if bytes.Equal(id, certifiers) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (fm *fabricMSP) certifiersIdentifier(pemBytes []byte) ([]byte, error) {
+	cert, err := parseCert(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:         fm.roots,
+		Intermediates: fm.intermediates,
+		CurrentTime:   cert.NotBefore.Add(time.Second),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "the certificate is not issued by the MSP")
+	}
+
+	return chainIdentifier(chains[0]), nil
+}
+
+func (c *crl) revokes(chain []*x509.Certificate) bool {
+	cert := chain[0]
+	if !bytes.Equal(c.issuer, cert.RawIssuer) {
+		return false
+	}
+	return c.serials[cert.SerialNumber.String()]
+}
+
+func parseCRL(pemBytes []byte) (*crl, error) {
+	list, err := x509.ParseCRL(pemBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse revocation list")
+	}
+
+	issuer, err := asn1.Marshal(list.TBSCertList.Issuer)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal revocation list issuer")
+	}
+
+	c := &crl{issuer: issuer, serials: make(map[string]bool)}
+	for _, revoked := range list.TBSCertList.RevokedCertificates {
+		c.serials[revoked.SerialNumber.String()] = true
+	}
+
+	return c, nil
+}
+
+func parseCert(pemBytes []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("could not decode PEM certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse certificate")
+	}
+
+	return cert, nil
+}
+
+func chainIdentifier(chain []*x509.Certificate) []byte {
+	h := sha256.New()
+	for _, cert := range chain {
+		h.Write(cert.Raw)
+	}
+	return h.Sum(nil)
+}
+
+func hasOU(cert *x509.Certificate, ou string) bool {
+	for _, o := range cert.Subject.OrganizationalUnit {
+		if o == ou {
+			return true
+		}
+	}
+	return false
+}
+
+func ouName(ou *mb.FabricOUIdentifier) string {
+	if ou == nil {
+		return ""
+	}
+	return ou.OrganizationalUnitIdentifier
+}
+
+func collectMSPConfigs(group *cb.ConfigGroup, configs *[]*mb.MSPConfig) error {
+	if value, ok := group.Values[channelconfig.MSPKey]; ok {
+		config := &mb.MSPConfig{}
+		if err := proto.Unmarshal(value.Value, config); err != nil {
+			return errors.Wrap(err, "could not unmarshal MSP config")
+		}
+		*configs = append(*configs, config)
+	}
+
+	for _, g := range group.Groups {
+		if err := collectMSPConfigs(g, configs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
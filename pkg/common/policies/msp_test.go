@@ -0,0 +1,208 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestMSPManager(t *testing.T) {
+	org1CA := mocks.NewMockCA("Org1MSP")
+	org1Admin := org1CA.NewIdentity("admin@org1", "admin")
+	org1Peer := org1CA.NewIdentity("peer0.org1", "peer")
+	org1Client := org1CA.NewIdentity("user1@org1", "client", "accounting")
+
+	org2CA := mocks.NewMockCA("Org2MSP")
+	org2Admin := org2CA.NewIdentity("admin@org2")
+	org2Member := org2CA.NewIdentity("user1@org2")
+
+	mspManager, err := NewMSPManager(org1CA.MSPConfig(true), org2CA.MSPConfig(false, org2Admin))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"Org1MSP", "Org2MSP"}, mspManager.MSPIDs())
+
+	t.Run("Roles", func(t *testing.T) {
+		id := deserialize(t, mspManager, org1Admin)
+		require.NoError(t, id.SatisfiesPrincipal(rolePrincipal("Org1MSP", mb.MSPRole_ADMIN)))
+		require.NoError(t, id.SatisfiesPrincipal(rolePrincipal("Org1MSP", mb.MSPRole_MEMBER)))
+		require.Error(t, id.SatisfiesPrincipal(rolePrincipal("Org1MSP", mb.MSPRole_PEER)))
+		require.EqualError(t, id.SatisfiesPrincipal(rolePrincipal("Org2MSP", mb.MSPRole_MEMBER)), "identity is a member of Org1MSP, not Org2MSP")
+
+		id = deserialize(t, mspManager, org1Peer)
+		require.NoError(t, id.SatisfiesPrincipal(rolePrincipal("Org1MSP", mb.MSPRole_PEER)))
+		require.EqualError(t, id.SatisfiesPrincipal(rolePrincipal("Org1MSP", mb.MSPRole_ADMIN)), "identity is not an admin")
+		require.EqualError(t, id.SatisfiesPrincipal(rolePrincipal("Org1MSP", mb.MSPRole_CLIENT)), "identity is not a CLIENT")
+
+		id = deserialize(t, mspManager, org2Admin)
+		require.NoError(t, id.SatisfiesPrincipal(rolePrincipal("Org2MSP", mb.MSPRole_ADMIN)))
+		require.EqualError(t, id.SatisfiesPrincipal(rolePrincipal("Org2MSP", mb.MSPRole_PEER)), "NodeOUs are not enabled for role PEER in MSP Org2MSP")
+
+		id = deserialize(t, mspManager, org2Member)
+		require.NoError(t, id.SatisfiesPrincipal(rolePrincipal("Org2MSP", mb.MSPRole_MEMBER)))
+		require.Error(t, id.SatisfiesPrincipal(rolePrincipal("Org2MSP", mb.MSPRole_ADMIN)))
+	})
+
+	t.Run("Organization unit", func(t *testing.T) {
+		id := deserialize(t, mspManager, org1Client)
+		require.NoError(t, id.SatisfiesPrincipal(&mb.MSPPrincipal{
+			PrincipalClassification: mb.MSPPrincipal_ORGANIZATION_UNIT,
+			Principal:               marshal(t, &mb.OrganizationUnit{MspIdentifier: "Org1MSP", OrganizationalUnitIdentifier: "accounting"}),
+		}))
+		require.NoError(t, id.SatisfiesPrincipal(&mb.MSPPrincipal{
+			PrincipalClassification: mb.MSPPrincipal_ORGANIZATION_UNIT,
+			Principal: marshal(t, &mb.OrganizationUnit{
+				MspIdentifier:                "Org1MSP",
+				OrganizationalUnitIdentifier: "accounting",
+				CertifiersIdentifier:         chainIdentifier(id.chain[1:]),
+			}),
+		}))
+		require.EqualError(t, id.SatisfiesPrincipal(&mb.MSPPrincipal{
+			PrincipalClassification: mb.MSPPrincipal_ORGANIZATION_UNIT,
+			Principal:               marshal(t, &mb.OrganizationUnit{MspIdentifier: "Org1MSP", OrganizationalUnitIdentifier: "sales"}),
+		}), "identity does not have organizational unit sales")
+	})
+
+	t.Run("Identity", func(t *testing.T) {
+		id := deserialize(t, mspManager, org1Client)
+		require.NoError(t, id.SatisfiesPrincipal(identityPrincipal(t, org1Client)))
+		require.EqualError(t, id.SatisfiesPrincipal(identityPrincipal(t, org1Peer)), "identity does not match the principal")
+	})
+
+	t.Run("Combined and anonymity", func(t *testing.T) {
+		id := deserialize(t, mspManager, org1Client)
+		require.NoError(t, id.SatisfiesPrincipal(&mb.MSPPrincipal{
+			PrincipalClassification: mb.MSPPrincipal_COMBINED,
+			Principal: marshal(t, &mb.CombinedPrincipal{Principals: []*mb.MSPPrincipal{
+				rolePrincipal("Org1MSP", mb.MSPRole_CLIENT),
+				{
+					PrincipalClassification: mb.MSPPrincipal_ANONYMITY,
+					Principal:               marshal(t, &mb.MSPIdentityAnonymity{AnonymityType: mb.MSPIdentityAnonymity_NOMINAL}),
+				},
+			}}),
+		}))
+		require.Error(t, id.SatisfiesPrincipal(&mb.MSPPrincipal{
+			PrincipalClassification: mb.MSPPrincipal_ANONYMITY,
+			Principal:               marshal(t, &mb.MSPIdentityAnonymity{AnonymityType: mb.MSPIdentityAnonymity_ANONYMOUS}),
+		}))
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		id := deserialize(t, mspManager, org1Client)
+		sig, err := org1Client.Sign([]byte("message"))
+		require.NoError(t, err)
+		require.NoError(t, id.Verify([]byte("message"), sig))
+		require.Error(t, id.Verify([]byte("other message"), sig))
+
+		// The same signature with S in the upper half of the order is rejected, as by Fabric MSPs
+		ecdsaSig := &struct{ R, S *big.Int }{}
+		_, err = asn1.Unmarshal(sig, ecdsaSig)
+		require.NoError(t, err)
+		highS, err := asn1.Marshal(struct{ R, S *big.Int }{ecdsaSig.R, new(big.Int).Sub(elliptic.P256().Params().N, ecdsaSig.S)})
+		require.NoError(t, err)
+		require.EqualError(t, id.Verify([]byte("message"), highS), "invalid ECDSA signature: S must be smaller than half the order")
+
+		require.Error(t, id.Verify([]byte("message"), []byte("invalid")))
+	})
+
+	t.Run("Signature hash family", func(t *testing.T) {
+		config := org1CA.FabricMSPConfig(true)
+		config.CryptoConfig.SignatureHashFamily = "SHA3"
+		sha3Manager, err := NewMSPManager(&mb.MSPConfig{Config: marshal(t, config)})
+		require.NoError(t, err)
+
+		// The mock identities sign SHA256 hashes
+		id := deserialize(t, sha3Manager, org1Client)
+		sig, err := org1Client.Sign([]byte("message"))
+		require.NoError(t, err)
+		require.EqualError(t, id.Verify([]byte("message"), sig), "signature is not valid")
+
+		config.CryptoConfig.SignatureHashFamily = "MD5"
+		_, err = NewMSPManager(&mb.MSPConfig{Config: marshal(t, config)})
+		require.EqualError(t, err, "invalid config for MSP Org1MSP: unsupported signature hash family MD5")
+	})
+
+	t.Run("Invalid identities", func(t *testing.T) {
+		_, err := mspManager.DeserializeIdentity(serialize(t, mocks.NewMockCA("Org3MSP").NewIdentity("user1@org3")))
+		require.EqualError(t, err, "MSP Org3MSP is unknown")
+
+		// Issued by a different CA
+		id := mocks.NewMockCA("Org2MSP").NewIdentity("user1@org2")
+		_, err = mspManager.DeserializeIdentity(serialize(t, id))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "could not validate identity of MSP Org2MSP")
+
+		// No NodeOU
+		_, err = mspManager.DeserializeIdentity(serialize(t, org1CA.NewIdentity("user2@org1")))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the certificate must carry exactly one node organizational unit, found 0")
+
+		// CA certificate
+		_, err = mspManager.DeserializeIdentity(marshal(t, &mb.SerializedIdentity{Mspid: "Org1MSP", IdBytes: org1CA.CertPEM}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "a CA certificate cannot be used as an identity")
+
+		_, err = mspManager.DeserializeIdentity([]byte("invalid"))
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		_, err := NewMSPManager(&mb.MSPConfig{Config: marshal(t, &mb.FabricMSPConfig{Name: "Org1MSP"})})
+		require.EqualError(t, err, "invalid config for MSP Org1MSP: expected at least one root certificate")
+
+		// Idemix configs are ignored
+		mgr, err := NewMSPManager(&mb.MSPConfig{Type: 1, Config: []byte("idemix")})
+		require.NoError(t, err)
+		require.Empty(t, mgr.MSPIDs())
+	})
+}
+
+func deserialize(t *testing.T, mspManager *MSPManager, signer *mocks.MockSigningIdentity) *Identity {
+	id, err := mspManager.DeserializeIdentity(serialize(t, signer))
+	require.NoError(t, err)
+	return id
+}
+
+func serialize(t *testing.T, signer *mocks.MockSigningIdentity) []byte {
+	sid, err := signer.Serialize()
+	require.NoError(t, err)
+	return sid
+}
+
+func rolePrincipal(mspID string, role mb.MSPRole_MSPRoleType) *mb.MSPPrincipal {
+	return &mb.MSPPrincipal{
+		PrincipalClassification: mb.MSPPrincipal_ROLE,
+		Principal:               marshalOrPanic(&mb.MSPRole{MspIdentifier: mspID, Role: role}),
+	}
+}
+
+func identityPrincipal(t *testing.T, signer *mocks.MockSigningIdentity) *mb.MSPPrincipal {
+	return &mb.MSPPrincipal{
+		PrincipalClassification: mb.MSPPrincipal_IDENTITY,
+		Principal:               serialize(t, signer),
+	}
+}
+
+func marshal(t *testing.T, msg proto.Message) []byte {
+	b, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	return b
+}
+
+func marshalOrPanic(msg proto.Message) []byte {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,306 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+)
+
+// PrincipalSet is a collection of MSPPrincipals
+type PrincipalSet = policies.PrincipalSet
+
+// PrincipalSets aggregates PrincipalSets
+type PrincipalSets = policies.PrincipalSets
+
+// NormalizeSignaturePolicy returns a simplified envelope which is satisfied by the same principal sets:
+//   - equal identities are merged and identities which are not referenced are removed
+//   - nested ORs are flattened into their OR, and nested ANDs into their AND
+//   - gates with a single rule are replaced by the rule, and duplicate rules of an OR are removed
+//   - rules which are always or never satisfied are removed from their gate
+//
+// The rule of the result is always a gate. A gate without rules which requires 0 rules is always satisfied,
+// and one which requires 1 rule is never satisfied; SignaturePolicyToString cannot render either of them.
+// Identities are ordered by their first reference.
+func NormalizeSignaturePolicy(envelope *cb.SignaturePolicyEnvelope) (*cb.SignaturePolicyEnvelope, error) {
+	if envelope == nil || envelope.Rule == nil {
+		return nil, errors.New("signature policy envelope has no rule")
+	}
+
+	if envelope.Version != 0 {
+		return nil, errors.Errorf("unsupported signature policy version %d", envelope.Version)
+	}
+
+	if err := checkRule(envelope.Rule, len(envelope.Identities)); err != nil {
+		return nil, err
+	}
+
+	// index every identity by the first identity which is equal to it
+	merged := make([]int32, len(envelope.Identities))
+	for i, principal := range envelope.Identities {
+		merged[i] = int32(i)
+		for j := 0; j < i; j++ {
+			if proto.Equal(envelope.Identities[j], principal) {
+				merged[i] = int32(j)
+				break
+			}
+		}
+	}
+
+	rule := simplifyRule(mergeIdentities(envelope.Rule, merged))
+	if _, ok := rule.Type.(*cb.SignaturePolicy_SignedBy); ok {
+		rule = cauthdsl.NOutOf(1, []*cb.SignaturePolicy{rule})
+	}
+
+	result := &cb.SignaturePolicyEnvelope{Version: 0}
+	result.Rule = compactIdentities(rule, envelope.Identities, make(map[int32]int32), &result.Identities)
+
+	return result, nil
+}
+
+// SignaturePolicyPrincipalSets returns the minimal principal sets which satisfy the envelope, i.e. every
+// principal set which satisfies the envelope contains one of them. A principal occurs in a set as many
+// times as distinct identities satisfying it are required.
+func SignaturePolicyPrincipalSets(envelope *cb.SignaturePolicyEnvelope) (PrincipalSets, error) {
+	normalized, err := NormalizeSignaturePolicy(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	sets, err := principalSets(normalized.Rule)
+	if err != nil {
+		return nil, err
+	}
+
+	var result PrincipalSets
+	for _, set := range minimalSets(sets) {
+		principalSet := make(PrincipalSet, len(set))
+		for i, index := range set {
+			principalSet[i] = normalized.Identities[index]
+		}
+		result = append(result, principalSet)
+	}
+
+	return result, nil
+}
+
+// EquivalentSignaturePolicies returns true if the envelopes are satisfied by exactly the same principal sets.
+// Principals are compared by value, so relationships between principals, e.g. that an admin of an MSP is also
+// a member of it, are not taken into account.
+func EquivalentSignaturePolicies(envelope1, envelope2 *cb.SignaturePolicyEnvelope) (bool, error) {
+	sets1, err := SignaturePolicyPrincipalSets(envelope1)
+	if err != nil {
+		return false, err
+	}
+
+	sets2, err := SignaturePolicyPrincipalSets(envelope2)
+	if err != nil {
+		return false, err
+	}
+
+	keys1 := principalSetKeys(sets1)
+	keys2 := principalSetKeys(sets2)
+	if len(keys1) != len(keys2) {
+		return false, nil
+	}
+	for i := range keys1 {
+		if keys1[i] != keys2[i] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// SatisfiedBy returns the minimal principal sets which satisfy the policy
+func (p *SignaturePolicy) SatisfiedBy() []PrincipalSet {
+	sets, err := SignaturePolicyPrincipalSets(p.envelope)
+	if err != nil {
+		logger.Warnf("Could not compute the principal sets of policy %s: %s", p.name, err)
+		return nil
+	}
+	return sets
+}
+
+func mergeIdentities(rule *cb.SignaturePolicy, merged []int32) *cb.SignaturePolicy {
+	switch t := rule.Type.(type) {
+	case *cb.SignaturePolicy_SignedBy:
+		return cauthdsl.SignedBy(merged[t.SignedBy])
+	case *cb.SignaturePolicy_NOutOf_:
+		var rules []*cb.SignaturePolicy
+		for _, sub := range t.NOutOf.Rules {
+			rules = append(rules, mergeIdentities(sub, merged))
+		}
+		return cauthdsl.NOutOf(t.NOutOf.N, rules)
+	default:
+		return rule
+	}
+}
+
+func simplifyRule(rule *cb.SignaturePolicy) *cb.SignaturePolicy {
+	gate, ok := rule.Type.(*cb.SignaturePolicy_NOutOf_)
+	if !ok {
+		return rule
+	}
+
+	n := int(gate.NOutOf.N)
+	var rules []*cb.SignaturePolicy
+	for _, sub := range gate.NOutOf.Rules {
+		sub = simplifyRule(sub)
+		switch {
+		case alwaysSatisfied(sub):
+			n--
+		case neverSatisfied(sub):
+		default:
+			rules = append(rules, sub)
+		}
+	}
+
+	if n <= 0 {
+		return cauthdsl.NOutOf(0, nil)
+	}
+	if n > len(rules) {
+		return cauthdsl.NOutOf(1, nil)
+	}
+
+	or := n == 1
+	and := n == len(rules)
+
+	var flattened []*cb.SignaturePolicy
+	for _, sub := range rules {
+		subGate, ok := sub.Type.(*cb.SignaturePolicy_NOutOf_)
+		switch {
+		case ok && or && subGate.NOutOf.N == 1:
+			flattened = append(flattened, subGate.NOutOf.Rules...)
+		case ok && and && int(subGate.NOutOf.N) == len(subGate.NOutOf.Rules):
+			flattened = append(flattened, subGate.NOutOf.Rules...)
+			n += len(subGate.NOutOf.Rules) - 1
+		default:
+			flattened = append(flattened, sub)
+		}
+	}
+	rules = flattened
+
+	if n == 1 {
+		var unique []*cb.SignaturePolicy
+		for _, sub := range rules {
+			if !containsRule(unique, sub) {
+				unique = append(unique, sub)
+			}
+		}
+		rules = unique
+	}
+
+	if n == 1 && len(rules) == 1 {
+		return rules[0]
+	}
+
+	return cauthdsl.NOutOf(int32(n), rules)
+}
+
+func alwaysSatisfied(rule *cb.SignaturePolicy) bool {
+	gate, ok := rule.Type.(*cb.SignaturePolicy_NOutOf_)
+	return ok && gate.NOutOf.N <= 0
+}
+
+func neverSatisfied(rule *cb.SignaturePolicy) bool {
+	gate, ok := rule.Type.(*cb.SignaturePolicy_NOutOf_)
+	return ok && int(gate.NOutOf.N) > len(gate.NOutOf.Rules)
+}
+
+func containsRule(rules []*cb.SignaturePolicy, rule *cb.SignaturePolicy) bool {
+	for _, r := range rules {
+		if proto.Equal(r, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// compactIdentities renumbers the identities referenced by the rule in the order of their first reference
+func compactIdentities(rule *cb.SignaturePolicy, identities []*mb.MSPPrincipal, indexes map[int32]int32, result *[]*mb.MSPPrincipal) *cb.SignaturePolicy {
+	switch t := rule.Type.(type) {
+	case *cb.SignaturePolicy_SignedBy:
+		index, ok := indexes[t.SignedBy]
+		if !ok {
+			index = int32(len(*result))
+			indexes[t.SignedBy] = index
+			*result = append(*result, identities[t.SignedBy])
+		}
+		return cauthdsl.SignedBy(index)
+	case *cb.SignaturePolicy_NOutOf_:
+		var rules []*cb.SignaturePolicy
+		for _, sub := range t.NOutOf.Rules {
+			rules = append(rules, compactIdentities(sub, identities, indexes, result))
+		}
+		return cauthdsl.NOutOf(t.NOutOf.N, rules)
+	default:
+		return rule
+	}
+}
+
+// minimalSets sorts the sets and removes the ones which contain another set, counting duplicate indexes
+func minimalSets(sets [][]int32) [][]int32 {
+	sorted := make([][]int32, len(sets))
+	for i, set := range sets {
+		sorted[i] = append([]int32{}, set...)
+		sort.Slice(sorted[i], func(a, b int) bool { return sorted[i][a] < sorted[i][b] })
+	}
+
+	// smaller sets first, so that a set is only compared with the sets it may contain
+	sort.SliceStable(sorted, func(a, b int) bool { return len(sorted[a]) < len(sorted[b]) })
+
+	var result [][]int32
+	for _, set := range sorted {
+		minimal := true
+		for _, m := range result {
+			if containsSet(set, m) {
+				minimal = false
+				break
+			}
+		}
+		if minimal {
+			result = append(result, set)
+		}
+	}
+
+	return result
+}
+
+// containsSet returns true if the sorted multiset contains the sorted multiset sub
+func containsSet(set, sub []int32) bool {
+	i := 0
+	for _, index := range set {
+		if i < len(sub) && sub[i] == index {
+			i++
+		}
+	}
+	return i == len(sub)
+}
+
+// principalSetKeys returns a sorted list of keys which identify the principal sets independently of the order
+// of the sets and of the principals within them
+func principalSetKeys(sets PrincipalSets) []string {
+	var keys []string
+	for _, set := range sets {
+		var principals []string
+		for _, principal := range set {
+			principals = append(principals, principal.PrincipalClassification.String()+":"+string(principal.Principal))
+		}
+		sort.Strings(principals)
+		keys = append(keys, strings.Join(principals, "\x00"))
+	}
+	sort.Strings(keys)
+	return keys
+}
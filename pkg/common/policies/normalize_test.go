@@ -0,0 +1,176 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+)
+
+func TestNormalizeSignaturePolicy(t *testing.T) {
+	// the principals of the Always and Never MSPs stand for gates without rules, which policy strings
+	// cannot express: OutOf(0), which is always satisfied, and OutOf(1), which is never satisfied
+	tests := []struct {
+		policy     string
+		normalized string
+	}{
+		{"OR('Org1MSP.member')", "OR('Org1MSP.member')"},
+		{"OR(OR('Org1MSP.member', 'Org2MSP.member'), OR('Org3MSP.member', 'Org1MSP.member'))", "OR('Org1MSP.member', 'Org2MSP.member', 'Org3MSP.member')"},
+		{"AND('Org1MSP.admin', AND('Org2MSP.admin', AND('Org3MSP.admin')))", "AND('Org1MSP.admin', 'Org2MSP.admin', 'Org3MSP.admin')"},
+		{"AND('Org1MSP.admin', 'Org1MSP.admin')", "AND('Org1MSP.admin', 'Org1MSP.admin')"},
+		{"OR(AND('Org1MSP.admin'), 'Always.member', 'Org2MSP.admin')", "OutOf(0)"},
+		{"AND('Always.member', 'Org1MSP.admin', OR('Org2MSP.peer', 'Never.member'))", "AND('Org1MSP.admin', 'Org2MSP.peer')"},
+		{"OutOf(2, OR('Org1MSP.admin', 'Org2MSP.admin'), 'Never.member', 'Org3MSP.admin')", "AND(OR('Org1MSP.admin', 'Org2MSP.admin'), 'Org3MSP.admin')"},
+		{"AND('Org1MSP.admin', OutOf(3, 'Org2MSP.admin', 'Org3MSP.admin'))", "OutOf(1)"},
+	}
+
+	for _, test := range tests {
+		envelope := envelopeWithRuleLessGates(t, test.policy)
+
+		normalized, err := NormalizeSignaturePolicy(envelope)
+		require.NoError(t, err)
+
+		s := normalizedString(t, normalized)
+		require.Equal(t, test.normalized, s, test.policy)
+
+		equivalent, err := EquivalentSignaturePolicies(envelope, normalized)
+		require.NoError(t, err)
+		require.True(t, equivalent, test.policy)
+	}
+
+	t.Run("Identities", func(t *testing.T) {
+		envelope, err := cauthdsl.FromString("OR(AND('Org1MSP.admin', 'Org2MSP.admin'), AND('Org2MSP.admin', 'Org1MSP.admin'))")
+		require.NoError(t, err)
+		require.Len(t, envelope.Identities, 4)
+
+		// unused and duplicate identities
+		envelope.Identities = append(envelope.Identities, envelope.Identities[0])
+
+		normalized, err := NormalizeSignaturePolicy(envelope)
+		require.NoError(t, err)
+		require.Len(t, normalized.Identities, 2)
+		require.Equal(t, "Org1MSP.admin", PrincipalString(normalized.Identities[0]))
+		require.Equal(t, "Org2MSP.admin", PrincipalString(normalized.Identities[1]))
+
+		s, err := SignaturePolicyToString(normalized)
+		require.NoError(t, err)
+		require.Equal(t, "OR(AND('Org1MSP.admin', 'Org2MSP.admin'), AND('Org2MSP.admin', 'Org1MSP.admin'))", s)
+
+		normalized, err = NormalizeSignaturePolicy(cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{[]byte("id")}))
+		require.NoError(t, err)
+		require.Equal(t, cauthdsl.NOutOf(1, []*cb.SignaturePolicy{cauthdsl.SignedBy(0)}), normalized.Rule)
+	})
+
+	t.Run("Invalid envelope", func(t *testing.T) {
+		_, err := NormalizeSignaturePolicy(&cb.SignaturePolicyEnvelope{})
+		require.EqualError(t, err, "signature policy envelope has no rule")
+
+		_, err = NormalizeSignaturePolicy(cauthdsl.Envelope(cauthdsl.SignedBy(2), nil))
+		require.EqualError(t, err, "identity index out of range, requested 2, but identities length is 0")
+	})
+}
+
+func TestEquivalentSignaturePolicies(t *testing.T) {
+	tests := []struct {
+		policy1    string
+		policy2    string
+		equivalent bool
+	}{
+		{"OR('Org1MSP.member', 'Org2MSP.member')", "OR('Org2MSP.member', 'Org1MSP.member')", true},
+		{"AND('Org1MSP.admin', OR('Org2MSP.admin', 'Org3MSP.admin'))", "OR(AND('Org1MSP.admin', 'Org2MSP.admin'), AND('Org3MSP.admin', 'Org1MSP.admin'))", true},
+		{"OutOf(2, 'Org1MSP.admin', 'Org2MSP.admin', 'Org3MSP.admin')", "OR(AND('Org1MSP.admin', 'Org2MSP.admin'), AND('Org1MSP.admin', 'Org3MSP.admin'), AND('Org2MSP.admin', 'Org3MSP.admin'))", true},
+		{"OR('Org1MSP.admin', AND('Org1MSP.admin', 'Org2MSP.admin'))", "OR('Org1MSP.admin')", true},
+		{"OutOf(2, 'Org1MSP.admin', 'Org2MSP.admin', 'Org3MSP.admin')", "AND('Org1MSP.admin', 'Org2MSP.admin')", false},
+		{"AND('Org1MSP.admin', 'Org1MSP.admin')", "OR('Org1MSP.admin')", false},
+		{"OR('Org1MSP.admin')", "OR('Org1MSP.member')", false},
+	}
+
+	for _, test := range tests {
+		envelope1, err := cauthdsl.FromString(test.policy1)
+		require.NoError(t, err)
+		envelope2, err := cauthdsl.FromString(test.policy2)
+		require.NoError(t, err)
+
+		equivalent, err := EquivalentSignaturePolicies(envelope1, envelope2)
+		require.NoError(t, err)
+		require.Equal(t, test.equivalent, equivalent, "%s <=> %s", test.policy1, test.policy2)
+	}
+
+	t.Run("Principal sets", func(t *testing.T) {
+		envelope, err := cauthdsl.FromString("OR(AND('Org1MSP.admin', 'Org2MSP.admin'), 'Org3MSP.admin', AND('Org3MSP.admin', 'Org1MSP.admin'))")
+		require.NoError(t, err)
+
+		sets, err := SignaturePolicyPrincipalSets(envelope)
+		require.NoError(t, err)
+		require.Len(t, sets, 2)
+		require.Len(t, sets[0], 1)
+		require.Equal(t, "Org3MSP.admin", PrincipalString(sets[0][0]))
+		require.Len(t, sets[1], 2)
+
+		policy, err := NewSignaturePolicy("policy", envelope, nil)
+		require.NoError(t, err)
+		require.Equal(t, []PrincipalSet(sets), policy.SatisfiedBy())
+
+		sets, err = SignaturePolicyPrincipalSets(cauthdsl.SignedByNOutOfGivenRole(3, mb.MSPRole_MEMBER, []string{"Org1MSP"}))
+		require.NoError(t, err)
+		require.Empty(t, sets)
+	})
+
+	t.Run("Invalid envelope", func(t *testing.T) {
+		_, err := EquivalentSignaturePolicies(&cb.SignaturePolicyEnvelope{}, cauthdsl.SignedByMspAdmin("Org1MSP"))
+		require.Error(t, err)
+		_, err = EquivalentSignaturePolicies(cauthdsl.SignedByMspAdmin("Org1MSP"), &cb.SignaturePolicyEnvelope{})
+		require.Error(t, err)
+	})
+}
+
+// envelopeWithRuleLessGates parses the policy string and replaces the rules which require a principal of
+// the Always or Never MSP by a gate without rules which requires 0 or 1 rules respectively
+func envelopeWithRuleLessGates(t *testing.T, policy string) *cb.SignaturePolicyEnvelope {
+	envelope, err := cauthdsl.FromString(policy)
+	require.NoError(t, err)
+
+	gates := map[string]int32{"Always": 0, "Never": 1}
+
+	var replace func(rule *cb.SignaturePolicy) *cb.SignaturePolicy
+	replace = func(rule *cb.SignaturePolicy) *cb.SignaturePolicy {
+		switch t := rule.Type.(type) {
+		case *cb.SignaturePolicy_SignedBy:
+			role := &mb.MSPRole{}
+			if err := proto.Unmarshal(envelope.Identities[t.SignedBy].Principal, role); err == nil {
+				if n, ok := gates[role.MspIdentifier]; ok {
+					return cauthdsl.NOutOf(n, nil)
+				}
+			}
+		case *cb.SignaturePolicy_NOutOf_:
+			for i, r := range t.NOutOf.Rules {
+				t.NOutOf.Rules[i] = replace(r)
+			}
+		}
+		return rule
+	}
+	envelope.Rule = replace(envelope.Rule)
+
+	return envelope
+}
+
+// normalizedString renders a normalized envelope, including a gate without rules which
+// SignaturePolicyToString rejects
+func normalizedString(t *testing.T, envelope *cb.SignaturePolicyEnvelope) string {
+	if gate := envelope.Rule.GetNOutOf(); gate != nil && len(gate.Rules) == 0 {
+		return fmt.Sprintf("OutOf(%d)", gate.N)
+	}
+	s, err := SignaturePolicyToString(envelope)
+	require.NoError(t, err)
+	return s
+}
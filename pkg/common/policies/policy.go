@@ -0,0 +1,102 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/common/logging"
+)
+
+var logger = logging.NewLogger("fablibgoext")
+
+// SignedData is a signature together with the data it signs and the serialized identity of its creator
+type SignedData = protoutil.SignedData
+
+// Policy evaluates whether a set of signatures satisfies a policy
+type Policy = policies.Policy
+
+// EvaluationError is returned when a set of signatures does not satisfy a policy
+type EvaluationError struct {
+	// Policy is the name of the policy which was not satisfied
+	Policy string
+	// MissingPrincipals are the principals for which no valid signature was supplied
+	MissingPrincipals []*mb.MSPPrincipal
+	// Reason explains why the policy could not be satisfied when no principals are missing
+	Reason string
+}
+
+// Error returns the error message
+func (e *EvaluationError) Error() string {
+	msg := fmt.Sprintf("signature set did not satisfy policy %s", e.Policy)
+	if len(e.MissingPrincipals) > 0 {
+		var principals []string
+		for _, p := range e.MissingPrincipals {
+			principals = append(principals, PrincipalString(p))
+		}
+		msg += fmt.Sprintf(", missing signatures from [%s]", strings.Join(principals, ", "))
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// PrincipalString returns a human readable representation of the principal, e.g. 'Org1MSP.admin'
+func PrincipalString(principal *mb.MSPPrincipal) string {
+	switch principal.PrincipalClassification {
+	case mb.MSPPrincipal_ROLE:
+		role := &mb.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, role); err != nil {
+			return "invalid role"
+		}
+		return role.MspIdentifier + "." + strings.ToLower(role.Role.String())
+	case mb.MSPPrincipal_ORGANIZATION_UNIT:
+		ou := &mb.OrganizationUnit{}
+		if err := proto.Unmarshal(principal.Principal, ou); err != nil {
+			return "invalid organization unit"
+		}
+		return ou.MspIdentifier + ".OU:" + ou.OrganizationalUnitIdentifier
+	case mb.MSPPrincipal_IDENTITY:
+		sid := &mb.SerializedIdentity{}
+		if err := proto.Unmarshal(principal.Principal, sid); err != nil {
+			return "invalid identity"
+		}
+		if cert, err := parseCert(sid.IdBytes); err == nil {
+			return sid.Mspid + ".identity:" + cert.Subject.CommonName
+		}
+		return sid.Mspid + ".identity"
+	case mb.MSPPrincipal_COMBINED:
+		combined := &mb.CombinedPrincipal{}
+		if err := proto.Unmarshal(principal.Principal, combined); err != nil {
+			return "invalid combined principal"
+		}
+		var principals []string
+		for _, p := range combined.Principals {
+			principals = append(principals, PrincipalString(p))
+		}
+		return "combined(" + strings.Join(principals, ", ") + ")"
+	default:
+		return principal.PrincipalClassification.String()
+	}
+}
+
+// rejectPolicy is a policy which can never be satisfied
+type rejectPolicy struct {
+	name   string
+	reason string
+}
+
+// Evaluate always returns an error
+func (p *rejectPolicy) Evaluate(signatureSet []*SignedData) error {
+	return &EvaluationError{Policy: p.name, Reason: p.reason}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+)
+
+var roleNames = map[mb.MSPRole_MSPRoleType]string{
+	mb.MSPRole_MEMBER:  cauthdsl.RoleMember,
+	mb.MSPRole_ADMIN:   cauthdsl.RoleAdmin,
+	mb.MSPRole_CLIENT:  cauthdsl.RoleClient,
+	mb.MSPRole_PEER:    cauthdsl.RolePeer,
+	mb.MSPRole_ORDERER: cauthdsl.RoleOrderer,
+}
+
+// SignaturePolicyToString renders a signature policy envelope as a policy string which SignaturePolicyFromString
+// parses into an equivalent envelope. The rendering is canonical: a gate which requires one of its rules is
+// rendered as OR, a gate which requires all of its rules as AND, and any other gate as OutOf, e.g.
+// "OR('Org1.member', AND('Org2.admin', 'Org3.peer'))". Only role principals may be rendered, since the language
+// has no other principals, and gates without rules may not, since the language requires at least one rule.
+//
+// SignaturePolicyFromString assigns identities in the order in which it evaluates the principals, i.e. the
+// principals of nested gates before those of the enclosing gate, so parsing the result returns the envelope itself
+// if its identities are referenced once each in that order, as they are in envelopes produced by the builder
+// functions. A rule which is a bare principal is rendered as OutOf(1, principal), since the language requires a gate.
+func SignaturePolicyToString(envelope *cb.SignaturePolicyEnvelope) (string, error) {
+	if envelope == nil || envelope.Rule == nil {
+		return "", errors.New("signature policy envelope has no rule")
+	}
+	if envelope.Version != 0 {
+		return "", errors.Errorf("unsupported signature policy version %d", envelope.Version)
+	}
+
+	principals := make([]string, len(envelope.Identities))
+	for i, identity := range envelope.Identities {
+		principal, err := principalToString(identity)
+		if err != nil {
+			return "", errors.WithMessagef(err, "identity %d", i)
+		}
+		principals[i] = principal
+	}
+
+	if signedBy, ok := envelope.Rule.Type.(*cb.SignaturePolicy_SignedBy); ok {
+		principal, err := signedByToString(signedBy.SignedBy, principals)
+		if err != nil {
+			return "", err
+		}
+		return cauthdsl.GateOutOf + "(1, " + principal + ")", nil
+	}
+
+	return ruleToString(envelope.Rule, principals)
+}
+
+func ruleToString(rule *cb.SignaturePolicy, principals []string) (string, error) {
+	switch t := rule.Type.(type) {
+	case *cb.SignaturePolicy_SignedBy:
+		return signedByToString(t.SignedBy, principals)
+	case *cb.SignaturePolicy_NOutOf_:
+		n := int(t.NOutOf.N)
+		if len(t.NOutOf.Rules) == 0 {
+			return "", errors.Errorf("gate %s(%d) without rules cannot be expressed in a policy string", cauthdsl.GateOutOf, n)
+		}
+
+		rules := make([]string, len(t.NOutOf.Rules))
+		for i, r := range t.NOutOf.Rules {
+			s, err := ruleToString(r, principals)
+			if err != nil {
+				return "", err
+			}
+			rules[i] = s
+		}
+
+		// same bounds as the parser, so that the result can be parsed
+		if n < 0 || n > len(rules)+1 {
+			return "", errors.Errorf("invalid t-out-of-n predicate, t %d, n %d", n, len(rules))
+		}
+
+		switch {
+		case n == 1:
+			return strings.ToUpper(cauthdsl.GateOr) + "(" + strings.Join(rules, ", ") + ")", nil
+		case n == len(rules):
+			return strings.ToUpper(cauthdsl.GateAnd) + "(" + strings.Join(rules, ", ") + ")", nil
+		default:
+			return fmt.Sprintf("%s(%d, %s)", cauthdsl.GateOutOf, n, strings.Join(rules, ", ")), nil
+		}
+	default:
+		return "", errors.Errorf("unknown signature policy type %T", rule.Type)
+	}
+}
+
+func signedByToString(index int32, principals []string) (string, error) {
+	if index < 0 || int(index) >= len(principals) {
+		return "", errors.Errorf("identity index %d out of range, envelope has %d identities", index, len(principals))
+	}
+	return principals[index], nil
+}
+
+func principalToString(principal *mb.MSPPrincipal) (string, error) {
+	if principal.PrincipalClassification != mb.MSPPrincipal_ROLE {
+		return "", errors.Errorf("principal classification %s cannot be expressed in a policy string", principal.PrincipalClassification)
+	}
+
+	role := &mb.MSPRole{}
+	if err := proto.Unmarshal(principal.Principal, role); err != nil {
+		return "", errors.Wrap(err, "invalid role principal")
+	}
+
+	roleName, ok := roleNames[role.Role]
+	if !ok {
+		return "", errors.Errorf("role %s cannot be expressed in a policy string", role.Role)
+	}
+
+	s := role.MspIdentifier + "." + roleName
+	if !rolePrincipalRegex.MatchString(s) {
+		return "", errors.Errorf("MSP identifier '%s' cannot be expressed in a policy string", role.MspIdentifier)
+	}
+	return "'" + s + "'", nil
+}
@@ -0,0 +1,234 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+)
+
+// ResolvedPolicy is a policy of a channel config whose implicit meta rule is expanded into the sub-policies it refers to
+type ResolvedPolicy struct {
+	// Path is the absolute path of the policy, e.g. /Channel/Application/Admins
+	Path string
+	// Type is the type of the policy. It is UNKNOWN for a sub-policy which is not defined in its group.
+	Type cb.Policy_PolicyType
+	// Envelope is the envelope of a signature policy
+	Envelope *cb.SignaturePolicyEnvelope
+	// ImplicitMeta is the rule of an implicit meta policy
+	ImplicitMeta *cb.ImplicitMetaPolicy
+	// Threshold is the number of sub-policies of an implicit meta policy which must be satisfied
+	Threshold int
+	// SubPolicies are the resolved sub-policies of an implicit meta policy, one for each sub-group in the order of
+	// the names of the sub-groups
+	SubPolicies []*ResolvedPolicy
+	// PrincipalSets are the minimal principal sets which satisfy the policy, i.e. the combinations of principals
+	// whose signatures are needed. It is empty if the policy can never be satisfied, and contains an empty set if
+	// the policy is satisfied without signatures.
+	PrincipalSets PrincipalSets
+}
+
+// ResolvePolicy resolves the policy with the given absolute path, e.g. /Channel/Application/Admins, in the channel
+// config. Implicit meta policies are expanded recursively into the policies of the sub-groups and the principal sets
+// which satisfy the policy are computed. As in Fabric, an identity may satisfy the sub-policies of several groups.
+func ResolvePolicy(config *cb.Config, path string) (*ResolvedPolicy, error) {
+	if config == nil || config.ChannelGroup == nil {
+		return nil, errors.New("config has no channel group")
+	}
+
+	prefix := policies.PathSeparator + channelconfig.ChannelGroupKey + policies.PathSeparator
+	if !strings.HasPrefix(path, prefix) {
+		return nil, errors.Errorf("policy path %s does not start with %s", path, prefix)
+	}
+
+	elements := strings.Split(path[len(prefix):], policies.PathSeparator)
+	group := config.ChannelGroup
+	for _, name := range elements[:len(elements)-1] {
+		next, ok := group.Groups[name]
+		if !ok {
+			return nil, errors.Errorf("group %s of policy %s does not exist", name, path)
+		}
+		group = next
+	}
+
+	name := elements[len(elements)-1]
+	configPolicy, ok := group.Policies[name]
+	if !ok || configPolicy.Policy == nil {
+		return nil, errors.Errorf("policy %s does not exist", path)
+	}
+
+	r := &policyResolver{sets: make(map[*ResolvedPolicy][][]int32)}
+	resolved, err := r.resolve(path, configPolicy.Policy, group)
+	if err != nil {
+		return nil, err
+	}
+
+	r.setPrincipalSets(resolved)
+
+	return resolved, nil
+}
+
+type policyResolver struct {
+	principals []*mb.MSPPrincipal
+	// sets are the principal sets of the resolved policies, as sorted indexes into the principals
+	sets map[*ResolvedPolicy][][]int32
+}
+
+func (r *policyResolver) resolve(path string, policy *cb.Policy, group *cb.ConfigGroup) (*ResolvedPolicy, error) {
+	resolved := &ResolvedPolicy{Path: path, Type: cb.Policy_PolicyType(policy.Type)}
+
+	switch resolved.Type {
+	case cb.Policy_SIGNATURE:
+		envelope := &cb.SignaturePolicyEnvelope{}
+		if err := proto.Unmarshal(policy.Value, envelope); err != nil {
+			return nil, errors.Wrapf(err, "could not unmarshal signature policy %s", path)
+		}
+		resolved.Envelope = envelope
+
+		sets, err := SignaturePolicyPrincipalSets(envelope)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "could not resolve signature policy %s", path)
+		}
+		r.sets[resolved] = r.indexSets(sets)
+	case cb.Policy_IMPLICIT_META:
+		definition := &cb.ImplicitMetaPolicy{}
+		if err := proto.Unmarshal(policy.Value, definition); err != nil {
+			return nil, errors.Wrapf(err, "could not unmarshal implicit meta policy %s", path)
+		}
+		resolved.ImplicitMeta = definition
+
+		groupPath := path[:strings.LastIndex(path, policies.PathSeparator)]
+		for _, name := range sortedGroupNames(group) {
+			subGroup := group.Groups[name]
+			subPath := groupPath + policies.PathSeparator + name + policies.PathSeparator + definition.SubPolicy
+
+			subPolicy, ok := subGroup.Policies[definition.SubPolicy]
+			if !ok || subPolicy.Policy == nil {
+				sub := &ResolvedPolicy{Path: subPath, Type: cb.Policy_UNKNOWN}
+				r.sets[sub] = nil
+				resolved.SubPolicies = append(resolved.SubPolicies, sub)
+				continue
+			}
+
+			sub, err := r.resolve(subPath, subPolicy.Policy, subGroup)
+			if err != nil {
+				return nil, err
+			}
+			resolved.SubPolicies = append(resolved.SubPolicies, sub)
+		}
+
+		resolved.Threshold = implicitMetaThreshold(definition.Rule, len(resolved.SubPolicies))
+
+		sets, err := r.implicitMetaSets(resolved)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "could not resolve implicit meta policy %s", path)
+		}
+		r.sets[resolved] = sets
+	default:
+		logger.Warnf("Policy %s has unsupported type %d and can never be satisfied", path, policy.Type)
+		r.sets[resolved] = nil
+	}
+
+	return resolved, nil
+}
+
+// implicitMetaSets combines the principal sets of every threshold of sub-policies. Since the sub-policies are
+// evaluated independently, a principal is required as often as the sub-policy which requires it most often does.
+func (r *policyResolver) implicitMetaSets(policy *ResolvedPolicy) ([][]int32, error) {
+	var result [][]int32
+	var err error
+	combinations(len(policy.SubPolicies), policy.Threshold, func(combination []int) bool {
+		if len(result)+productSize(combination, func(i int) int { return len(r.sets[policy.SubPolicies[i]]) }) > maxPrincipalSets {
+			err = errors.Errorf("policy is satisfied by more than %d combinations of principals", maxPrincipalSets)
+			return false
+		}
+
+		sets := [][]int32{{}}
+		for _, i := range combination {
+			var product [][]int32
+			for _, set := range sets {
+				for _, subSet := range r.sets[policy.SubPolicies[i]] {
+					product = append(product, unionSets(set, subSet))
+				}
+			}
+			sets = product
+		}
+		result = append(result, sets...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return minimalSets(result), nil
+}
+
+func (r *policyResolver) indexSets(sets PrincipalSets) [][]int32 {
+	var result [][]int32
+	for _, set := range sets {
+		indexes := make([]int32, len(set))
+		for i, principal := range set {
+			indexes[i] = r.index(principal)
+		}
+		sort.Slice(indexes, func(a, b int) bool { return indexes[a] < indexes[b] })
+		result = append(result, indexes)
+	}
+	return result
+}
+
+func (r *policyResolver) index(principal *mb.MSPPrincipal) int32 {
+	for i, p := range r.principals {
+		if proto.Equal(p, principal) {
+			return int32(i)
+		}
+	}
+	r.principals = append(r.principals, principal)
+	return int32(len(r.principals) - 1)
+}
+
+func (r *policyResolver) setPrincipalSets(policy *ResolvedPolicy) {
+	for _, set := range r.sets[policy] {
+		principalSet := make(PrincipalSet, len(set))
+		for i, index := range set {
+			principalSet[i] = r.principals[index]
+		}
+		policy.PrincipalSets = append(policy.PrincipalSets, principalSet)
+	}
+
+	for _, sub := range policy.SubPolicies {
+		r.setPrincipalSets(sub)
+	}
+}
+
+// unionSets returns the sorted multiset which contains each index as often as the sorted multiset
+// which contains it most often
+func unionSets(set1, set2 []int32) []int32 {
+	var result []int32
+	i, j := 0, 0
+	for i < len(set1) || j < len(set2) {
+		switch {
+		case j == len(set2) || (i < len(set1) && set1[i] < set2[j]):
+			result = append(result, set1[i])
+			i++
+		case i == len(set1) || set2[j] < set1[i]:
+			result = append(result, set2[j])
+			j++
+		default:
+			result = append(result, set1[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestResolvePolicy(t *testing.T) {
+	org1CA := mocks.NewMockCA("Org1MSP")
+	org2CA := mocks.NewMockCA("Org2MSP")
+	org3CA := mocks.NewMockCA("Org3MSP")
+	ordererCA := mocks.NewMockCA("OrdererMSP")
+
+	org3 := orgGroup(org3CA)
+	org3.Policies["Admins"] = configPolicy(policies.SignaturePolicy("Admins", cauthdsl.SignedByNOutOfGivenRole(2, 1, []string{"Org3MSP", "Org3MSP"})).Value())
+
+	config := &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				channelconfig.ApplicationGroupKey: {
+					Groups: map[string]*cb.ConfigGroup{
+						"Org1MSP": orgGroup(org1CA),
+						"Org2MSP": orgGroup(org2CA),
+						"Org3MSP": org3,
+					},
+					Policies: map[string]*cb.ConfigPolicy{
+						"Admins":  configPolicy(policies.ImplicitMetaMajorityPolicy("Admins").Value()),
+						"Writers": configPolicy(policies.ImplicitMetaAnyPolicy("Writers").Value()),
+					},
+				},
+				channelconfig.OrdererGroupKey: {
+					Groups: map[string]*cb.ConfigGroup{
+						"OrdererMSP": orgGroup(ordererCA),
+					},
+					Policies: map[string]*cb.ConfigPolicy{
+						"Admins": configPolicy(policies.ImplicitMetaAnyPolicy("Admins").Value()),
+					},
+				},
+				"Empty": {
+					Policies: map[string]*cb.ConfigPolicy{
+						"Admins":  configPolicy(policies.ImplicitMetaAllPolicy("Admins").Value()),
+						"Unknown": configPolicy(&cb.Policy{Type: 42}),
+					},
+				},
+			},
+			Policies: map[string]*cb.ConfigPolicy{
+				"Admins": configPolicy(policies.ImplicitMetaAllPolicy("Admins").Value()),
+			},
+		},
+	}
+
+	t.Run("Majority of orgs", func(t *testing.T) {
+		resolved, err := ResolvePolicy(config, "/Channel/Application/Admins")
+		require.NoError(t, err)
+		require.Equal(t, cb.Policy_IMPLICIT_META, resolved.Type)
+		require.Equal(t, 2, resolved.Threshold)
+		require.Len(t, resolved.SubPolicies, 3)
+		require.Equal(t, "/Channel/Application/Org1MSP/Admins", resolved.SubPolicies[0].Path)
+		require.Equal(t, cb.Policy_SIGNATURE, resolved.SubPolicies[0].Type)
+		require.NotNil(t, resolved.SubPolicies[0].Envelope)
+		require.Equal(t, []string{"Org1MSP.admin"}, principalSetStrings(resolved.SubPolicies[0].PrincipalSets))
+
+		require.Equal(t, []string{
+			"Org1MSP.admin, Org2MSP.admin",
+			"Org1MSP.admin, Org3MSP.admin, Org3MSP.admin",
+			"Org2MSP.admin, Org3MSP.admin, Org3MSP.admin",
+		}, principalSetStrings(resolved.PrincipalSets))
+	})
+
+	t.Run("Nested implicit meta", func(t *testing.T) {
+		resolved, err := ResolvePolicy(config, "/Channel/Admins")
+		require.NoError(t, err)
+		require.Equal(t, 3, resolved.Threshold)
+		require.Len(t, resolved.SubPolicies, 3)
+		require.Equal(t, "/Channel/Application/Admins", resolved.SubPolicies[0].Path)
+		require.Len(t, resolved.SubPolicies[0].SubPolicies, 3)
+
+		// the empty group has no sub-groups, so ALL of its zero Admins policies are satisfied without signatures
+		require.Equal(t, "/Channel/Empty/Admins", resolved.SubPolicies[1].Path)
+		require.Equal(t, []string{""}, principalSetStrings(resolved.SubPolicies[1].PrincipalSets))
+
+		require.Equal(t, []string{
+			"Org1MSP.admin, Org2MSP.admin, OrdererMSP.admin",
+			"Org1MSP.admin, Org3MSP.admin, Org3MSP.admin, OrdererMSP.admin",
+			"Org2MSP.admin, Org3MSP.admin, Org3MSP.admin, OrdererMSP.admin",
+		}, principalSetStrings(resolved.PrincipalSets))
+	})
+
+	t.Run("Undefined sub-policies", func(t *testing.T) {
+		resolved, err := ResolvePolicy(config, "/Channel/Application/Writers")
+		require.NoError(t, err)
+		require.Len(t, resolved.SubPolicies, 3)
+		require.Equal(t, cb.Policy_UNKNOWN, resolved.SubPolicies[0].Type)
+		require.Empty(t, resolved.PrincipalSets)
+
+		resolved, err = ResolvePolicy(config, "/Channel/Empty/Unknown")
+		require.NoError(t, err)
+		require.Empty(t, resolved.PrincipalSets)
+	})
+
+	t.Run("Too many combinations of principals", func(t *testing.T) {
+		// all of eight orgs whose admins are any of eight MSPs, i.e. 8^8 combinations of principals
+		orgs := make(map[string]*cb.ConfigGroup)
+		for i := 0; i < 8; i++ {
+			var mspIDs []string
+			for j := 0; j < 8; j++ {
+				mspIDs = append(mspIDs, fmt.Sprintf("Org%dMSP", i*8+j+1))
+			}
+			orgs[mspIDs[0]] = &cb.ConfigGroup{
+				Policies: map[string]*cb.ConfigPolicy{
+					"Admins": configPolicy(policies.SignaturePolicy("Admins", cauthdsl.SignedByAnyAdmin(mspIDs)).Value()),
+				},
+			}
+		}
+		wide := &cb.Config{
+			ChannelGroup: &cb.ConfigGroup{
+				Groups: map[string]*cb.ConfigGroup{
+					channelconfig.ApplicationGroupKey: {
+						Groups: orgs,
+						Policies: map[string]*cb.ConfigPolicy{
+							"Admins": configPolicy(policies.ImplicitMetaAllPolicy("Admins").Value()),
+						},
+					},
+				},
+			},
+		}
+
+		_, err := ResolvePolicy(wide, "/Channel/Application/Admins")
+		require.EqualError(t, err, "could not resolve implicit meta policy /Channel/Application/Admins: policy is satisfied by more than 100000 combinations of principals")
+	})
+
+	t.Run("Invalid path", func(t *testing.T) {
+		_, err := ResolvePolicy(&cb.Config{}, "/Channel/Admins")
+		require.EqualError(t, err, "config has no channel group")
+
+		_, err = ResolvePolicy(config, "Admins")
+		require.EqualError(t, err, "policy path Admins does not start with /Channel/")
+
+		_, err = ResolvePolicy(config, "/Channel/Consortiums/Admins")
+		require.EqualError(t, err, "group Consortiums of policy /Channel/Consortiums/Admins does not exist")
+
+		_, err = ResolvePolicy(config, "/Channel/Application/Readers")
+		require.EqualError(t, err, "policy /Channel/Application/Readers does not exist")
+	})
+}
+
+func principalSetStrings(sets PrincipalSets) []string {
+	var result []string
+	for _, set := range sets {
+		var principals []string
+		for _, principal := range set {
+			principals = append(principals, PrincipalString(principal))
+		}
+		result = append(result, strings.Join(principals, ", "))
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,172 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+)
+
+// SignaturePolicy evaluates signatures against a SignaturePolicyEnvelope. As in Fabric, each
+// signing identity may satisfy at most one principal of the policy.
+type SignaturePolicy struct {
+	name       string
+	envelope   *cb.SignaturePolicyEnvelope
+	mspManager *MSPManager
+}
+
+// NewSignaturePolicy returns a policy which evaluates signatures against the given envelope
+func NewSignaturePolicy(name string, envelope *cb.SignaturePolicyEnvelope, mspManager *MSPManager) (*SignaturePolicy, error) {
+	if envelope == nil || envelope.Rule == nil {
+		return nil, errors.New("signature policy envelope has no rule")
+	}
+
+	if envelope.Version != 0 {
+		return nil, errors.Errorf("unsupported signature policy version %d", envelope.Version)
+	}
+
+	if err := checkRule(envelope.Rule, len(envelope.Identities)); err != nil {
+		return nil, err
+	}
+
+	return &SignaturePolicy{
+		name:       name,
+		envelope:   envelope,
+		mspManager: mspManager,
+	}, nil
+}
+
+// NewSignaturePolicyFromBytes returns a policy which evaluates signatures against the serialized envelope
+func NewSignaturePolicyFromBytes(name string, envelopeBytes []byte, mspManager *MSPManager) (*SignaturePolicy, error) {
+	envelope := &cb.SignaturePolicyEnvelope{}
+	if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal signature policy envelope")
+	}
+
+	return NewSignaturePolicy(name, envelope, mspManager)
+}
+
+// SignaturePolicyFromString parses a signature policy string, e.g. "OR('Org1MSP.member', 'Org2MSP.admin')"
+func SignaturePolicyFromString(policy string) (*cb.SignaturePolicyEnvelope, error) {
+	return cauthdsl.FromString(policy)
+}
+
+// String returns the policy string of the envelope, or a description of the error if it cannot be rendered
+func (p *SignaturePolicy) String() string {
+	s, err := SignaturePolicyToString(p.envelope)
+	if err != nil {
+		return fmt.Sprintf("signature policy %s: %s", p.name, err)
+	}
+	return s
+}
+
+// Envelope returns the signature policy envelope
+func (p *SignaturePolicy) Envelope() *cb.SignaturePolicyEnvelope {
+	return p.envelope
+}
+
+// Evaluate returns nil if the signature set satisfies the policy. Otherwise an *EvaluationError
+// listing the principals without a valid signature is returned.
+func (p *SignaturePolicy) Evaluate(signatureSet []*SignedData) error {
+	identities := p.validIdentities(signatureSet)
+
+	used := make([]bool, len(identities))
+	if p.evaluate(p.envelope.Rule, identities, used) {
+		return nil
+	}
+
+	return &EvaluationError{
+		Policy:            p.name,
+		MissingPrincipals: p.missingPrincipals(identities),
+	}
+}
+
+// validIdentities returns the deduplicated identities of the signature set which carry a valid signature.
+// Signatures from unknown or invalid identities are ignored.
+func (p *SignaturePolicy) validIdentities(signatureSet []*SignedData) []*Identity {
+	var identities []*Identity
+	var seen [][]byte
+
+	for i, sd := range signatureSet {
+		if containsBytes(seen, sd.Identity) {
+			logger.Debugf("Ignoring duplicate identity in signature %d", i)
+			continue
+		}
+
+		id, err := p.mspManager.DeserializeIdentity(sd.Identity)
+		if err != nil {
+			logger.Warnf("Ignoring signature %d for policy %s: %s", i, p.name, err)
+			continue
+		}
+
+		if err := id.Verify(sd.Data, sd.Signature); err != nil {
+			logger.Warnf("Ignoring signature %d for policy %s: %s", i, p.name, err)
+			continue
+		}
+
+		seen = append(seen, sd.Identity)
+		identities = append(identities, id)
+	}
+
+	return identities
+}
+
+func (p *SignaturePolicy) evaluate(rule *cb.SignaturePolicy, identities []*Identity, used []bool) bool {
+	return evaluateRule(rule, p.envelope.Identities, identities, used).Satisfied
+}
+
+// missingPrincipals returns the principals of the policy which are not satisfied by any of the identities
+func (p *SignaturePolicy) missingPrincipals(identities []*Identity) []*mb.MSPPrincipal {
+	var missing []*mb.MSPPrincipal
+	for _, principal := range p.envelope.Identities {
+		satisfied := false
+		for _, id := range identities {
+			if id.SatisfiesPrincipal(principal) == nil {
+				satisfied = true
+				break
+			}
+		}
+		if !satisfied {
+			missing = append(missing, principal)
+		}
+	}
+	return missing
+}
+
+func checkRule(rule *cb.SignaturePolicy, numIdentities int) error {
+	switch t := rule.Type.(type) {
+	case *cb.SignaturePolicy_SignedBy:
+		if t.SignedBy < 0 || int(t.SignedBy) >= numIdentities {
+			return errors.Errorf("identity index out of range, requested %d, but identities length is %d", t.SignedBy, numIdentities)
+		}
+		return nil
+	case *cb.SignaturePolicy_NOutOf_:
+		for _, sub := range t.NOutOf.Rules {
+			if err := checkRule(sub, numIdentities); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown signature policy type: %T", t)
+	}
+}
+
+func containsBytes(list [][]byte, b []byte) bool {
+	for _, item := range list {
+		if bytes.Equal(item, b) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestSignaturePolicy(t *testing.T) {
+	org1CA := mocks.NewMockCA("Org1MSP")
+	org1Admin := org1CA.NewIdentity("admin@org1", "admin")
+	org1Admin2 := org1CA.NewIdentity("admin2@org1", "admin")
+	org1Peer := org1CA.NewIdentity("peer0.org1", "peer")
+
+	org2CA := mocks.NewMockCA("Org2MSP")
+	org2Admin := org2CA.NewIdentity("admin@org2", "admin")
+
+	mspManager, err := NewMSPManager(org1CA.MSPConfig(true), org2CA.MSPConfig(true))
+	require.NoError(t, err)
+
+	t.Run("AND of admins", func(t *testing.T) {
+		envelope, err := cauthdsl.FromString("AND('Org1MSP.admin', 'Org2MSP.admin')")
+		require.NoError(t, err)
+
+		policy, err := NewSignaturePolicy("/Channel/Application/Admins", envelope, mspManager)
+		require.NoError(t, err)
+
+		require.NoError(t, policy.Evaluate(signedData(t, []byte("data"), org1Admin, org2Admin)))
+
+		err = policy.Evaluate(signedData(t, []byte("data"), org1Admin, org1Peer))
+		require.Error(t, err)
+		evalErr, ok := errors.Cause(err).(*EvaluationError)
+		require.True(t, ok)
+		require.Len(t, evalErr.MissingPrincipals, 1)
+		require.Equal(t, "Org2MSP.admin", PrincipalString(evalErr.MissingPrincipals[0]))
+		require.EqualError(t, err, "signature set did not satisfy policy /Channel/Application/Admins, missing signatures from [Org2MSP.admin]")
+	})
+
+	t.Run("Identity can only be used once", func(t *testing.T) {
+		envelope, err := cauthdsl.FromString("OutOf(2, 'Org1MSP.admin', 'Org1MSP.member')")
+		require.NoError(t, err)
+
+		policy, err := NewSignaturePolicy("policy", envelope, mspManager)
+		require.NoError(t, err)
+
+		require.Error(t, policy.Evaluate(signedData(t, []byte("data"), org1Admin)))
+		require.Error(t, policy.Evaluate(signedData(t, []byte("data"), org1Admin, org1Admin)))
+		require.NoError(t, policy.Evaluate(signedData(t, []byte("data"), org1Admin, org1Peer)))
+		require.NoError(t, policy.Evaluate(signedData(t, []byte("data"), org1Admin, org1Admin2)))
+	})
+
+	t.Run("Invalid signatures are ignored", func(t *testing.T) {
+		policy, err := NewSignaturePolicy("policy", cauthdsl.SignedByMspAdmin("Org1MSP"), mspManager)
+		require.NoError(t, err)
+
+		sd := signedData(t, []byte("data"), org1Admin)
+		sd[0].Data = []byte("other data")
+		require.Error(t, policy.Evaluate(sd))
+
+		unknown := mocks.NewMockCA("Org3MSP").NewIdentity("admin@org3", "admin")
+		require.Error(t, policy.Evaluate(signedData(t, []byte("data"), unknown)))
+		require.NoError(t, policy.Evaluate(signedData(t, []byte("data"), unknown, org1Admin)))
+	})
+
+	t.Run("Invalid policy", func(t *testing.T) {
+		_, err := NewSignaturePolicy("policy", &cb.SignaturePolicyEnvelope{}, mspManager)
+		require.EqualError(t, err, "signature policy envelope has no rule")
+
+		_, err = NewSignaturePolicy("policy", cauthdsl.Envelope(cauthdsl.SignedBy(1), nil), mspManager)
+		require.EqualError(t, err, "identity index out of range, requested 1, but identities length is 0")
+
+		_, err = NewSignaturePolicyFromBytes("policy", []byte("invalid"), mspManager)
+		require.Error(t, err)
+	})
+}
+
+func signedData(t *testing.T, data []byte, signers ...*mocks.MockSigningIdentity) []*SignedData {
+	var result []*SignedData
+	for _, signer := range signers {
+		sig, err := signer.Sign(data)
+		require.NoError(t, err)
+
+		result = append(result, &SignedData{
+			Data:      data,
+			Identity:  serialize(t, signer),
+			Signature: sig,
+		})
+	}
+	return result
+}
+
+func TestSignaturePolicyString(t *testing.T) {
+	t.Run("Round trip of builder envelopes", func(t *testing.T) {
+		ids := []string{"Org1MSP", "Org2MSP", "Org3MSP"}
+		envelopes := []*cb.SignaturePolicyEnvelope{
+			cauthdsl.SignedByAnyMember(ids),
+			cauthdsl.SignedByAnyAdmin(ids),
+			cauthdsl.SignedByNOutOfGivenRole(2, mb.MSPRole_PEER, ids),
+			cauthdsl.SignedByNOutOfGivenRole(3, mb.MSPRole_CLIENT, ids),
+			cauthdsl.SignedByNOutOfGivenRole(0, mb.MSPRole_MEMBER, ids),
+			{
+				// nested gates are evaluated first by the parser, so their identities come first
+				Rule: cauthdsl.NOutOf(2, []*cb.SignaturePolicy{
+					cauthdsl.SignedBy(4),
+					cauthdsl.Or(cauthdsl.SignedBy(0), cauthdsl.SignedBy(1)),
+					cauthdsl.And(cauthdsl.SignedBy(2), cauthdsl.SignedBy(3)),
+				}),
+				Identities: cauthdsl.SignedByNOutOfGivenRole(1, mb.MSPRole_ORDERER, []string{"a", "b", "c", "d", "e"}).Identities,
+			},
+		}
+
+		for _, envelope := range envelopes {
+			s, err := SignaturePolicyToString(envelope)
+			require.NoError(t, err)
+
+			parsed, err := SignaturePolicyFromString(s)
+			require.NoError(t, err, s)
+			require.True(t, proto.Equal(envelope, parsed), s)
+		}
+	})
+
+	t.Run("Canonical gates", func(t *testing.T) {
+		envelope, err := SignaturePolicyFromString("and('Org1MSP.admin', or('Org2MSP.member', 'Org3MSP.peer'), OutOf(2, 'Org1MSP.client', 'Org2MSP.orderer', 'Org3MSP.admin'))")
+		require.NoError(t, err)
+
+		s, err := SignaturePolicyToString(envelope)
+		require.NoError(t, err)
+		require.Equal(t, "AND('Org1MSP.admin', OR('Org2MSP.member', 'Org3MSP.peer'), OutOf(2, 'Org1MSP.client', 'Org2MSP.orderer', 'Org3MSP.admin'))", s)
+
+		s, err = SignaturePolicyToString(cauthdsl.SignedByMspAdmin("Org1MSP"))
+		require.NoError(t, err)
+		require.Equal(t, "OR('Org1MSP.admin')", s)
+
+		signedBy := cauthdsl.SignedByMspAdmin("Org1MSP")
+		signedBy.Rule = cauthdsl.SignedBy(0)
+		s, err = SignaturePolicyToString(signedBy)
+		require.NoError(t, err)
+		require.Equal(t, "OutOf(1, 'Org1MSP.admin')", s)
+
+		policy, err := NewSignaturePolicy("policy", envelope, nil)
+		require.NoError(t, err)
+		require.Equal(t, "AND('Org1MSP.admin', OR('Org2MSP.member', 'Org3MSP.peer'), OutOf(2, 'Org1MSP.client', 'Org2MSP.orderer', 'Org3MSP.admin'))", policy.String())
+	})
+
+	t.Run("Envelopes which cannot be rendered", func(t *testing.T) {
+		_, err := SignaturePolicyToString(&cb.SignaturePolicyEnvelope{})
+		require.EqualError(t, err, "signature policy envelope has no rule")
+
+		_, err = SignaturePolicyToString(cauthdsl.Envelope(cauthdsl.SignedBy(1), nil))
+		require.EqualError(t, err, "identity index 1 out of range, envelope has 0 identities")
+
+		_, err = SignaturePolicyToString(cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{[]byte("identity")}))
+		require.EqualError(t, err, "identity 0: principal classification IDENTITY cannot be expressed in a policy string")
+
+		envelope := cauthdsl.SignedByMspPeer("Org1MSP")
+		envelope.Rule = cauthdsl.NOutOf(3, []*cb.SignaturePolicy{cauthdsl.SignedBy(0)})
+		_, err = SignaturePolicyToString(envelope)
+		require.EqualError(t, err, "invalid t-out-of-n predicate, t 3, n 1")
+
+		// the parser rejects gates without rules, which are satisfied by no signatures or never satisfied
+		_, err = SignaturePolicyToString(cauthdsl.SignedByNOutOfGivenRole(0, mb.MSPRole_MEMBER, nil))
+		require.EqualError(t, err, "gate OutOf(0) without rules cannot be expressed in a policy string")
+
+		envelope = cauthdsl.SignedByMspPeer("Org1MSP")
+		envelope.Rule = cauthdsl.Or(cauthdsl.SignedBy(0), cauthdsl.NOutOf(1, nil))
+		_, err = SignaturePolicyToString(envelope)
+		require.EqualError(t, err, "gate OutOf(1) without rules cannot be expressed in a policy string")
+	})
+}
@@ -0,0 +1,325 @@
+// Copyright SecureKey Technologies Inc. All Rights Reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package protolator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	plator "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/tools/protolator"
+)
+
+// ChaincodeDecoder provides the messages into which the arguments, response payloads and event payloads of
+// a chaincode are decoded by DeepMarshalJSON, and from which they are re-encoded by DeepUnmarshalJSON.
+// A nil message leaves the payload as bytes.
+type ChaincodeDecoder interface {
+	// ArgMessage returns a newly allocated message for the argument at the given index of an invocation
+	// of the function. The function name is the argument at index 0 and is never decoded.
+	ArgMessage(function string, index int) proto.Message
+
+	// ResponseMessage returns a newly allocated message for the response payload of the function
+	ResponseMessage(function string) proto.Message
+
+	// EventMessage returns a newly allocated message for the payload of the event
+	EventMessage(event string) proto.Message
+}
+
+var (
+	chaincodeDecodersLock sync.RWMutex
+	chaincodeDecoders     = map[string]ChaincodeDecoder{}
+)
+
+// RegisterChaincodeDecoder registers the decoder of the payloads of the chaincode with the given name, replacing
+// any decoder registered before. A nil decoder removes the registration.
+func RegisterChaincodeDecoder(chaincode string, decoder ChaincodeDecoder) {
+	chaincodeDecodersLock.Lock()
+	defer chaincodeDecodersLock.Unlock()
+
+	if decoder == nil {
+		delete(chaincodeDecoders, chaincode)
+		return
+	}
+	chaincodeDecoders[chaincode] = decoder
+}
+
+func chaincodeDecoder(chaincode string) ChaincodeDecoder {
+	chaincodeDecodersLock.RLock()
+	defer chaincodeDecodersLock.RUnlock()
+
+	return chaincodeDecoders[chaincode]
+}
+
+func hasChaincodeDecoders() bool {
+	chaincodeDecodersLock.RLock()
+	defer chaincodeDecodersLock.RUnlock()
+
+	return len(chaincodeDecoders) > 0
+}
+
+// ChaincodeMessages is a ChaincodeDecoder which provides the messages of the payloads by function and event name
+type ChaincodeMessages struct {
+	// Args holds the factories of the messages of the arguments of each function, starting with the argument
+	// which follows the function name. A nil factory leaves the argument as bytes.
+	Args map[string][]func() proto.Message
+	// Responses holds the factories of the messages of the response payloads of each function
+	Responses map[string]func() proto.Message
+	// Events holds the factories of the messages of the payloads of each event
+	Events map[string]func() proto.Message
+}
+
+// ArgMessage returns a new message for the argument at the given index of an invocation of the function
+func (m *ChaincodeMessages) ArgMessage(function string, index int) proto.Message {
+	args := m.Args[function]
+	if index < 1 || index > len(args) || args[index-1] == nil {
+		return nil
+	}
+	return args[index-1]()
+}
+
+// ResponseMessage returns a new message for the response payload of the function
+func (m *ChaincodeMessages) ResponseMessage(function string) proto.Message {
+	return newMessage(m.Responses[function])
+}
+
+// EventMessage returns a new message for the payload of the event
+func (m *ChaincodeMessages) EventMessage(event string) proto.Message {
+	return newMessage(m.Events[event])
+}
+
+func newMessage(factory func() proto.Message) proto.Message {
+	if factory == nil {
+		return nil
+	}
+	return factory()
+}
+
+// JSONPayload is a message for chaincode payloads which are JSON objects. DeepMarshalJSON renders the object
+// inline, and DeepUnmarshalJSON re-encodes it as compact JSON with sorted keys. Payloads which are not in that
+// form are left as bytes by DeepMarshalJSON, since re-encoding them would change them.
+type JSONPayload struct {
+	value []byte
+}
+
+// NewJSONPayload returns a new JSON payload message. It may be used as a factory of ChaincodeMessages.
+func NewJSONPayload() proto.Message {
+	return &JSONPayload{}
+}
+
+// Reset clears the payload
+func (p *JSONPayload) Reset() {
+	p.value = nil
+}
+
+// String returns the JSON of the payload
+func (p *JSONPayload) String() string {
+	return string(p.value)
+}
+
+// ProtoMessage marks JSONPayload as a proto message
+func (p *JSONPayload) ProtoMessage() {}
+
+// Marshal returns the JSON of the payload
+func (p *JSONPayload) Marshal() ([]byte, error) {
+	return p.value, nil
+}
+
+// Unmarshal sets the JSON of the payload
+func (p *JSONPayload) Unmarshal(b []byte) error {
+	p.value = append([]byte(nil), b...)
+	return nil
+}
+
+// MarshalJSONPB returns the JSON of the payload, which must be an object
+func (p *JSONPayload) MarshalJSONPB(*jsonpb.Marshaler) ([]byte, error) {
+	value := bytes.TrimSpace(p.value)
+	if len(value) == 0 || value[0] != '{' || !json.Valid(value) {
+		return nil, errors.New("chaincode payload is not a JSON object")
+	}
+	return value, nil
+}
+
+// UnmarshalJSONPB sets the JSON of the payload
+func (p *JSONPayload) UnmarshalJSONPB(_ *jsonpb.Unmarshaler, b []byte) error {
+	return p.Unmarshal(b)
+}
+
+// chaincodePayloadFunc converts a chaincode payload of a JSON tree, given the factory of the message it is decoded into
+type chaincodePayloadFunc func(value interface{}, newMsg func() proto.Message) (interface{}, error)
+
+// convertChaincodePayloads applies convert to the chaincode arguments, response payloads and event payloads of the
+// chaincode action payloads in the JSON tree, for which a decoder is registered
+func convertChaincodePayloads(tree interface{}, convert chaincodePayloadFunc) error {
+	switch t := tree.(type) {
+	case map[string]interface{}:
+		if _, ok := t["chaincode_proposal_payload"].(map[string]interface{}); ok {
+			if _, ok := t["action"].(map[string]interface{}); ok {
+				return convertChaincodeActionPayload(t, convert)
+			}
+		}
+		for _, v := range t {
+			if err := convertChaincodePayloads(v, convert); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, v := range t {
+			if err := convertChaincodePayloads(v, convert); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// convertChaincodeActionPayload converts the payloads of the JSON tree of a ChaincodeActionPayload. The function
+// which selects the messages of the arguments and of the response is the first argument of the invocation.
+func convertChaincodeActionPayload(tree map[string]interface{}, convert chaincodePayloadFunc) error {
+	spec := jsonObject(tree, "chaincode_proposal_payload", "input", "chaincode_spec")
+	args, _ := jsonObject(spec, "input")["args"].([]interface{})
+
+	var function string
+	if len(args) > 0 {
+		if s, ok := args[0].(string); ok {
+			if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+				function = string(b)
+			}
+		}
+	}
+
+	if decoder := chaincodeDecoder(jsonString(spec, "chaincode_id", "name")); decoder != nil && function != "" {
+		for i := 1; i < len(args); i++ {
+			index := i
+			arg, err := convert(args[i], func() proto.Message { return decoder.ArgMessage(function, index) })
+			if err != nil {
+				return errors.WithMessagef(err, "argument %d of chaincode function %s", i, function)
+			}
+			args[i] = arg
+		}
+	}
+
+	extension := jsonObject(tree, "action", "proposal_response_payload", "extension")
+
+	response := jsonObject(extension, "response")
+	if decoder := chaincodeDecoder(jsonString(extension, "chaincode_id", "name")); decoder != nil && function != "" {
+		if payload, ok := response["payload"]; ok {
+			converted, err := convert(payload, func() proto.Message { return decoder.ResponseMessage(function) })
+			if err != nil {
+				return errors.WithMessagef(err, "response of chaincode function %s", function)
+			}
+			response["payload"] = converted
+		}
+	}
+
+	events := jsonObject(extension, "events")
+	if decoder := chaincodeDecoder(jsonString(events, "chaincode_id")); decoder != nil {
+		if payload, ok := events["payload"]; ok {
+			event := jsonString(events, "event_name")
+			converted, err := convert(payload, func() proto.Message { return decoder.EventMessage(event) })
+			if err != nil {
+				return errors.WithMessagef(err, "payload of chaincode event %s", event)
+			}
+			events["payload"] = converted
+		}
+	}
+
+	return nil
+}
+
+// decodeChaincodePayload replaces a base64 payload by the JSON tree of the message it is decoded into, if encoding
+// the tree again reproduces the payload. Other payloads, e.g. JSON objects which are not in the compact form with
+// sorted keys, are left as base64, so that unmarshaling the output preserves the bytes which were signed.
+func decodeChaincodePayload(value interface{}, newMsg func() proto.Message) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	msg := newMsg()
+	if msg == nil {
+		return value, nil
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding chaincode payload")
+	}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling chaincode payload")
+	}
+
+	var buf bytes.Buffer
+	if err := plator.DeepMarshalJSON(&buf, msg); err != nil {
+		return nil, err
+	}
+
+	tree, err := decodeJSONTree(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := encodeChaincodePayload(tree, newMsg)
+	if err != nil || encoded != s {
+		return value, nil
+	}
+	return tree, nil
+}
+
+// encodeChaincodePayload replaces the JSON tree of a decoded payload by the base64 encoding of its message
+func encodeChaincodePayload(value interface{}, newMsg func() proto.Message) (interface{}, error) {
+	if _, ok := value.(map[string]interface{}); !ok {
+		return value, nil
+	}
+
+	msg := newMsg()
+	if msg == nil {
+		return nil, errors.New("chaincode payload is decoded, but its decoder provides no message for it")
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling chaincode payload")
+	}
+	if err := plator.DeepUnmarshalJSON(bytes.NewReader(b), msg); err != nil {
+		return nil, err
+	}
+
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(msg); err != nil {
+		return nil, errors.Wrap(err, "error marshaling chaincode payload")
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decodeJSONTree decodes JSON into a tree whose numbers keep their original representation
+func decodeJSONTree(b []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling intermediate JSON")
+	}
+	return tree, nil
+}
+
+// jsonObject returns the object at the path of keys in the JSON tree, or nil if there is none
+func jsonObject(tree map[string]interface{}, keys ...string) map[string]interface{} {
+	for _, key := range keys {
+		tree, _ = tree[key].(map[string]interface{})
+	}
+	return tree
+}
+
+// jsonString returns the string at the path of keys in the JSON tree, or an empty string if there is none
+func jsonString(tree map[string]interface{}, keys ...string) string {
+	s, _ := jsonObject(tree, keys[:len(keys)-1]...)[keys[len(keys)-1]].(string)
+	return s
+}
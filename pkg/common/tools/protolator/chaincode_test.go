@@ -0,0 +1,253 @@
+// Copyright SecureKey Technologies Inc. All Rights Reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package protolator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+)
+
+func TestChaincodeDecoder(t *testing.T) {
+	transferArg := []byte(`{"amount":10,"to":"bob"}`)
+	ownerArg := protoutil.MarshalOrPanic(&peer.ChaincodeID{Name: "owner", Version: "v1"})
+	responsePayload := []byte(`{"balance":90}`)
+	eventPayload := protoutil.MarshalOrPanic(&peer.ChaincodeID{Name: "event", Version: "v2"})
+
+	block := &common.Block{
+		Header: &common.BlockHeader{Number: 5},
+		Data: &common.BlockData{
+			Data: [][]byte{endorserTransaction(t, "mycc", [][]byte{[]byte("transfer"), transferArg, ownerArg, []byte("raw")}, responsePayload, eventPayload)},
+		},
+	}
+
+	t.Run("Not registered", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalJSON(&buf, block))
+
+		tx := decodeTransaction(t, buf.Bytes())
+		require.Equal(t, base64.StdEncoding.EncodeToString(transferArg), tx.Args[1])
+		require.Equal(t, base64.StdEncoding.EncodeToString(responsePayload), tx.Response)
+		require.Equal(t, base64.StdEncoding.EncodeToString(eventPayload), tx.Event)
+
+		newBlock := &common.Block{}
+		require.NoError(t, DeepUnmarshalJSON(&buf, newBlock))
+		require.True(t, proto.Equal(block, newBlock))
+	})
+
+	RegisterChaincodeDecoder("mycc", &ChaincodeMessages{
+		Args: map[string][]func() proto.Message{
+			"transfer": {NewJSONPayload, func() proto.Message { return &peer.ChaincodeID{} }},
+		},
+		Responses: map[string]func() proto.Message{
+			"transfer": NewJSONPayload,
+		},
+		Events: map[string]func() proto.Message{
+			"transferred": func() proto.Message { return &peer.ChaincodeID{} },
+		},
+	})
+	defer RegisterChaincodeDecoder("mycc", nil)
+
+	t.Run("Registered", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalJSON(&buf, block))
+
+		tx := decodeTransaction(t, buf.Bytes())
+		require.Equal(t, base64.StdEncoding.EncodeToString([]byte("transfer")), tx.Args[0])
+		require.Equal(t, map[string]interface{}{"amount": float64(10), "to": "bob"}, tx.Args[1])
+		require.Equal(t, map[string]interface{}{"name": "owner", "path": "", "version": "v1"}, tx.Args[2])
+		require.Equal(t, base64.StdEncoding.EncodeToString([]byte("raw")), tx.Args[3])
+		require.Equal(t, map[string]interface{}{"balance": float64(90)}, tx.Response)
+		require.Equal(t, map[string]interface{}{"name": "event", "path": "", "version": "v2"}, tx.Event)
+
+		newBlock := &common.Block{}
+		require.NoError(t, DeepUnmarshalJSON(&buf, newBlock))
+		require.True(t, proto.Equal(block, newBlock))
+	})
+
+	t.Run("Edited", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalJSON(&buf, block))
+
+		edited := bytes.Replace(buf.Bytes(), []byte(`"amount": 10`), []byte(`"amount": 20`), 1)
+
+		newBlock := &common.Block{}
+		require.NoError(t, DeepUnmarshalJSON(bytes.NewReader(edited), newBlock))
+
+		buf.Reset()
+		require.NoError(t, DeepMarshalJSON(&buf, newBlock))
+		tx := decodeTransaction(t, buf.Bytes())
+		require.Equal(t, map[string]interface{}{"amount": float64(20), "to": "bob"}, tx.Args[1])
+	})
+
+	t.Run("Non-canonical JSON", func(t *testing.T) {
+		nonCanonicalArg := []byte(`{"to": "bob", "amount": 10}`)
+		nonCanonicalResponse := []byte(`{"balance":90}` + "\n")
+		envelope := endorserTransaction(t, "mycc", [][]byte{[]byte("transfer"), nonCanonicalArg, ownerArg}, nonCanonicalResponse, eventPayload)
+		nonCanonical := &common.Block{
+			Header: &common.BlockHeader{Number: 6},
+			Data:   &common.BlockData{Data: [][]byte{envelope}},
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalJSON(&buf, nonCanonical))
+
+		// re-encoding the JSON would change the signed bytes, so they are left as base64
+		tx := decodeTransaction(t, buf.Bytes())
+		require.Equal(t, base64.StdEncoding.EncodeToString(nonCanonicalArg), tx.Args[1])
+		require.Equal(t, map[string]interface{}{"name": "owner", "path": "", "version": "v1"}, tx.Args[2])
+		require.Equal(t, base64.StdEncoding.EncodeToString(nonCanonicalResponse), tx.Response)
+
+		newBlock := &common.Block{}
+		require.NoError(t, DeepUnmarshalJSON(&buf, newBlock))
+		require.True(t, proto.Equal(nonCanonical, newBlock))
+		require.Equal(t, envelope, newBlock.Data.Data[0])
+	})
+
+	t.Run("Block stream and YAML", func(t *testing.T) {
+		var expected bytes.Buffer
+		require.NoError(t, DeepMarshalJSON(&expected, block))
+
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalBlockJSON(&buf, block))
+		require.Equal(t, expected.String(), buf.String())
+
+		buf.Reset()
+		require.NoError(t, DeepMarshalYAML(&buf, block))
+		require.Contains(t, buf.String(), "amount: 10")
+
+		newBlock := &common.Block{}
+		require.NoError(t, DeepUnmarshalYAML(&buf, newBlock))
+		require.True(t, proto.Equal(block, newBlock))
+	})
+
+	t.Run("Not a JSON object", func(t *testing.T) {
+		invalid := &common.Block{
+			Data: &common.BlockData{
+				Data: [][]byte{endorserTransaction(t, "mycc", [][]byte{[]byte("transfer"), []byte("10")}, nil, nil)},
+			},
+		}
+
+		var buf bytes.Buffer
+		err := DeepMarshalJSON(&buf, invalid)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "chaincode payload is not a JSON object")
+	})
+}
+
+// endorserTransaction returns a marshaled envelope with an endorser transaction which invokes the chaincode
+func endorserTransaction(t *testing.T, chaincode string, args [][]byte, response, event []byte) []byte {
+	chaincodeID := &peer.ChaincodeID{Name: chaincode}
+
+	action := &peer.ChaincodeAction{
+		ChaincodeId: chaincodeID,
+		Response:    &peer.Response{Status: 200, Payload: response},
+	}
+	if event != nil {
+		action.Events = protoutil.MarshalOrPanic(&peer.ChaincodeEvent{
+			ChaincodeId: chaincode,
+			TxId:        "txid",
+			EventName:   "transferred",
+			Payload:     event,
+		})
+	}
+
+	actionPayload := &peer.ChaincodeActionPayload{
+		ChaincodeProposalPayload: protoutil.MarshalOrPanic(&peer.ChaincodeProposalPayload{
+			Input: protoutil.MarshalOrPanic(&peer.ChaincodeInvocationSpec{
+				ChaincodeSpec: &peer.ChaincodeSpec{
+					Type:        peer.ChaincodeSpec_GOLANG,
+					ChaincodeId: chaincodeID,
+					Input:       &peer.ChaincodeInput{Args: args},
+				},
+			}),
+		}),
+		Action: &peer.ChaincodeEndorsedAction{
+			ProposalResponsePayload: protoutil.MarshalOrPanic(&peer.ProposalResponsePayload{
+				ProposalHash: []byte("hash"),
+				Extension:    protoutil.MarshalOrPanic(action),
+			}),
+		},
+	}
+
+	payload := &common.Payload{
+		Header: &common.Header{
+			ChannelHeader: protoutil.MarshalOrPanic(&common.ChannelHeader{
+				Type:      int32(common.HeaderType_ENDORSER_TRANSACTION),
+				ChannelId: "mychannel",
+				TxId:      "txid",
+			}),
+		},
+		Data: protoutil.MarshalOrPanic(&peer.Transaction{
+			Actions: []*peer.TransactionAction{{Payload: protoutil.MarshalOrPanic(actionPayload)}},
+		}),
+	}
+
+	envelope, err := protoutil.Marshal(&common.Envelope{Payload: protoutil.MarshalOrPanic(payload)})
+	require.NoError(t, err)
+	return envelope
+}
+
+type decodedTransaction struct {
+	Args     []interface{}
+	Response interface{}
+	Event    interface{}
+}
+
+// decodeTransaction returns the chaincode payloads of the first transaction of the block JSON
+func decodeTransaction(t *testing.T, blockJSON []byte) *decodedTransaction {
+	var tree struct {
+		Data struct {
+			Data []struct {
+				Payload struct {
+					Data struct {
+						Actions []struct {
+							Payload struct {
+								ChaincodeProposalPayload struct {
+									Input struct {
+										ChaincodeSpec struct {
+											Input struct {
+												Args []interface{} `json:"args"`
+											} `json:"input"`
+										} `json:"chaincode_spec"`
+									} `json:"input"`
+								} `json:"chaincode_proposal_payload"`
+								Action struct {
+									ProposalResponsePayload struct {
+										Extension struct {
+											Response struct {
+												Payload interface{} `json:"payload"`
+											} `json:"response"`
+											Events struct {
+												Payload interface{} `json:"payload"`
+											} `json:"events"`
+										} `json:"extension"`
+									} `json:"proposal_response_payload"`
+								} `json:"action"`
+							} `json:"payload"`
+						} `json:"actions"`
+					} `json:"data"`
+				} `json:"payload"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(blockJSON, &tree))
+	require.Len(t, tree.Data.Data, 1)
+	require.Len(t, tree.Data.Data[0].Payload.Data.Actions, 1)
+
+	payload := tree.Data.Data[0].Payload.Data.Actions[0].Payload
+	return &decodedTransaction{
+		Args:     payload.ChaincodeProposalPayload.Input.ChaincodeSpec.Input.Args,
+		Response: payload.Action.ProposalResponsePayload.Extension.Response.Payload,
+		Event:    payload.Action.ProposalResponsePayload.Extension.Events.Payload,
+	}
+}
@@ -5,22 +5,70 @@
 package protolator
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
+	"io/ioutil"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
 	plator "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/tools/protolator"
 )
 
 // DeepMarshalJSON marshals msg to w as JSON, but instead of marshaling bytes fields which contain nested
 // marshaled messages as base64 (like the standard proto encoding), these nested messages are remarshaled
 // as the JSON representation of those messages.  This is done so that the JSON representation is as non-binary
-// and human readable as possible.
+// and human readable as possible. The payloads of chaincodes whose decoders are registered are decoded as well.
 func DeepMarshalJSON(w io.Writer, msg proto.Message) error {
-	return plator.DeepMarshalJSON(w, msg)
+	if !hasChaincodeDecoders() {
+		return plator.DeepMarshalJSON(w, msg)
+	}
+
+	var buf bytes.Buffer
+	if err := plator.DeepMarshalJSON(&buf, msg); err != nil {
+		return err
+	}
+	return decodeChaincodePayloadsJSON(w, buf.Bytes())
 }
 
 // DeepUnmarshalJSON takes JSON output as generated by DeepMarshalJSON and decodes it into msg
 // This includes re-marshaling the expanded nested elements to binary form
 func DeepUnmarshalJSON(r io.Reader, msg proto.Message) error {
-	return plator.DeepUnmarshalJSON(r, msg)
+	if !hasChaincodeDecoders() {
+		return plator.DeepUnmarshalJSON(r, msg)
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	tree, err := decodeJSONTree(b)
+	if err != nil {
+		return err
+	}
+	if err := convertChaincodePayloads(tree, encodeChaincodePayload); err != nil {
+		return err
+	}
+
+	b, err = json.Marshal(tree)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling intermediate JSON")
+	}
+	return plator.DeepUnmarshalJSON(bytes.NewReader(b), msg)
+}
+
+// decodeChaincodePayloadsJSON writes the JSON output of the engine to w, with the chaincode payloads decoded
+func decodeChaincodePayloadsJSON(w io.Writer, b []byte) error {
+	tree, err := decodeJSONTree(b)
+	if err != nil {
+		return err
+	}
+	if err := convertChaincodePayloads(tree, decodeChaincodePayload); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(tree)
 }
@@ -0,0 +1,181 @@
+// Copyright SecureKey Technologies Inc. All Rights Reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package protolator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	plator "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/tools/protolator"
+)
+
+// BlockOption configures the marshaling of a block by DeepMarshalBlockJSON
+type BlockOption func(opts *blockOptions)
+
+type blockOptions struct {
+	bytesFilter plator.BytesFilter
+	err         error
+}
+
+// WithTruncatedBytes replaces the value of every bytes field which is longer than threshold bytes, e.g. a written
+// value or a chaincode package, by an object holding its length and the base64 encoding of its first threshold bytes.
+// The threshold must not be negative.
+func WithTruncatedBytes(threshold int) BlockOption {
+	return func(opts *blockOptions) {
+		if threshold < 0 {
+			opts.err = errors.Errorf("invalid bytes threshold %d, must not be negative", threshold)
+			return
+		}
+		opts.bytesFilter = func(value []byte) (interface{}, bool) {
+			if len(value) <= threshold {
+				return nil, false
+			}
+			return map[string]interface{}{
+				"length":    len(value),
+				"truncated": base64.StdEncoding.EncodeToString(value[:threshold]),
+			}, true
+		}
+	}
+}
+
+// WithHashedBytes replaces the value of every bytes field which is longer than threshold bytes, e.g. a written
+// value or a chaincode package, by an object holding its length and the hex encoding of its SHA-256 hash.
+// The threshold must not be negative.
+func WithHashedBytes(threshold int) BlockOption {
+	return func(opts *blockOptions) {
+		if threshold < 0 {
+			opts.err = errors.Errorf("invalid bytes threshold %d, must not be negative", threshold)
+			return
+		}
+		opts.bytesFilter = func(value []byte) (interface{}, bool) {
+			if len(value) <= threshold {
+				return nil, false
+			}
+			hash := sha256.Sum256(value)
+			return map[string]interface{}{
+				"length": len(value),
+				"sha256": hex.EncodeToString(hash[:]),
+			}, true
+		}
+	}
+}
+
+// DeepMarshalBlockJSON marshals block to w as DeepMarshalJSON does, but the envelopes of the block are marshaled and
+// written one at a time, so that only the decoded tree of a single envelope is held in memory. Without options the
+// output is identical to the output of DeepMarshalJSON. The output of a block whose large bytes fields were truncated
+// or hashed may not be unmarshaled again, and chaincode payloads whose bytes were truncated or hashed are not decoded.
+func DeepMarshalBlockJSON(w io.Writer, block *common.Block, opts ...BlockOption) error {
+	options := &blockOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.err != nil {
+		return options.err
+	}
+
+	if block == nil {
+		return errors.New("block is nil")
+	}
+
+	if block.Data == nil || len(block.Data.Data) == 0 {
+		return plator.DeepMarshalJSONWithBytesFilter(w, block, options.bytesFilter)
+	}
+
+	// the data is written first, as the encoder sorts the fields of the block by name
+	var buf bytes.Buffer
+	if err := plator.DeepMarshalJSONWithBytesFilter(&buf, &common.Block{Header: block.Header, Metadata: block.Metadata}, options.bytesFilter); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		return errors.Wrap(err, "error unmarshaling block header and metadata")
+	}
+
+	if _, err := io.WriteString(w, "{\n\t\"data\": {\n\t\t\"data\": [\n"); err != nil {
+		return err
+	}
+
+	for i, data := range block.Data.Data {
+		envelope := &common.Envelope{}
+		if err := proto.Unmarshal(data, envelope); err != nil {
+			return errors.Wrapf(err, "error unmarshaling envelope %d", i)
+		}
+
+		buf.Reset()
+		if err := marshalEnvelope(&buf, envelope, options.bytesFilter); err != nil {
+			return errors.WithMessagef(err, "error marshaling envelope %d", i)
+		}
+
+		separator := ",\n"
+		if i == len(block.Data.Data)-1 {
+			separator = "\n"
+		}
+		if _, err := io.WriteString(w, "\t\t\t"); err != nil {
+			return err
+		}
+		if err := writeIndented(w, "\t\t\t", buf.Bytes(), separator); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(w, "\t\t]\n\t}"); err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range fields {
+		if name != "data" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := io.WriteString(w, ",\n\t\""+name+"\": "); err != nil {
+			return err
+		}
+		if err := writeIndented(w, "\t", fields[name], ""); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "\n}\n")
+	return err
+}
+
+// marshalEnvelope marshals the envelope to buf, decoding the payloads of chaincodes whose decoders are registered
+func marshalEnvelope(buf *bytes.Buffer, envelope *common.Envelope, filter plator.BytesFilter) error {
+	if !hasChaincodeDecoders() {
+		return plator.DeepMarshalJSONWithBytesFilter(buf, envelope, filter)
+	}
+
+	var envelopeJSON bytes.Buffer
+	if err := plator.DeepMarshalJSONWithBytesFilter(&envelopeJSON, envelope, filter); err != nil {
+		return err
+	}
+	return decodeChaincodePayloadsJSON(buf, envelopeJSON.Bytes())
+}
+
+// writeIndented writes the JSON value to w, indented as a nested value whose lines start with prefix,
+// followed by suffix. The first line is not prefixed.
+func writeIndented(w io.Writer, prefix string, value []byte, suffix string) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(value), prefix, "\t"); err != nil {
+		return errors.Wrap(err, "error indenting JSON")
+	}
+	buf.WriteString(suffix)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
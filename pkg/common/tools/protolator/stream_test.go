@@ -0,0 +1,141 @@
+// Copyright SecureKey Technologies Inc. All Rights Reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package protolator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestDeepMarshalBlockJSON(t *testing.T) {
+	builder := &mocks.MockConfigBlockBuilder{
+		MockConfigGroupBuilder: mocks.MockConfigGroupBuilder{
+			ModPolicy:               "Admins",
+			MSPNames:                []string{"Org1MSP", "Org2MSP"},
+			OrdererAddress:          "localhost:9999",
+			RootCA:                  cert,
+			ChannelCapabilities:     []string{"V1_1"},
+			OrdererCapabilities:     []string{"V1_1", "V2_0"},
+			ApplicationCapabilities: []string{"V1_2"},
+		},
+		Index:           3,
+		LastConfigIndex: 2,
+	}
+	block := builder.Build()
+
+	largeValue := bytes.Repeat([]byte("value"), 100)
+	block.Data.Data = append(block.Data.Data,
+		messageEnvelope(t, []byte("small")),
+		messageEnvelope(t, largeValue),
+	)
+
+	t.Run("Same as DeepMarshalJSON", func(t *testing.T) {
+		var expected bytes.Buffer
+		require.NoError(t, DeepMarshalJSON(&expected, block))
+
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalBlockJSON(&buf, block))
+		require.Equal(t, expected.String(), buf.String())
+
+		newBlock := &common.Block{}
+		require.NoError(t, DeepUnmarshalJSON(&buf, newBlock))
+		require.Len(t, newBlock.Data.Data, 3)
+	})
+
+	t.Run("Empty block", func(t *testing.T) {
+		empty := &common.Block{Header: block.Header, Data: &common.BlockData{}}
+
+		var expected bytes.Buffer
+		require.NoError(t, DeepMarshalJSON(&expected, empty))
+
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalBlockJSON(&buf, empty))
+		require.Equal(t, expected.String(), buf.String())
+	})
+
+	t.Run("Hashed bytes", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalBlockJSON(&buf, block, WithHashedBytes(100)))
+
+		hash := sha256.Sum256(largeValue)
+		value := messageValue(t, buf.Bytes(), 2)
+		require.Equal(t, map[string]interface{}{"length": float64(len(largeValue)), "sha256": hex.EncodeToString(hash[:])}, value)
+		require.Equal(t, base64.StdEncoding.EncodeToString([]byte("small")), messageValue(t, buf.Bytes(), 1))
+	})
+
+	t.Run("Truncated bytes", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalBlockJSON(&buf, block, WithTruncatedBytes(10)))
+
+		value := messageValue(t, buf.Bytes(), 2)
+		require.Equal(t, map[string]interface{}{"length": float64(len(largeValue)), "truncated": base64.StdEncoding.EncodeToString(largeValue[:10])}, value)
+		require.Equal(t, base64.StdEncoding.EncodeToString([]byte("small")), messageValue(t, buf.Bytes(), 1))
+	})
+
+	t.Run("Negative threshold", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.EqualError(t, DeepMarshalBlockJSON(&buf, block, WithTruncatedBytes(-1)), "invalid bytes threshold -1, must not be negative")
+		require.EqualError(t, DeepMarshalBlockJSON(&buf, block, WithHashedBytes(-1)), "invalid bytes threshold -1, must not be negative")
+		require.Zero(t, buf.Len())
+	})
+
+	t.Run("Invalid envelope", func(t *testing.T) {
+		invalid := &common.Block{Header: block.Header, Data: &common.BlockData{Data: [][]byte{[]byte("invalid")}}}
+
+		var buf bytes.Buffer
+		err := DeepMarshalBlockJSON(&buf, invalid)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "envelope 0")
+	})
+
+	t.Run("Nil block", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.EqualError(t, DeepMarshalBlockJSON(&buf, nil), "block is nil")
+	})
+}
+
+// messageEnvelope returns a marshaled envelope of type MESSAGE whose payload data is a config value with the given value
+func messageEnvelope(t *testing.T, value []byte) []byte {
+	payload := &common.Payload{
+		Header: &common.Header{
+			ChannelHeader: protoutil.MarshalOrPanic(&common.ChannelHeader{
+				Type:      int32(common.HeaderType_MESSAGE),
+				ChannelId: "mychannel",
+			}),
+		},
+		Data: protoutil.MarshalOrPanic(&common.ConfigValue{Value: value}),
+	}
+
+	envelope, err := protoutil.Marshal(&common.Envelope{Payload: protoutil.MarshalOrPanic(payload)})
+	require.NoError(t, err)
+	return envelope
+}
+
+// messageValue returns the value of the config value in the envelope of type MESSAGE at the given index of the block
+func messageValue(t *testing.T, blockJSON []byte, index int) interface{} {
+	var tree struct {
+		Data struct {
+			Data []struct {
+				Payload struct {
+					Data struct {
+						Value interface{} `json:"value"`
+					} `json:"data"`
+				} `json:"payload"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(blockJSON, &tree))
+	require.True(t, index < len(tree.Data.Data))
+	return tree.Data.Data[index].Payload.Data.Value
+}
@@ -0,0 +1,138 @@
+// Copyright SecureKey Technologies Inc. All Rights Reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package protolator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	plator "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/tools/protolator"
+	"gopkg.in/yaml.v2"
+)
+
+// DeepMarshalYAML marshals msg to w as YAML, expanding nested marshaled messages as DeepMarshalJSON does
+func DeepMarshalYAML(w io.Writer, msg proto.Message) error {
+	var buf bytes.Buffer
+	if err := plator.DeepMarshalJSON(&buf, msg); err != nil {
+		return err
+	}
+
+	tree, err := decodeJSONTree(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if err := convertChaincodePayloads(tree, decodeChaincodePayload); err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(jsonToYAMLTree(tree))
+	if err != nil {
+		return errors.Wrap(err, "error marshaling YAML")
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+// DeepUnmarshalYAML takes YAML output as generated by DeepMarshalYAML, possibly edited, and decodes it into msg.
+// This includes re-marshaling the expanded nested elements to binary form, so that the result is the same as
+// decoding the equivalent JSON with DeepUnmarshalJSON.
+func DeepUnmarshalYAML(r io.Reader, msg proto.Message) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var tree interface{}
+	if err := yaml.Unmarshal(b, &tree); err != nil {
+		return errors.Wrap(err, "error unmarshaling YAML")
+	}
+
+	if _, ok := tree.(map[interface{}]interface{}); !ok {
+		return errors.New("YAML document is not a mapping")
+	}
+
+	jsonTree, err := yamlToJSONTree(tree)
+	if err != nil {
+		return err
+	}
+
+	if err := convertChaincodePayloads(jsonTree, encodeChaincodePayload); err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.Marshal(jsonTree)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling intermediate JSON")
+	}
+
+	return plator.DeepUnmarshalJSON(bytes.NewReader(jsonBytes), msg)
+}
+
+// jsonToYAMLTree converts the numbers of a JSON tree to YAML integers or floats. Numbers which proto encodes
+// as strings in JSON, e.g. 64 bit integers, are strings in the tree and remain strings.
+func jsonToYAMLTree(tree interface{}) interface{} {
+	switch t := tree.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = jsonToYAMLTree(v)
+		}
+		return t
+	case []interface{}:
+		for i, v := range t {
+			t[i] = jsonToYAMLTree(v)
+		}
+		return t
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	default:
+		return t
+	}
+}
+
+// yamlToJSONTree converts the mappings of a YAML tree, which have arbitrary keys, to JSON objects
+func yamlToJSONTree(tree interface{}) (interface{}, error) {
+	switch t := tree.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			key, ok := k.(string)
+			if !ok {
+				return nil, errors.Errorf("YAML mapping key %v is not a string", k)
+			}
+			value, err := yamlToJSONTree(v)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(t))
+		for i, v := range t {
+			value, err := yamlToJSONTree(v)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = value
+		}
+		return result, nil
+	case int, int64, uint64, float64, bool, string, nil:
+		return t, nil
+	default:
+		return fmt.Sprint(t), nil
+	}
+}
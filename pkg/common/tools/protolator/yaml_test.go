@@ -0,0 +1,86 @@
+// Copyright SecureKey Technologies Inc. All Rights Reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package protolator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/stretchr/testify/require"
+	plator "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/tools/protolator"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestMarshalBlockYAML(t *testing.T) {
+	builder := &mocks.MockConfigBlockBuilder{
+		MockConfigGroupBuilder: mocks.MockConfigGroupBuilder{
+			ModPolicy:               "Admins",
+			MSPNames:                []string{"Org1MSP", "Org2MSP"},
+			OrdererAddress:          "localhost:9999",
+			RootCA:                  cert,
+			ChannelCapabilities:     []string{"V1_1"},
+			OrdererCapabilities:     []string{"V1_1", "V2_0"},
+			ApplicationCapabilities: []string{"V1_2"},
+		},
+		Index:           3,
+		LastConfigIndex: 2,
+	}
+	block := builder.Build()
+
+	var yamlBuf bytes.Buffer
+	require.NoError(t, DeepMarshalYAML(&yamlBuf, block))
+	require.Contains(t, yamlBuf.String(), "mod_policy: Admins")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, DeepMarshalJSON(&jsonBuf, block))
+
+	fromYAML := &common.Block{}
+	require.NoError(t, DeepUnmarshalYAML(bytes.NewReader(yamlBuf.Bytes()), fromYAML))
+
+	fromJSON := &common.Block{}
+	require.NoError(t, DeepUnmarshalJSON(bytes.NewReader(jsonBuf.Bytes()), fromJSON))
+
+	yamlBytes, err := plator.MostlyDeterministicMarshal(fromYAML)
+	require.NoError(t, err)
+	jsonBytes, err := plator.MostlyDeterministicMarshal(fromJSON)
+	require.NoError(t, err)
+	require.Equal(t, jsonBytes, yamlBytes)
+	require.Equal(t, uint64(3), fromYAML.Header.Number)
+
+	// the decoded blocks are marshaled to the same YAML
+	var yamlBuf1, yamlBuf2 bytes.Buffer
+	require.NoError(t, DeepMarshalYAML(&yamlBuf1, fromJSON))
+	require.NoError(t, DeepMarshalYAML(&yamlBuf2, fromYAML))
+	require.Equal(t, yamlBuf1.String(), yamlBuf2.String())
+
+	t.Run("Edited YAML with comments", func(t *testing.T) {
+		edited := "# edited by hand\n" + strings.Replace(yamlBuf.String(), "mod_policy: Admins", "mod_policy: Writers # was Admins", 1)
+
+		block := &common.Block{}
+		require.NoError(t, DeepUnmarshalYAML(strings.NewReader(edited), block))
+
+		var buf bytes.Buffer
+		require.NoError(t, DeepMarshalYAML(&buf, block))
+		require.Contains(t, buf.String(), "mod_policy: Writers")
+		require.NotContains(t, buf.String(), "was Admins")
+	})
+
+	t.Run("Invalid YAML", func(t *testing.T) {
+		err := DeepUnmarshalYAML(strings.NewReader("- a\n- b\n"), &common.Block{})
+		require.EqualError(t, err, "YAML document is not a mapping")
+
+		err = DeepUnmarshalYAML(strings.NewReader("header: [\n"), &common.Block{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error unmarshaling YAML")
+
+		err = DeepUnmarshalYAML(strings.NewReader("header:\n  1: 2\n"), &common.Block{})
+		require.EqualError(t, err, "YAML mapping key 1 is not a string")
+
+		err = DeepUnmarshalYAML(strings.NewReader("header:\n  unknown: 2\n"), &common.Block{})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+)
+
+// ApplyConfigUpdate returns the config which results from applying the config update to the given config, i.e.
+// the config the orderer would commit for the update. The read set must match the versions of the config and the
+// modified elements of the write set must have their versions bumped. Signatures are not checked; use
+// EvaluateConfigUpdate or a Validator to check that the update is authorized.
+func ApplyConfigUpdate(config *cb.Config, configUpdate *cb.ConfigUpdate) (*cb.Config, error) {
+	if config == nil || config.ChannelGroup == nil {
+		return nil, errors.New("config has no channel group")
+	}
+
+	if configUpdate == nil {
+		return nil, errors.New("config update is nil")
+	}
+
+	configMap, err := mapConfig(config.ChannelGroup, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not map config")
+	}
+
+	readSet, err := mapConfig(configUpdate.ReadSet, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping read set")
+	}
+
+	if err := verifyReadSet(configMap, readSet); err != nil {
+		return nil, errors.WithMessage(err, "error validating read set")
+	}
+
+	writeSet, err := mapConfig(configUpdate.WriteSet, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping write set")
+	}
+
+	deltaSet := computeDeltaSet(readSet, writeSet)
+	if len(deltaSet) == 0 {
+		return nil, errors.New("delta set was empty -- update would have no effect")
+	}
+
+	var keys []string
+	for key := range deltaSet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := verifyElement(configMap, deltaSet[key], key); err != nil {
+			return nil, errors.WithMessage(err, "error validating delta set")
+		}
+	}
+
+	channelGroup, err := configMapToConfig(computeUpdateResult(configMap, deltaSet), channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not turn config map back to channel group")
+	}
+
+	return &cb.Config{
+		Sequence:     config.Sequence + 1,
+		ChannelGroup: channelGroup,
+	}, nil
+}
+
+// ApplyConfigUpdateEnvelope unmarshals the config update of the envelope and applies it to the given config
+func ApplyConfigUpdateEnvelope(config *cb.Config, configUpdateEnv *cb.ConfigUpdateEnvelope) (*cb.Config, error) {
+	if configUpdateEnv == nil {
+		return nil, errors.New("config update envelope is nil")
+	}
+
+	configUpdate := &cb.ConfigUpdate{}
+	if err := proto.Unmarshal(configUpdateEnv.ConfigUpdate, configUpdate); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal config update")
+	}
+
+	return ApplyConfigUpdate(config, configUpdate)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+)
+
+func TestApplyConfigUpdate(t *testing.T) {
+	n := newTestNetwork()
+	config := n.config()
+
+	edits := []configtxgen.ConfigEdit{
+		configtxgen.RemoveApplicationOrg("Org3MSP"),
+		configtxgen.SetBatchSize(genesisconfig.BatchSize{MaxMessageCount: 20, AbsoluteMaxBytes: 1024, PreferredMaxBytes: 512}),
+		configtxgen.SetOrdererAddresses([]string{"orderer:7050"}),
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		configUpdateEnv := n.configUpdate(t, config, edits...)
+
+		result, err := ApplyConfigUpdateEnvelope(config, configUpdateEnv)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), result.Sequence)
+
+		// The result is equivalent to applying the edits directly
+		expected := proto.Clone(config).(*cb.Config)
+		for _, edit := range edits {
+			require.NoError(t, edit(expected.ChannelGroup))
+		}
+
+		diff, err := DiffConfigs(expected, result)
+		require.NoError(t, err)
+		require.True(t, diff.Empty(), diff.String())
+
+		// Versions are bumped the way the orderer would
+		require.Equal(t, uint64(1), result.ChannelGroup.Version)
+		require.Equal(t, uint64(1), result.ChannelGroup.Groups[channelconfig.ApplicationGroupKey].Version)
+		require.Equal(t, uint64(0), result.ChannelGroup.Groups[channelconfig.OrdererGroupKey].Version)
+		require.Equal(t, uint64(1), result.ChannelGroup.Groups[channelconfig.OrdererGroupKey].Values[channelconfig.BatchSizeKey].Version)
+		require.Equal(t, uint64(0), result.ChannelGroup.Values[channelconfig.OrdererAddressesKey].Version)
+
+		// The base config is not modified
+		require.True(t, proto.Equal(n.config(), config))
+
+		// The same update cannot be applied twice
+		_, err = ApplyConfigUpdateEnvelope(result, configUpdateEnv)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error validating read set: proposed update requires that key [Group]  /Channel")
+	})
+
+	t.Run("Matches validator", func(t *testing.T) {
+		configUpdateEnv := n.configUpdate(t, config, configtxgen.RemoveApplicationOrg("Org3MSP"))
+
+		result, err := ApplyConfigUpdateEnvelope(config, configUpdateEnv)
+		require.NoError(t, err)
+
+		vi, err := NewValidatorFromBlock(testConfigBlock(t, "mychannel", config))
+		require.NoError(t, err)
+
+		configEnv, err := vi.ProposeConfigUpdate(n.updateEnvelope(t, configUpdateEnv, n.org1Admin, n.org3Admin))
+		require.NoError(t, err)
+		require.True(t, proto.Equal(configEnv.Config, result))
+	})
+
+	t.Run("Invalid version", func(t *testing.T) {
+		configUpdate := &cb.ConfigUpdate{
+			ReadSet: &cb.ConfigGroup{},
+			WriteSet: &cb.ConfigGroup{
+				Version:   2,
+				ModPolicy: "Admins",
+			},
+		}
+
+		_, err := ApplyConfigUpdate(config, configUpdate)
+		require.EqualError(t, err, "error validating delta set: attempt to set key [Group]  /Channel to version 2, but key is at version 0")
+	})
+
+	t.Run("Empty delta set", func(t *testing.T) {
+		_, err := ApplyConfigUpdate(config, &cb.ConfigUpdate{ReadSet: &cb.ConfigGroup{}, WriteSet: &cb.ConfigGroup{}})
+		require.EqualError(t, err, "delta set was empty -- update would have no effect")
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		_, err := ApplyConfigUpdate(&cb.Config{}, &cb.ConfigUpdate{})
+		require.EqualError(t, err, "config has no channel group")
+
+		_, err = ApplyConfigUpdate(config, nil)
+		require.EqualError(t, err, "config update is nil")
+
+		_, err = ApplyConfigUpdateEnvelope(config, nil)
+		require.EqualError(t, err, "config update envelope is nil")
+
+		_, err = ApplyConfigUpdateEnvelope(config, &cb.ConfigUpdateEnvelope{ConfigUpdate: []byte("invalid")})
+		require.Error(t, err)
+	})
+}
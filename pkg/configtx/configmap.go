@@ -0,0 +1,273 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"regexp"
+	"strings"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+)
+
+const (
+	groupPrefix  = "[Group]  "
+	valuePrefix  = "[Value]  "
+	policyPrefix = "[Policy] "
+
+	pathSeparator = "/"
+
+	// maxLength is the maximum length of a config element ID, as defined by Fabric
+	maxLength = 249
+)
+
+// configElementRegexp is the pattern allowed for the names of config elements and mod policies
+var configElementRegexp = regexp.MustCompile("^[a-zA-Z0-9.-]+$")
+
+// ElementType is the type of a config element
+type ElementType string
+
+const (
+	// GroupElement is a ConfigGroup
+	GroupElement ElementType = "Group"
+	// ValueElement is a ConfigValue
+	ValueElement ElementType = "Value"
+	// PolicyElement is a ConfigPolicy
+	PolicyElement ElementType = "Policy"
+)
+
+// comparable is a config element together with its location in the config tree
+type comparable struct {
+	*cb.ConfigGroup
+	*cb.ConfigValue
+	*cb.ConfigPolicy
+	key  string
+	path []string
+}
+
+func (cg comparable) elementType() ElementType {
+	switch {
+	case cg.ConfigGroup != nil:
+		return GroupElement
+	case cg.ConfigValue != nil:
+		return ValueElement
+	default:
+		return PolicyElement
+	}
+}
+
+func (cg comparable) version() uint64 {
+	switch {
+	case cg.ConfigGroup != nil:
+		return cg.ConfigGroup.Version
+	case cg.ConfigValue != nil:
+		return cg.ConfigValue.Version
+	case cg.ConfigPolicy != nil:
+		return cg.ConfigPolicy.Version
+	}
+	return 0
+}
+
+func (cg comparable) modPolicy() string {
+	switch {
+	case cg.ConfigGroup != nil:
+		return cg.ConfigGroup.ModPolicy
+	case cg.ConfigValue != nil:
+		return cg.ConfigValue.ModPolicy
+	case cg.ConfigPolicy != nil:
+		return cg.ConfigPolicy.ModPolicy
+	}
+	return ""
+}
+
+// fullPath returns the path of the element itself, e.g. /Channel/Application/Org1MSP
+func (cg comparable) fullPath() string {
+	return pathSeparator + strings.Join(append(append([]string{}, cg.path...), cg.key), pathSeparator)
+}
+
+// mapConfig flattens the config tree rooted at the given group into a map keyed by element type and path
+func mapConfig(channelGroup *cb.ConfigGroup, rootGroupKey string) (map[string]comparable, error) {
+	result := make(map[string]comparable)
+	if channelGroup != nil {
+		if err := recurseConfig(result, []string{rootGroupKey}, channelGroup); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func addToMap(cg comparable, result map[string]comparable) error {
+	var fqPath string
+
+	switch {
+	case cg.ConfigGroup != nil:
+		fqPath = groupPrefix
+	case cg.ConfigValue != nil:
+		fqPath = valuePrefix
+	case cg.ConfigPolicy != nil:
+		fqPath = policyPrefix
+	}
+
+	if err := validateConfigID(cg.key); err != nil {
+		return errors.WithMessagef(err, "illegal characters in key: %s", fqPath)
+	}
+
+	if len(cg.path) == 0 {
+		fqPath += pathSeparator + cg.key
+	} else {
+		fqPath += pathSeparator + strings.Join(cg.path, pathSeparator) + pathSeparator + cg.key
+	}
+
+	logger.Debugf("Adding to config map: %s", fqPath)
+
+	result[fqPath] = cg
+
+	return nil
+}
+
+func recurseConfig(result map[string]comparable, path []string, group *cb.ConfigGroup) error {
+	if err := addToMap(comparable{key: path[len(path)-1], path: path[:len(path)-1], ConfigGroup: group}, result); err != nil {
+		return err
+	}
+
+	for key, group := range group.Groups {
+		nextPath := make([]string, len(path)+1)
+		copy(nextPath, path)
+		nextPath[len(nextPath)-1] = key
+		if err := recurseConfig(result, nextPath, group); err != nil {
+			return err
+		}
+	}
+
+	for key, value := range group.Values {
+		if err := addToMap(comparable{key: key, path: path, ConfigValue: value}, result); err != nil {
+			return err
+		}
+	}
+
+	for key, policy := range group.Policies {
+		if err := addToMap(comparable{key: key, path: path, ConfigPolicy: policy}, result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// computeDeltaSet returns the elements of the write set whose version differs from the read set
+func computeDeltaSet(readSet, writeSet map[string]comparable) map[string]comparable {
+	result := make(map[string]comparable)
+	for key, value := range writeSet {
+		readVal, ok := readSet[key]
+
+		if ok && readVal.version() == value.version() {
+			continue
+		}
+
+		result[key] = value
+	}
+	return result
+}
+
+// verifyReadSet checks that every element of the read set exists in the config with the same version
+func verifyReadSet(configMap, readSet map[string]comparable) error {
+	for key, value := range readSet {
+		existing, ok := configMap[key]
+		if !ok {
+			return errors.Errorf("existing config does not contain element for %s but was in the read set", key)
+		}
+
+		if existing.version() != value.version() {
+			return errors.Errorf("proposed update requires that key %s be at version %d, but it is currently at version %d", key, value.version(), existing.version())
+		}
+	}
+	return nil
+}
+
+// policyForItem resolves the mod_policy of the existing config element
+func policyForItem(manager policies.Manager, item comparable) (policies.Policy, string, bool) {
+	modPolicy := item.modPolicy()
+
+	// If the mod_policy path is relative, get the right manager for the context.
+	// If the item has a zero length path, it is the root group, use the base policy manager.
+	// If the mod_policy path is absolute (starts with /) also use the base policy manager.
+	if len(item.path) > 0 && len(modPolicy) > 0 && modPolicy[0] != pathSeparator[0] {
+		var ok bool
+		manager, ok = manager.Manager(item.path[1:])
+		if !ok {
+			return nil, "", false
+		}
+
+		// In the case of the group type, its key is part of its path for the purposes of finding the policy manager
+		if item.ConfigGroup != nil {
+			manager, ok = manager.Manager([]string{item.key})
+			if !ok {
+				return nil, "", false
+			}
+		}
+	}
+
+	policy, ok := manager.GetPolicy(modPolicy)
+	return policy, resolvedPolicyName(item, modPolicy), ok
+}
+
+// resolvedPolicyName returns the absolute name of the mod_policy of the element
+func resolvedPolicyName(item comparable, modPolicy string) string {
+	if strings.HasPrefix(modPolicy, pathSeparator) {
+		return modPolicy
+	}
+
+	path := item.path
+	if item.ConfigGroup != nil {
+		path = append(append([]string{}, item.path...), item.key)
+	}
+
+	return pathSeparator + strings.Join(path, pathSeparator) + pathSeparator + modPolicy
+}
+
+// validateConfigID makes sure that the config element names (ie map key of ConfigGroup) comply with the
+// following restrictions:
+//  1. Contain only ASCII alphanumerics, dots '.', dashes '-'
+//  2. Are shorter than 250 characters
+//  3. Are not the strings "." or ".."
+func validateConfigID(configID string) error {
+	if len(configID) <= 0 {
+		return errors.New("config ID illegal, cannot be empty")
+	}
+	if len(configID) > maxLength {
+		return errors.Errorf("config ID illegal, cannot be longer than %d", maxLength)
+	}
+	// Illegal name
+	if configID == "." || configID == ".." {
+		return errors.Errorf("name '%s' is invalid", configID)
+	}
+	if !configElementRegexp.MatchString(configID) {
+		return errors.Errorf("config ID '%s' contains illegal characters", configID)
+	}
+	return nil
+}
+
+// validateModPolicy checks that the mod_policy is a valid relative or absolute policy path
+func validateModPolicy(modPolicy string) error {
+	if modPolicy == "" {
+		return errors.New("mod_policy not set")
+	}
+
+	trimmed := modPolicy
+	if modPolicy[0] == '/' {
+		trimmed = modPolicy[1:]
+	}
+
+	for i, pathElement := range strings.Split(trimmed, pathSeparator) {
+		err := validateConfigID(pathElement)
+		if err != nil {
+			return errors.Wrapf(err, "path element at %d is invalid", i)
+		}
+	}
+	return nil
+}
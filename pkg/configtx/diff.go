@@ -0,0 +1,417 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/configtxlator/update"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/common/tools/protolator"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen"
+)
+
+// ChangeType is the kind of change made to a config element
+type ChangeType string
+
+const (
+	// Added means that the element does not exist in the original config
+	Added ChangeType = "added"
+	// Removed means that the element does not exist in the updated config
+	Removed ChangeType = "removed"
+	// Modified means that the element exists in both configs but its content differs
+	Modified ChangeType = "modified"
+)
+
+// FieldChange is a difference between the decoded contents of a modified element
+type FieldChange struct {
+	// Field is the dotted path of the field within the element, e.g. value.max_message_count
+	Field string `json:"field"`
+	// Before is the original value of the field, or nil if the field was added
+	Before interface{} `json:"before,omitempty"`
+	// After is the updated value of the field, or nil if the field was removed
+	After interface{} `json:"after,omitempty"`
+}
+
+// ConfigChange describes a single added, removed or modified config element
+type ConfigChange struct {
+	// Type is the kind of change
+	Type ChangeType `json:"type"`
+	// Element is the type of the changed element
+	Element ElementType `json:"element"`
+	// Path is the path of the element, e.g. /Channel/Application/Org1MSP/AnchorPeers
+	Path string `json:"path"`
+	// Before is the element in the original config, decoded by protolator. It is empty for added elements.
+	Before json.RawMessage `json:"before,omitempty"`
+	// After is the element in the updated config, decoded by protolator. It is empty for removed elements.
+	After json.RawMessage `json:"after,omitempty"`
+	// Fields lists the differing fields of a modified element
+	Fields []*FieldChange `json:"fields,omitempty"`
+}
+
+// ConfigDiff is the set of changes between two channel configs
+type ConfigDiff struct {
+	// Changes contains the changed elements, sorted by path. An added or removed group is reported
+	// as a single change which contains the whole group. A group whose members changed is only reported
+	// as modified if its own mod_policy changed; its members are reported individually.
+	Changes []*ConfigChange `json:"changes"`
+}
+
+// Empty returns true if there are no changes
+func (d *ConfigDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// JSON returns the indented JSON representation of the diff
+func (d *ConfigDiff) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "\t")
+}
+
+// String returns a human readable representation of the diff with one line per change, followed
+// by the differing fields of modified elements
+func (d *ConfigDiff) String() string {
+	buf := &bytes.Buffer{}
+	for _, change := range d.Changes {
+		fmt.Fprintf(buf, "%s %-8s %s\n", changeSymbol(change.Type), change.Element, change.Path)
+		for _, field := range change.Fields {
+			fmt.Fprintf(buf, "    %s: %s -> %s\n", field.Field, fieldString(field.Before), fieldString(field.After))
+		}
+	}
+	return buf.String()
+}
+
+// DiffConfigBlocks returns the changes between the configs contained in two config blocks
+func DiffConfigBlocks(original, updated *cb.Block) (*ConfigDiff, error) {
+	originalConfig, err := configtxgen.ConfigFromBlock(original)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid original config block")
+	}
+
+	updatedConfig, err := configtxgen.ConfigFromBlock(updated)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid updated config block")
+	}
+
+	return DiffConfigs(originalConfig, updatedConfig)
+}
+
+// DiffConfigs returns the changes between two channel configs. The changed elements are the ones contained
+// in the delta set of the config update computed from the two configs.
+func DiffConfigs(original, updated *cb.Config) (*ConfigDiff, error) {
+	if original == nil || updated == nil {
+		return nil, errors.New("configs cannot be nil")
+	}
+
+	diff := &ConfigDiff{}
+
+	configUpdate, err := update.Compute(original, updated)
+	if err != nil {
+		if original.ChannelGroup != nil && updated.ChannelGroup != nil {
+			// The configs are equivalent
+			return diff, nil
+		}
+		return nil, errors.Wrap(err, "could not compute config update")
+	}
+
+	readSet, err := mapConfig(configUpdate.ReadSet, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping read set")
+	}
+
+	writeSet, err := mapConfig(configUpdate.WriteSet, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping write set")
+	}
+
+	originalMap, err := mapConfig(original.ChannelGroup, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping original config")
+	}
+
+	updatedMap, err := mapConfig(updated.ChannelGroup, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping updated config")
+	}
+
+	originalJSON, err := decodeConfig(original)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not decode original config")
+	}
+
+	updatedJSON, err := decodeConfig(updated)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not decode updated config")
+	}
+
+	for key := range computeDeltaSet(readSet, writeSet) {
+		changes, err := diffElement(key, originalMap, updatedMap, originalJSON, updatedJSON)
+		if err != nil {
+			return nil, err
+		}
+		diff.Changes = append(diff.Changes, changes...)
+	}
+
+	sort.Slice(diff.Changes, func(i, j int) bool {
+		if diff.Changes[i].Path != diff.Changes[j].Path {
+			return diff.Changes[i].Path < diff.Changes[j].Path
+		}
+		return diff.Changes[i].Element < diff.Changes[j].Element
+	})
+
+	return diff, nil
+}
+
+// diffElement returns the changes for an element of the delta set
+func diffElement(key string, originalMap, updatedMap map[string]comparable, originalJSON, updatedJSON interface{}) ([]*ConfigChange, error) {
+	updatedElement := updatedMap[key]
+
+	originalElement, ok := originalMap[key]
+	if !ok {
+		if _, ok := originalMap[groupPrefix+pathSeparator+strings.Join(updatedElement.path, pathSeparator)]; !ok {
+			// The containing group was added and is reported as a whole
+			return nil, nil
+		}
+
+		after, err := decodedElement(updatedJSON, updatedElement)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*ConfigChange{newChange(Added, updatedElement, nil, after)}, nil
+	}
+
+	before, err := decodedElement(originalJSON, originalElement)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := decodedElement(updatedJSON, updatedElement)
+	if err != nil {
+		return nil, err
+	}
+
+	if originalElement.ConfigGroup == nil {
+		change := newChange(Modified, updatedElement, before, after)
+		change.Fields = diffFields("", before, after)
+		if len(change.Fields) == 0 {
+			// The decoded contents are equal
+			return nil, nil
+		}
+		return []*ConfigChange{change}, nil
+	}
+
+	var changes []*ConfigChange
+
+	for _, removed := range removedMembers(originalMap, originalElement.ConfigGroup, updatedElement.ConfigGroup, originalElement.fullPath()) {
+		removedJSON, err := decodedElement(originalJSON, removed)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, newChange(Removed, removed, removedJSON, nil))
+	}
+
+	if originalElement.ConfigGroup.ModPolicy != updatedElement.ConfigGroup.ModPolicy {
+		before, after = groupHeader(before), groupHeader(after)
+		change := newChange(Modified, updatedElement, before, after)
+		change.Fields = diffFields("", before, after)
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// removedMembers returns the members of the original group which don't exist in the updated group
+func removedMembers(originalMap map[string]comparable, original, updated *cb.ConfigGroup, path string) []comparable {
+	var removed []comparable
+
+	for key := range original.Groups {
+		if _, ok := updated.Groups[key]; !ok {
+			removed = append(removed, originalMap[groupPrefix+path+pathSeparator+key])
+		}
+	}
+
+	for key := range original.Values {
+		if _, ok := updated.Values[key]; !ok {
+			removed = append(removed, originalMap[valuePrefix+path+pathSeparator+key])
+		}
+	}
+
+	for key := range original.Policies {
+		if _, ok := updated.Policies[key]; !ok {
+			removed = append(removed, originalMap[policyPrefix+path+pathSeparator+key])
+		}
+	}
+
+	return removed
+}
+
+func newChange(changeType ChangeType, element comparable, before, after interface{}) *ConfigChange {
+	return &ConfigChange{
+		Type:    changeType,
+		Element: element.elementType(),
+		Path:    element.fullPath(),
+		Before:  rawJSON(before),
+		After:   rawJSON(after),
+	}
+}
+
+// decodeConfig returns the config as a generic JSON tree in which values and policies are decoded by protolator
+func decodeConfig(config *cb.Config) (interface{}, error) {
+	buf := &bytes.Buffer{}
+	if err := protolator.DeepMarshalJSON(buf, config); err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal decoded config")
+	}
+
+	return result, nil
+}
+
+// decodedElement returns the JSON of the element from the decoded config
+func decodedElement(configJSON interface{}, element comparable) (interface{}, error) {
+	node := field(configJSON, "channel_group")
+
+	// The first element of the path is the channel group itself
+	path := append(append([]string{}, element.path...), element.key)[1:]
+
+	if element.elementType() == GroupElement {
+		for _, name := range path {
+			node = field(field(node, "groups"), name)
+		}
+	} else {
+		for _, name := range path[:len(path)-1] {
+			node = field(field(node, "groups"), name)
+		}
+
+		if element.elementType() == ValueElement {
+			node = field(field(node, "values"), element.key)
+		} else {
+			node = field(field(node, "policies"), element.key)
+		}
+	}
+
+	if node == nil {
+		return nil, errors.Errorf("element %s not found in decoded config", element.fullPath())
+	}
+
+	return node, nil
+}
+
+func field(node interface{}, name string) interface{} {
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return m[name]
+}
+
+// groupHeader returns the decoded group without its members
+func groupHeader(group interface{}) interface{} {
+	m, ok := group.(map[string]interface{})
+	if !ok {
+		return group
+	}
+
+	header := make(map[string]interface{})
+	for key, value := range m {
+		if key != "groups" && key != "values" && key != "policies" {
+			header[key] = value
+		}
+	}
+	return header
+}
+
+// diffFields recursively compares two decoded JSON trees and returns the differing leaves
+func diffFields(prefix string, before, after interface{}) []*FieldChange {
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := make(map[string]struct{})
+		for key := range beforeMap {
+			keys[key] = struct{}{}
+		}
+		for key := range afterMap {
+			keys[key] = struct{}{}
+		}
+
+		var sortedKeys []string
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+
+		var fields []*FieldChange
+		for _, key := range sortedKeys {
+			fields = append(fields, diffFields(joinField(prefix, key), beforeMap[key], afterMap[key])...)
+		}
+		return fields
+	}
+
+	beforeSlice, beforeIsSlice := before.([]interface{})
+	afterSlice, afterIsSlice := after.([]interface{})
+	if beforeIsSlice && afterIsSlice && len(beforeSlice) == len(afterSlice) {
+		var fields []*FieldChange
+		for i := range beforeSlice {
+			fields = append(fields, diffFields(fmt.Sprintf("%s[%d]", prefix, i), beforeSlice[i], afterSlice[i])...)
+		}
+		return fields
+	}
+
+	return []*FieldChange{{Field: prefix, Before: before, After: after}}
+}
+
+func joinField(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func rawJSON(node interface{}) json.RawMessage {
+	if node == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(node)
+	if err != nil {
+		// Cannot happen since the node was unmarshalled from JSON
+		panic(err)
+	}
+	return b
+}
+
+func fieldString(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	return string(rawJSON(value))
+}
+
+func changeSymbol(changeType ChangeType) string {
+	switch changeType {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
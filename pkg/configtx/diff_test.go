@@ -0,0 +1,117 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	n := newTestNetwork()
+	original := n.config()
+
+	updated := proto.Clone(original).(*cb.Config)
+	updated.Sequence = 1
+	for _, edit := range []configtxgen.ConfigEdit{
+		configtxgen.RemoveApplicationOrg("Org3MSP"),
+		configtxgen.SetBatchSize(genesisconfig.BatchSize{MaxMessageCount: 20, AbsoluteMaxBytes: 1024, PreferredMaxBytes: 512}),
+		configtxgen.SetOrdererAddresses([]string{"orderer:7050"}),
+		configtxgen.SetPolicy([]string{channelconfig.ApplicationGroupKey, "Org1MSP"}, "Writers",
+			&genesisconfig.Policy{Type: "Signature", Rule: "OR('Org1MSP.client')"}),
+	} {
+		require.NoError(t, edit(updated.ChannelGroup))
+	}
+	updated.ChannelGroup.Groups[channelconfig.ApplicationGroupKey].Groups["Org4MSP"] = testOrgGroup(mocks.NewMockCA("Org4MSP"))
+	updated.ChannelGroup.Groups[channelconfig.OrdererGroupKey].ModPolicy = "Writers"
+
+	t.Run("Changes", func(t *testing.T) {
+		diff, err := DiffConfigs(original, updated)
+		require.NoError(t, err)
+		require.False(t, diff.Empty())
+
+		var paths []string
+		for _, change := range diff.Changes {
+			paths = append(paths, string(change.Type)+" "+change.Path)
+		}
+		require.Equal(t, []string{
+			"modified /Channel/Application/Org1MSP/Writers",
+			"removed /Channel/Application/Org3MSP",
+			"added /Channel/Application/Org4MSP",
+			"modified /Channel/Orderer",
+			"modified /Channel/Orderer/BatchSize",
+			"added /Channel/OrdererAddresses",
+		}, paths)
+
+		writers := diff.Changes[0]
+		require.Equal(t, PolicyElement, writers.Element)
+		require.NotEmpty(t, writers.Before)
+		require.NotEmpty(t, writers.After)
+		require.NotEmpty(t, writers.Fields)
+
+		org3 := diff.Changes[1]
+		require.Equal(t, GroupElement, org3.Element)
+		require.Empty(t, org3.After)
+		require.Contains(t, string(org3.Before), `"MSP"`)
+
+		orderer := diff.Changes[3]
+		require.Equal(t, []*FieldChange{{Field: "mod_policy", Before: "Admins", After: "Writers"}}, orderer.Fields)
+		require.NotContains(t, string(orderer.After), "BatchSize")
+
+		batchSize := diff.Changes[4]
+		require.Equal(t, ValueElement, batchSize.Element)
+		require.Equal(t, []*FieldChange{{Field: "value.max_message_count", Before: float64(10), After: float64(20)}}, batchSize.Fields)
+
+		addresses := diff.Changes[5]
+		require.Empty(t, addresses.Before)
+		require.Contains(t, string(addresses.After), "orderer:7050")
+
+		require.Contains(t, diff.String(), "+ Value    /Channel/OrdererAddresses\n")
+		require.Contains(t, diff.String(), "~ Value    /Channel/Orderer/BatchSize\n    value.max_message_count: 10 -> 20\n")
+		require.Contains(t, diff.String(), "- Group    /Channel/Application/Org3MSP\n")
+
+		b, err := diff.JSON()
+		require.NoError(t, err)
+
+		decoded := &ConfigDiff{}
+		require.NoError(t, json.Unmarshal(b, decoded))
+		require.Len(t, decoded.Changes, len(diff.Changes))
+		require.Equal(t, Removed, decoded.Changes[1].Type)
+	})
+
+	t.Run("Blocks", func(t *testing.T) {
+		diff, err := DiffConfigBlocks(testConfigBlock(t, "mychannel", original), testConfigBlock(t, "mychannel", updated))
+		require.NoError(t, err)
+		require.Len(t, diff.Changes, 6)
+
+		_, err = DiffConfigBlocks(nil, testConfigBlock(t, "mychannel", updated))
+		require.EqualError(t, err, "invalid original config block: missing block")
+	})
+
+	t.Run("No changes", func(t *testing.T) {
+		diff, err := DiffConfigs(original, proto.Clone(original).(*cb.Config))
+		require.NoError(t, err)
+		require.True(t, diff.Empty())
+		require.Empty(t, diff.String())
+	})
+
+	t.Run("Invalid configs", func(t *testing.T) {
+		_, err := DiffConfigs(original, nil)
+		require.EqualError(t, err, "configs cannot be nil")
+
+		_, err = DiffConfigs(original, &cb.Config{})
+		require.EqualError(t, err, "could not compute config update: no channel group included for updated config")
+	})
+}
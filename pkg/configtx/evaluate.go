@@ -0,0 +1,198 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/common/logging"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/common/policies"
+)
+
+var logger = logging.NewLogger("fablibgoext")
+
+// PolicyCheck is the result of evaluating the mod_policy of a config element modified by a config update
+type PolicyCheck struct {
+	// Type is the type of the modified element
+	Type ElementType
+	// Path is the path of the modified element, e.g. /Channel/Application/Org1MSP
+	Path string
+	// ModPolicy is the absolute path of the policy which authorizes the modification. It is empty for
+	// elements which are added by the update, since they are authorized by the mod_policy of their group.
+	ModPolicy string
+	// Satisfied is true if the signatures of the update satisfy the mod_policy
+	Satisfied bool
+	// MissingPrincipals are the principals whose signatures are missing to satisfy the mod_policy
+	MissingPrincipals []*mb.MSPPrincipal
+	// Err explains why the check failed
+	Err error
+}
+
+// EvaluationReport is the result of evaluating a config update against a channel config
+type EvaluationReport struct {
+	// Checks contains a check for each element modified by the update, sorted by path
+	Checks []*PolicyCheck
+}
+
+// Satisfied returns true if the mod_policy of every element modified by the update is satisfied
+func (r *EvaluationReport) Satisfied() bool {
+	for _, check := range r.Checks {
+		if !check.Satisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the checks which are not satisfied
+func (r *EvaluationReport) Failed() []*PolicyCheck {
+	var failed []*PolicyCheck
+	for _, check := range r.Checks {
+		if !check.Satisfied {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// EvaluateConfigUpdate checks whether the signatures of the config update envelope satisfy the mod_policy of every
+// element in the write set of the update which is modified with respect to the read set. Policies are resolved from
+// the given channel config and identities are validated against the MSPs defined in it.
+func EvaluateConfigUpdate(config *cb.Config, configUpdateEnv *cb.ConfigUpdateEnvelope) (*EvaluationReport, error) {
+	manager, err := policies.NewManagerFromConfig(config)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not create policy manager from config")
+	}
+
+	return EvaluateConfigUpdateWithManager(manager, config, configUpdateEnv)
+}
+
+// EvaluateConfigUpdateWithManager checks whether the signatures of the config update envelope satisfy the mod_policy of
+// every element in the write set of the update which is modified with respect to the read set. Policies are resolved
+// using the given policy manager of the channel group.
+func EvaluateConfigUpdateWithManager(manager policies.Manager, config *cb.Config, configUpdateEnv *cb.ConfigUpdateEnvelope) (*EvaluationReport, error) {
+	if config == nil || config.ChannelGroup == nil {
+		return nil, errors.New("config has no channel group")
+	}
+
+	if configUpdateEnv == nil {
+		return nil, errors.New("config update envelope is nil")
+	}
+
+	configUpdate := &cb.ConfigUpdate{}
+	if err := proto.Unmarshal(configUpdateEnv.ConfigUpdate, configUpdate); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal config update")
+	}
+
+	configMap, err := mapConfig(config.ChannelGroup, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not map config")
+	}
+
+	readSet, err := mapConfig(configUpdate.ReadSet, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping read set")
+	}
+
+	writeSet, err := mapConfig(configUpdate.WriteSet, channelconfig.ChannelGroupKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping write set")
+	}
+
+	if err := verifyReadSet(configMap, readSet); err != nil {
+		return nil, errors.WithMessage(err, "error validating read set")
+	}
+
+	signedData, err := protoutil.ConfigUpdateEnvelopeAsSignedData(configUpdateEnv)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get signatures of config update")
+	}
+
+	deltaSet := computeDeltaSet(readSet, writeSet)
+
+	var keys []string
+	for key := range deltaSet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	report := &EvaluationReport{}
+	for _, key := range keys {
+		report.Checks = append(report.Checks, checkElement(manager, configMap, deltaSet[key], key, signedData))
+	}
+
+	return report, nil
+}
+
+func checkElement(manager policies.Manager, configMap map[string]comparable, value comparable, key string, signedData []*policies.SignedData) *PolicyCheck {
+	logger.Debugf("Evaluating policy for change to key: %s", key)
+
+	check := &PolicyCheck{
+		Type: value.elementType(),
+		Path: value.fullPath(),
+	}
+
+	existing, ok := configMap[key]
+	if ok {
+		check.ModPolicy = resolvedPolicyName(existing, existing.modPolicy())
+	}
+
+	if err := verifyElement(configMap, value, key); err != nil {
+		check.Err = err
+		return check
+	}
+
+	if !ok {
+		check.Satisfied = true
+		return check
+	}
+
+	policy, _, ok := policyForItem(manager, existing)
+	if !ok {
+		check.Err = errors.Errorf("unexpected missing policy %s for item %s", existing.modPolicy(), key)
+		return check
+	}
+
+	if err := policy.Evaluate(signedData); err != nil {
+		check.Err = errors.WithMessagef(err, "policy for %s not satisfied", key)
+		if evalErr, ok := errors.Cause(err).(*policies.EvaluationError); ok {
+			check.MissingPrincipals = evalErr.MissingPrincipals
+		}
+		return check
+	}
+
+	check.Satisfied = true
+	return check
+}
+
+// verifyElement checks the mod_policy and the version of an element of the delta set against the existing config.
+// New elements must be at version 0 and existing elements must be incremented by one.
+func verifyElement(configMap map[string]comparable, value comparable, key string) error {
+	if err := validateModPolicy(value.modPolicy()); err != nil {
+		return errors.WithMessagef(err, "invalid mod_policy for element %s", key)
+	}
+
+	existing, ok := configMap[key]
+	if !ok {
+		if value.version() != 0 {
+			return errors.Errorf("attempted to set key %s to version %d, but key does not exist", key, value.version())
+		}
+		return nil
+	}
+
+	if value.version() != existing.version()+1 {
+		return errors.Errorf("attempt to set key %s to version %d, but key is at version %d", key, value.version(), existing.version())
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestEvaluateConfigUpdate(t *testing.T) {
+	n := newTestNetwork()
+	config := n.config()
+
+	t.Run("Org policy update", func(t *testing.T) {
+		configUpdateEnv := n.configUpdate(t, config, configtxgen.SetPolicy(
+			[]string{channelconfig.ApplicationGroupKey, "Org1MSP"}, "Writers",
+			&genesisconfig.Policy{Type: "Signature", Rule: "OR('Org1MSP.client')"},
+		))
+
+		report, err := EvaluateConfigUpdate(config, configUpdateEnv)
+		require.NoError(t, err)
+		require.False(t, report.Satisfied())
+		require.Len(t, report.Checks, 1)
+
+		check := report.Checks[0]
+		require.Equal(t, PolicyElement, check.Type)
+		require.Equal(t, "/Channel/Application/Org1MSP/Writers", check.Path)
+		require.Equal(t, "/Channel/Application/Org1MSP/Admins", check.ModPolicy)
+		require.Len(t, check.MissingPrincipals, 1)
+		require.Error(t, check.Err)
+
+		report, err = EvaluateConfigUpdate(config, n.sign(t, configUpdateEnv, n.org2Admin))
+		require.NoError(t, err)
+		require.False(t, report.Satisfied())
+
+		report, err = EvaluateConfigUpdate(config, n.sign(t, configUpdateEnv, n.org1Admin))
+		require.NoError(t, err)
+		require.True(t, report.Satisfied())
+		require.Empty(t, report.Failed())
+	})
+
+	t.Run("Remove org", func(t *testing.T) {
+		configUpdateEnv := n.configUpdate(t, config, configtxgen.RemoveApplicationOrg("Org3MSP"))
+
+		report, err := EvaluateConfigUpdate(config, n.sign(t, configUpdateEnv, n.org1Admin))
+		require.NoError(t, err)
+		require.False(t, report.Satisfied())
+
+		failed := report.Failed()
+		require.Len(t, failed, 1)
+		require.Equal(t, GroupElement, failed[0].Type)
+		require.Equal(t, "/Channel/Application", failed[0].Path)
+		require.Equal(t, "/Channel/Application/Admins", failed[0].ModPolicy)
+		require.Len(t, failed[0].MissingPrincipals, 2)
+
+		report, err = EvaluateConfigUpdate(config, n.sign(t, configUpdateEnv, n.org1Admin, n.org3Admin))
+		require.NoError(t, err)
+		require.True(t, report.Satisfied())
+	})
+
+	t.Run("Add value", func(t *testing.T) {
+		configUpdateEnv := n.configUpdate(t, config, configtxgen.SetOrdererAddresses([]string{"orderer:7050"}))
+
+		// The channel Admins policy requires a majority of both the application and the orderer admins
+		report, err := EvaluateConfigUpdate(config, n.sign(t, configUpdateEnv, n.org1Admin, n.org2Admin))
+		require.NoError(t, err)
+		require.False(t, report.Satisfied())
+
+		report, err = EvaluateConfigUpdate(config, n.sign(t, configUpdateEnv, n.org1Admin, n.org2Admin, n.ordererAdmin))
+		require.NoError(t, err)
+		require.True(t, report.Satisfied())
+		require.Len(t, report.Checks, 2)
+
+		// The new value is authorized by the mod_policy of the channel group
+		require.Equal(t, "/Channel", report.Checks[0].Path)
+		require.Equal(t, "/Channel/Admins", report.Checks[0].ModPolicy)
+		require.Equal(t, "/Channel/OrdererAddresses", report.Checks[1].Path)
+		require.Empty(t, report.Checks[1].ModPolicy)
+	})
+
+	t.Run("Stale read set", func(t *testing.T) {
+		configUpdateEnv := n.configUpdate(t, config, configtxgen.RemoveApplicationOrg("Org3MSP"))
+
+		updated := proto.Clone(config).(*cb.Config)
+		updated.ChannelGroup.Groups[channelconfig.ApplicationGroupKey].Version = 1
+
+		_, err := EvaluateConfigUpdate(updated, configUpdateEnv)
+		require.EqualError(t, err, "error validating read set: proposed update requires that key [Group]  /Channel/Application be at version 0, but it is currently at version 1")
+	})
+
+	t.Run("Invalid version", func(t *testing.T) {
+		configUpdate := &cb.ConfigUpdate{
+			ChannelId: "mychannel",
+			ReadSet:   &cb.ConfigGroup{},
+			WriteSet: &cb.ConfigGroup{
+				Version:   2,
+				ModPolicy: "Admins",
+			},
+		}
+		configUpdateEnv := &cb.ConfigUpdateEnvelope{ConfigUpdate: marshalOrPanic(configUpdate)}
+
+		report, err := EvaluateConfigUpdate(config, configUpdateEnv)
+		require.NoError(t, err)
+		require.Len(t, report.Checks, 1)
+		require.EqualError(t, report.Checks[0].Err, "attempt to set key [Group]  /Channel to version 2, but key is at version 0")
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		_, err := EvaluateConfigUpdate(&cb.Config{}, &cb.ConfigUpdateEnvelope{})
+		require.Error(t, err)
+
+		_, err = EvaluateConfigUpdate(config, nil)
+		require.EqualError(t, err, "config update envelope is nil")
+
+		_, err = EvaluateConfigUpdate(config, &cb.ConfigUpdateEnvelope{ConfigUpdate: []byte("invalid")})
+		require.Error(t, err)
+	})
+}
+
+type testNetwork struct {
+	org1CA, org2CA, org3CA          *mocks.MockCA
+	org1Admin, org2Admin, org3Admin *mocks.MockSigningIdentity
+	ordererCA                       *mocks.MockCA
+	ordererAdmin                    *mocks.MockSigningIdentity
+}
+
+func newTestNetwork() *testNetwork {
+	n := &testNetwork{
+		org1CA:    mocks.NewMockCA("Org1MSP"),
+		org2CA:    mocks.NewMockCA("Org2MSP"),
+		org3CA:    mocks.NewMockCA("Org3MSP"),
+		ordererCA: mocks.NewMockCA("OrdererMSP"),
+	}
+	n.org1Admin = n.org1CA.NewIdentity("admin@org1", "admin")
+	n.org2Admin = n.org2CA.NewIdentity("admin@org2", "admin")
+	n.org3Admin = n.org3CA.NewIdentity("admin@org3", "admin")
+	n.ordererAdmin = n.ordererCA.NewIdentity("admin@orderer", "admin")
+	return n
+}
+
+// config returns a channel config with an application group containing three orgs
+// and an orderer group containing a single orderer org
+func (n *testNetwork) config() *cb.Config {
+	return &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				channelconfig.ApplicationGroupKey: {
+					Groups: map[string]*cb.ConfigGroup{
+						"Org1MSP": testOrgGroup(n.org1CA),
+						"Org2MSP": testOrgGroup(n.org2CA),
+						"Org3MSP": testOrgGroup(n.org3CA),
+					},
+					Policies:  testImplicitMetaPolicies(),
+					ModPolicy: "Admins",
+				},
+				channelconfig.OrdererGroupKey: {
+					Groups: map[string]*cb.ConfigGroup{
+						"OrdererMSP": testOrgGroup(n.ordererCA),
+					},
+					Values: map[string]*cb.ConfigValue{
+						channelconfig.BatchSizeKey: {
+							Value:     marshalOrPanic(channelconfig.BatchSizeValue(10, 1024, 512).Value()),
+							ModPolicy: "Admins",
+						},
+					},
+					Policies:  testImplicitMetaPolicies(),
+					ModPolicy: "Admins",
+				},
+			},
+			Policies:  testImplicitMetaPolicies(),
+			ModPolicy: "Admins",
+		},
+	}
+}
+
+func (n *testNetwork) configUpdate(t *testing.T, config *cb.Config, edits ...configtxgen.ConfigEdit) *cb.ConfigUpdateEnvelope {
+	configUpdateEnv, err := configtxgen.CreateConfigUpdate("mychannel", config, edits...)
+	require.NoError(t, err)
+	return configUpdateEnv
+}
+
+func (n *testNetwork) sign(t *testing.T, configUpdateEnv *cb.ConfigUpdateEnvelope, signers ...*mocks.MockSigningIdentity) *cb.ConfigUpdateEnvelope {
+	envBytes := marshalOrPanic(configUpdateEnv)
+	for _, signer := range signers {
+		var err error
+		envBytes, err = configtxgen.SignConfigUpdate(envBytes, signer)
+		require.NoError(t, err)
+	}
+
+	signed := &cb.ConfigUpdateEnvelope{}
+	require.NoError(t, proto.Unmarshal(envBytes, signed))
+	return signed
+}
+
+func testOrgGroup(ca *mocks.MockCA) *cb.ConfigGroup {
+	return &cb.ConfigGroup{
+		Values: map[string]*cb.ConfigValue{
+			channelconfig.MSPKey: {Value: marshalOrPanic(ca.MSPConfig(true)), ModPolicy: "Admins"},
+		},
+		Policies: map[string]*cb.ConfigPolicy{
+			"Admins":  testConfigPolicy(policies.SignaturePolicy("Admins", cauthdsl.SignedByMspAdmin(ca.MSPID)).Value()),
+			"Readers": testConfigPolicy(policies.SignaturePolicy("Readers", cauthdsl.SignedByMspMember(ca.MSPID)).Value()),
+			"Writers": testConfigPolicy(policies.SignaturePolicy("Writers", cauthdsl.SignedByMspMember(ca.MSPID)).Value()),
+		},
+		ModPolicy: "Admins",
+	}
+}
+
+func testImplicitMetaPolicies() map[string]*cb.ConfigPolicy {
+	return map[string]*cb.ConfigPolicy{
+		"Admins":  testConfigPolicy(policies.ImplicitMetaMajorityPolicy("Admins").Value()),
+		"Readers": testConfigPolicy(policies.ImplicitMetaAnyPolicy("Readers").Value()),
+		"Writers": testConfigPolicy(policies.ImplicitMetaAnyPolicy("Writers").Value()),
+	}
+}
+
+func testConfigPolicy(policy *cb.Policy) *cb.ConfigPolicy {
+	return &cb.ConfigPolicy{Policy: policy, ModPolicy: "Admins"}
+}
+
+func marshalOrPanic(msg proto.Message) []byte {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
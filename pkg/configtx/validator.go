@@ -0,0 +1,328 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	fabricconfigtx "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/configtx"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/common/policies"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen"
+)
+
+// channelAllowedChars is the pattern allowed for channel IDs
+var channelAllowedChars = regexp.MustCompile("^[a-z][a-z0-9.-]*$")
+
+// Validator provides a mechanism to propose config updates, see the config update results
+// and validate the results of a config update
+type Validator = fabricconfigtx.Validator
+
+// ValidatorImpl validates config transitions of a channel in memory, without an ordering service
+type ValidatorImpl struct {
+	channelID   string
+	sequence    uint64
+	configMap   map[string]comparable
+	configProto *cb.Config
+	namespace   string
+	pm          policies.Manager
+}
+
+// NewValidatorFromBlock returns a validator for the channel of the given config block. The mod_policies of
+// config updates are evaluated against the policies and MSPs defined in the config of the block.
+func NewValidatorFromBlock(block *cb.Block) (*ValidatorImpl, error) {
+	if block == nil {
+		return nil, errors.New("missing block")
+	}
+
+	channelID, err := protoutil.GetChainIDFromBlock(block)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not get channel ID from config block")
+	}
+
+	config, err := configtxgen.ConfigFromBlock(block)
+	if err != nil {
+		return nil, err
+	}
+
+	pm, err := policies.NewManagerFromConfig(config)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not create policy manager from config")
+	}
+
+	return NewValidatorImpl(channelID, config, channelconfig.ChannelGroupKey, pm)
+}
+
+// NewValidatorImpl constructs a new validator for the channel config. The namespace is the name of
+// the root group, i.e. "Channel", and the policy manager resolves the mod_policies of the config.
+func NewValidatorImpl(channelID string, config *cb.Config, namespace string, pm policies.Manager) (*ValidatorImpl, error) {
+	if config == nil {
+		return nil, errors.New("nil config parameter")
+	}
+
+	if config.ChannelGroup == nil {
+		return nil, errors.New("nil channel group")
+	}
+
+	if err := validateChannelID(channelID); err != nil {
+		return nil, errors.WithMessage(err, "bad channel ID")
+	}
+
+	configMap, err := mapConfig(config.ChannelGroup, namespace)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error converting config to map")
+	}
+
+	return &ValidatorImpl{
+		namespace:   namespace,
+		pm:          pm,
+		sequence:    config.Sequence,
+		configMap:   configMap,
+		channelID:   channelID,
+		configProto: config,
+	}, nil
+}
+
+// ProposeConfigUpdate takes in an Envelope of type CONFIG_UPDATE and produces a
+// ConfigEnvelope to be used as the Envelope Payload Data of a CONFIG message
+func (vi *ValidatorImpl) ProposeConfigUpdate(configtx *cb.Envelope) (*cb.ConfigEnvelope, error) {
+	configUpdateEnv, err := protoutil.EnvelopeToConfigUpdate(configtx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error converting envelope to config update")
+	}
+
+	configMap, err := vi.authorizeUpdate(configUpdateEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	channelGroup, err := configMapToConfig(configMap, vi.namespace)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not turn config map back to channel group")
+	}
+
+	return &cb.ConfigEnvelope{
+		Config: &cb.Config{
+			Sequence:     vi.sequence + 1,
+			ChannelGroup: channelGroup,
+		},
+		LastUpdate: configtx,
+	}, nil
+}
+
+// Validate checks that the config envelope is the valid next config of the channel, i.e. that its
+// sequence is incremented and that its LastUpdate is authorized and produces the supplied config
+func (vi *ValidatorImpl) Validate(configEnv *cb.ConfigEnvelope) error {
+	if configEnv == nil {
+		return errors.New("config envelope cannot be nil")
+	}
+
+	if configEnv.Config == nil {
+		return errors.New("config cannot be nil")
+	}
+
+	if configEnv.Config.Sequence != vi.sequence+1 {
+		return errors.Errorf("config currently at sequence %d, cannot validate config at sequence %d", vi.sequence, configEnv.Config.Sequence)
+	}
+
+	configUpdateEnv, err := protoutil.EnvelopeToConfigUpdate(configEnv.LastUpdate)
+	if err != nil {
+		return err
+	}
+
+	configMap, err := vi.authorizeUpdate(configUpdateEnv)
+	if err != nil {
+		return err
+	}
+
+	channelGroup, err := configMapToConfig(configMap, vi.namespace)
+	if err != nil {
+		return errors.WithMessage(err, "could not turn config map back to channel group")
+	}
+
+	// reflect.Equal will not work here, because it considers nil and empty maps as different
+	if !proto.Equal(channelGroup, configEnv.Config.ChannelGroup) {
+		return errors.New("ConfigEnvelope LastUpdate did not produce the supplied config result")
+	}
+
+	return nil
+}
+
+// ChannelID retrieves the channel ID associated with this validator
+func (vi *ValidatorImpl) ChannelID() string {
+	return vi.channelID
+}
+
+// Sequence returns the sequence number of the current config
+func (vi *ValidatorImpl) Sequence() uint64 {
+	return vi.sequence
+}
+
+// ConfigProto returns the config proto which initialized this validator
+func (vi *ValidatorImpl) ConfigProto() *cb.Config {
+	return vi.configProto
+}
+
+// authorizeUpdate validates that all modified config has the corresponding modification policies satisfied
+// by the signature set and returns the config map which results from applying the update
+func (vi *ValidatorImpl) authorizeUpdate(configUpdateEnv *cb.ConfigUpdateEnvelope) (map[string]comparable, error) {
+	if configUpdateEnv == nil {
+		return nil, errors.New("cannot process nil ConfigUpdateEnvelope")
+	}
+
+	configUpdate := &cb.ConfigUpdate{}
+	if err := proto.Unmarshal(configUpdateEnv.ConfigUpdate, configUpdate); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal config update")
+	}
+
+	if configUpdate.ChannelId != vi.channelID {
+		return nil, errors.Errorf("ConfigUpdate for channel '%s' but envelope for channel '%s'", configUpdate.ChannelId, vi.channelID)
+	}
+
+	readSet, err := mapConfig(configUpdate.ReadSet, vi.namespace)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping ReadSet")
+	}
+
+	if err := verifyReadSet(vi.configMap, readSet); err != nil {
+		return nil, errors.WithMessage(err, "error validating ReadSet")
+	}
+
+	writeSet, err := mapConfig(configUpdate.WriteSet, vi.namespace)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error mapping WriteSet")
+	}
+
+	deltaSet := computeDeltaSet(readSet, writeSet)
+
+	signedData, err := protoutil.ConfigUpdateEnvelopeAsSignedData(configUpdateEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vi.verifyDeltaSet(deltaSet, signedData); err != nil {
+		return nil, errors.WithMessage(err, "error validating DeltaSet")
+	}
+
+	return computeUpdateResult(vi.configMap, deltaSet), nil
+}
+
+func (vi *ValidatorImpl) verifyDeltaSet(deltaSet map[string]comparable, signedData []*policies.SignedData) error {
+	if len(deltaSet) == 0 {
+		return errors.New("delta set was empty -- update would have no effect")
+	}
+
+	var keys []string
+	for key := range deltaSet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		check := checkElement(vi.pm, vi.configMap, deltaSet[key], key, signedData)
+		if !check.Satisfied {
+			return check.Err
+		}
+	}
+
+	return nil
+}
+
+// computeUpdateResult takes a configMap generated by an update and produces a new configMap overlaying it onto the old config
+func computeUpdateResult(configMap, deltaSet map[string]comparable) map[string]comparable {
+	newConfigMap := make(map[string]comparable)
+	for key, value := range configMap {
+		newConfigMap[key] = value
+	}
+
+	for key, value := range deltaSet {
+		newConfigMap[key] = value
+	}
+
+	return newConfigMap
+}
+
+// configMapToConfig is intended to be called only from this package and reconstructs the config
+// group tree rooted at the given key from the config map
+func configMapToConfig(configMap map[string]comparable, rootGroupKey string) (*cb.ConfigGroup, error) {
+	rootPath := pathSeparator + rootGroupKey
+	return recurseConfigMap(rootPath, configMap)
+}
+
+func recurseConfigMap(path string, configMap map[string]comparable) (*cb.ConfigGroup, error) {
+	groupPath := groupPrefix + path
+	group, ok := configMap[groupPath]
+	if !ok {
+		return nil, errors.Errorf("missing group at path: %s", groupPath)
+	}
+
+	if group.ConfigGroup == nil {
+		return nil, errors.Errorf("ConfigGroup not found at group path: %s", groupPath)
+	}
+
+	newConfigGroup := protoutil.NewConfigGroup()
+	proto.Merge(newConfigGroup, group.ConfigGroup)
+
+	for key := range group.Groups {
+		updatedGroup, err := recurseConfigMap(path+pathSeparator+key, configMap)
+		if err != nil {
+			return nil, err
+		}
+		newConfigGroup.Groups[key] = updatedGroup
+	}
+
+	for key := range group.Values {
+		valuePath := valuePrefix + path + pathSeparator + key
+		value, ok := configMap[valuePath]
+		if !ok {
+			return nil, errors.Errorf("missing value at path: %s", valuePath)
+		}
+		if value.ConfigValue == nil {
+			return nil, errors.Errorf("ConfigValue not found at value path: %s", valuePath)
+		}
+		newConfigGroup.Values[key] = proto.Clone(value.ConfigValue).(*cb.ConfigValue)
+	}
+
+	for key := range group.Policies {
+		policyPath := policyPrefix + path + pathSeparator + key
+		policy, ok := configMap[policyPath]
+		if !ok {
+			return nil, errors.Errorf("missing policy at path: %s", policyPath)
+		}
+		if policy.ConfigPolicy == nil {
+			return nil, errors.Errorf("ConfigPolicy not found at policy path: %s", policyPath)
+		}
+		newConfigGroup.Policies[key] = proto.Clone(policy.ConfigPolicy).(*cb.ConfigPolicy)
+	}
+
+	return newConfigGroup, nil
+}
+
+// validateChannelID makes sure that proposed channel IDs comply with the following restrictions:
+//   1. Contain only lower case ASCII alphanumerics, dots '.', and dashes '-'
+//   2. Are shorter than 250 characters.
+//   3. Start with a letter
+func validateChannelID(channelID string) error {
+	if len(channelID) <= 0 {
+		return errors.New("channel ID illegal, cannot be empty")
+	}
+
+	if len(channelID) > maxLength {
+		return errors.Errorf("channel ID illegal, cannot be longer than %d", maxLength)
+	}
+
+	if !channelAllowedChars.MatchString(channelID) {
+		return errors.Errorf("'%s' contains illegal characters", channelID)
+	}
+
+	return nil
+}
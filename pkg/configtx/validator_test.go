@@ -0,0 +1,191 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+var _ Validator = (*ValidatorImpl)(nil)
+
+func TestValidator(t *testing.T) {
+	n := newTestNetwork()
+	config := n.config()
+
+	vi, err := NewValidatorFromBlock(testConfigBlock(t, "mychannel", config))
+	require.NoError(t, err)
+	require.Equal(t, "mychannel", vi.ChannelID())
+	require.Equal(t, uint64(0), vi.Sequence())
+	require.True(t, proto.Equal(config, vi.ConfigProto()))
+
+	removeOrg3 := n.configUpdate(t, config, configtxgen.RemoveApplicationOrg("Org3MSP"))
+
+	t.Run("Propose and validate", func(t *testing.T) {
+		env := n.updateEnvelope(t, removeOrg3, n.org1Admin, n.org3Admin)
+
+		configEnv, err := vi.ProposeConfigUpdate(env)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), configEnv.Config.Sequence)
+		require.True(t, proto.Equal(env, configEnv.LastUpdate))
+
+		appGroup := configEnv.Config.ChannelGroup.Groups[channelconfig.ApplicationGroupKey]
+		require.Equal(t, uint64(1), appGroup.Version)
+		require.Contains(t, appGroup.Groups, "Org1MSP")
+		require.Contains(t, appGroup.Groups, "Org2MSP")
+		require.NotContains(t, appGroup.Groups, "Org3MSP")
+		require.True(t, proto.Equal(config.ChannelGroup.Groups[channelconfig.OrdererGroupKey], configEnv.Config.ChannelGroup.Groups[channelconfig.OrdererGroupKey]))
+
+		require.NoError(t, vi.Validate(configEnv))
+
+		// The next validator accepts updates against the new config only
+		next, err := NewValidatorImpl("mychannel", configEnv.Config, channelconfig.ChannelGroupKey, vi.pm)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), next.Sequence())
+
+		_, err = next.ProposeConfigUpdate(env)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error validating ReadSet")
+	})
+
+	t.Run("Insufficient signatures", func(t *testing.T) {
+		_, err := vi.ProposeConfigUpdate(n.updateEnvelope(t, removeOrg3, n.org1Admin))
+		require.Error(t, err)
+		require.True(t, strings.HasPrefix(err.Error(), "error validating DeltaSet: policy for [Group]  /Channel/Application not satisfied"))
+	})
+
+	t.Run("Wrong channel", func(t *testing.T) {
+		configUpdateEnv, err := configtxgen.CreateConfigUpdate("otherchannel", config, configtxgen.RemoveApplicationOrg("Org3MSP"))
+		require.NoError(t, err)
+
+		_, err = vi.ProposeConfigUpdate(n.updateEnvelope(t, configUpdateEnv, n.org1Admin, n.org3Admin))
+		require.EqualError(t, err, "ConfigUpdate for channel 'otherchannel' but envelope for channel 'mychannel'")
+	})
+
+	t.Run("Empty delta set", func(t *testing.T) {
+		configUpdate := &cb.ConfigUpdate{
+			ChannelId: "mychannel",
+			ReadSet:   &cb.ConfigGroup{},
+			WriteSet:  &cb.ConfigGroup{},
+		}
+		configUpdateEnv := &cb.ConfigUpdateEnvelope{ConfigUpdate: marshalOrPanic(configUpdate)}
+
+		_, err := vi.ProposeConfigUpdate(n.updateEnvelope(t, configUpdateEnv, n.org1Admin))
+		require.EqualError(t, err, "error validating DeltaSet: delta set was empty -- update would have no effect")
+	})
+
+	t.Run("New element with non-zero version", func(t *testing.T) {
+		configUpdate := &cb.ConfigUpdate{
+			ChannelId: "mychannel",
+			ReadSet:   &cb.ConfigGroup{},
+			WriteSet: &cb.ConfigGroup{
+				Values: map[string]*cb.ConfigValue{
+					"Foo": {Version: 1, ModPolicy: "Admins"},
+				},
+			},
+		}
+		configUpdateEnv := &cb.ConfigUpdateEnvelope{ConfigUpdate: marshalOrPanic(configUpdate)}
+
+		_, err := vi.ProposeConfigUpdate(n.updateEnvelope(t, configUpdateEnv, n.org1Admin))
+		require.EqualError(t, err, "error validating DeltaSet: attempted to set key [Value]  /Channel/Foo to version 1, but key does not exist")
+	})
+
+	t.Run("Invalid mod_policy", func(t *testing.T) {
+		configUpdate := &cb.ConfigUpdate{
+			ChannelId: "mychannel",
+			ReadSet:   &cb.ConfigGroup{},
+			WriteSet: &cb.ConfigGroup{
+				Values: map[string]*cb.ConfigValue{
+					"Foo": {ModPolicy: "Bad/../Policy"},
+				},
+			},
+		}
+		configUpdateEnv := &cb.ConfigUpdateEnvelope{ConfigUpdate: marshalOrPanic(configUpdate)}
+
+		_, err := vi.ProposeConfigUpdate(n.updateEnvelope(t, configUpdateEnv, n.org1Admin))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid mod_policy for element [Value]  /Channel/Foo")
+	})
+
+	t.Run("Illegal key", func(t *testing.T) {
+		configUpdate := &cb.ConfigUpdate{
+			ChannelId: "mychannel",
+			ReadSet:   &cb.ConfigGroup{},
+			WriteSet: &cb.ConfigGroup{
+				Values: map[string]*cb.ConfigValue{
+					"Foo Bar": {ModPolicy: "Admins"},
+				},
+			},
+		}
+		configUpdateEnv := &cb.ConfigUpdateEnvelope{ConfigUpdate: marshalOrPanic(configUpdate)}
+
+		_, err := vi.ProposeConfigUpdate(n.updateEnvelope(t, configUpdateEnv, n.org1Admin))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error mapping WriteSet")
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		configEnv, err := vi.ProposeConfigUpdate(n.updateEnvelope(t, removeOrg3, n.org1Admin, n.org3Admin))
+		require.NoError(t, err)
+
+		badSequence := proto.Clone(configEnv).(*cb.ConfigEnvelope)
+		badSequence.Config.Sequence = 2
+		require.EqualError(t, vi.Validate(badSequence), "config currently at sequence 0, cannot validate config at sequence 2")
+
+		tampered := proto.Clone(configEnv).(*cb.ConfigEnvelope)
+		delete(tampered.Config.ChannelGroup.Groups[channelconfig.ApplicationGroupKey].Groups, "Org2MSP")
+		require.EqualError(t, vi.Validate(tampered), "ConfigEnvelope LastUpdate did not produce the supplied config result")
+
+		require.EqualError(t, vi.Validate(nil), "config envelope cannot be nil")
+		require.EqualError(t, vi.Validate(&cb.ConfigEnvelope{}), "config cannot be nil")
+	})
+
+	t.Run("Invalid construction", func(t *testing.T) {
+		_, err := NewValidatorImpl("MyChannel", config, channelconfig.ChannelGroupKey, vi.pm)
+		require.EqualError(t, err, "bad channel ID: 'MyChannel' contains illegal characters")
+
+		_, err = NewValidatorImpl("", config, channelconfig.ChannelGroupKey, vi.pm)
+		require.EqualError(t, err, "bad channel ID: channel ID illegal, cannot be empty")
+
+		_, err = NewValidatorImpl("mychannel", nil, channelconfig.ChannelGroupKey, vi.pm)
+		require.EqualError(t, err, "nil config parameter")
+
+		_, err = NewValidatorImpl("mychannel", &cb.Config{}, channelconfig.ChannelGroupKey, vi.pm)
+		require.EqualError(t, err, "nil channel group")
+
+		_, err = NewValidatorFromBlock(nil)
+		require.EqualError(t, err, "missing block")
+
+		_, err = NewValidatorFromBlock(testConfigBlock(t, "mychannel", &cb.Config{}))
+		require.EqualError(t, err, "config block does not contain a channel group")
+	})
+}
+
+func (n *testNetwork) updateEnvelope(t *testing.T, configUpdateEnv *cb.ConfigUpdateEnvelope, signers ...*mocks.MockSigningIdentity) *cb.Envelope {
+	signed := n.sign(t, configUpdateEnv, signers...)
+
+	env, err := configtxgen.CreateSignedConfigUpdateEnvelope(marshalOrPanic(signed), signers[0])
+	require.NoError(t, err)
+	return env
+}
+
+func testConfigBlock(t *testing.T, channelID string, config *cb.Config) *cb.Block {
+	env, err := protoutil.CreateSignedEnvelope(cb.HeaderType_CONFIG, channelID, nil, &cb.ConfigEnvelope{Config: config}, 0, 0)
+	require.NoError(t, err)
+
+	block := protoutil.NewBlock(0, nil)
+	block.Data.Data = [][]byte{marshalOrPanic(env)}
+	return block
+}
@@ -0,0 +1,447 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/configtxgen/encoder"
+	mspcfg "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+)
+
+// defaultExpiryWarning is how long before the expiry of a certificate it is reported as expiring soon
+const defaultExpiryWarning = 30 * 24 * time.Hour
+
+// CertRole is the role of a certificate within an MSP
+type CertRole string
+
+// Roles of the certificates of an MSP
+const (
+	CertRoleRoot            CertRole = "root"
+	CertRoleIntermediate    CertRole = "intermediate"
+	CertRoleAdmin           CertRole = "admin"
+	CertRoleTLSRoot         CertRole = "tlsroot"
+	CertRoleTLSIntermediate CertRole = "tlsintermediate"
+)
+
+// CertReport describes a certificate of an MSP. HasCRL is only meaningful for CA certificates and
+// is set if the MSP holds a revocation list issued by the certificate.
+type CertReport struct {
+	Role         CertRole
+	Index        int
+	Subject      string
+	Issuer       string
+	SerialNumber string
+	NotBefore    time.Time
+	NotAfter     time.Time
+	KeyAlgorithm string
+	Revoked      bool
+	HasCRL       bool
+	Issues       []string
+}
+
+// MSPCertReport describes the certificates of an MSP. Group and OrgName locate the MSP within a
+// channel config and are empty for reports of a single MSP config. Issues lists the problems of
+// the MSP as a whole, the problems of single certificates are listed by their reports.
+type MSPCertReport struct {
+	Group        string
+	OrgName      string
+	MSPID        string
+	MSPType      string
+	Certificates []*CertReport
+	Issues       []string
+}
+
+// HasIssues returns true if any problem was found in the MSP or in one of its certificates
+func (r *MSPCertReport) HasIssues() bool {
+	if len(r.Issues) != 0 {
+		return true
+	}
+	for _, cert := range r.Certificates {
+		if len(cert.Issues) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CertCheckOption configures the analysis of MSP certificates
+type CertCheckOption func(opts *certCheckOptions)
+
+type certCheckOptions struct {
+	now           time.Time
+	expiryWarning time.Duration
+}
+
+// WithCheckTime sets the time at which the validity of the certificates is checked, which is
+// the current time by default
+func WithCheckTime(t time.Time) CertCheckOption {
+	return func(opts *certCheckOptions) {
+		opts.now = t
+	}
+}
+
+// WithExpiryWarning sets how long before their expiry certificates are reported as expiring soon,
+// which is 30 days by default
+func WithExpiryWarning(d time.Duration) CertCheckOption {
+	return func(opts *certCheckOptions) {
+		opts.expiryWarning = d
+	}
+}
+
+// AnalyzeMSPConfig reports the expiry, key algorithm, chain validity and revocation status of the
+// certificates of an MSP config, as well as the coverage of its revocation lists and the consistency
+// of its NodeOUs. Idemix MSPs hold no certificates, so their reports are empty.
+func AnalyzeMSPConfig(config *msp.MSPConfig, opts ...CertCheckOption) (*MSPCertReport, error) {
+	options := &certCheckOptions{now: time.Now(), expiryWarning: defaultExpiryWarning}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	report := &MSPCertReport{MSPType: mspcfg.ProviderTypeToString(mspcfg.ProviderType(config.Type))}
+
+	switch mspcfg.ProviderType(config.Type) {
+	case mspcfg.FABRIC:
+		fabricConfig := &msp.FabricMSPConfig{}
+		if err := proto.Unmarshal(config.Config, fabricConfig); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal fabric MSP config")
+		}
+		report.MSPID = fabricConfig.Name
+		newCertAnalyzer(fabricConfig, options, report).analyze()
+	case mspcfg.IDEMIX:
+		idemixConfig := &msp.IdemixMSPConfig{}
+		if err := proto.Unmarshal(config.Config, idemixConfig); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal idemix MSP config")
+		}
+		report.MSPID = idemixConfig.Name
+	default:
+		return nil, errors.Errorf("unsupported MSP type %d", config.Type)
+	}
+
+	return report, nil
+}
+
+// AnalyzeConfigBlockCerts analyzes the MSP of every organization of the Application, Orderer and
+// Consortiums groups of a config block, see AnalyzeMSPConfig
+func AnalyzeConfigBlockCerts(block *cb.Block, opts ...CertCheckOption) ([]*MSPCertReport, error) {
+	config, err := ConfigFromBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	return analyzeChannelGroupCerts(config.ChannelGroup, opts)
+}
+
+// AnalyzeProfileCerts analyzes the MSP of every organization of a profile, see AnalyzeMSPConfig. The
+// MSPs are loaded the way they are when the profile is encoded into a genesis block.
+func AnalyzeProfileCerts(profile *genesisconfig.Profile, opts ...CertCheckOption) ([]*MSPCertReport, error) {
+	localProfile, err := genesisToLocalConfig(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	channelGroup, err := encoder.NewChannelGroup(localProfile)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not encode profile")
+	}
+	return analyzeChannelGroupCerts(channelGroup, opts)
+}
+
+func analyzeChannelGroupCerts(channelGroup *cb.ConfigGroup, opts []CertCheckOption) ([]*MSPCertReport, error) {
+	orgs, err := orgMSPs(channelGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*MSPCertReport
+	for _, org := range orgs {
+		report, err := AnalyzeMSPConfig(org.config, opts...)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid MSP of organization %s of group %s", org.orgName, org.groupPath)
+		}
+		report.Group = org.groupPath
+		report.OrgName = org.orgName
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// analyzedCert is a certificate of an MSP along with its report; cert is nil if it could not be parsed
+type analyzedCert struct {
+	raw    []byte
+	cert   *x509.Certificate
+	report *CertReport
+}
+
+type certAnalyzer struct {
+	config  *msp.FabricMSPConfig
+	options *certCheckOptions
+	report  *MSPCertReport
+	certs   map[CertRole][]*analyzedCert
+	crls    []*pkix.CertificateList
+}
+
+func newCertAnalyzer(config *msp.FabricMSPConfig, options *certCheckOptions, report *MSPCertReport) *certAnalyzer {
+	return &certAnalyzer{
+		config:  config,
+		options: options,
+		report:  report,
+		certs:   make(map[CertRole][]*analyzedCert),
+	}
+}
+
+func (a *certAnalyzer) analyze() {
+	for _, role := range []struct {
+		role  CertRole
+		certs [][]byte
+	}{
+		{CertRoleRoot, a.config.RootCerts},
+		{CertRoleIntermediate, a.config.IntermediateCerts},
+		{CertRoleAdmin, a.config.Admins},
+		{CertRoleTLSRoot, a.config.TlsRootCerts},
+		{CertRoleTLSIntermediate, a.config.TlsIntermediateCerts},
+	} {
+		for i, raw := range role.certs {
+			a.addCert(role.role, i, raw)
+		}
+	}
+
+	if len(a.config.RootCerts) == 0 {
+		a.report.Issues = append(a.report.Issues, "MSP has no root certificates")
+	}
+
+	a.parseCRLs()
+
+	cas := append(a.parsedCerts(CertRoleRoot), a.parsedCerts(CertRoleIntermediate)...)
+	tlsCAs := append(a.parsedCerts(CertRoleTLSRoot), a.parsedCerts(CertRoleTLSIntermediate)...)
+
+	a.checkChains(CertRoleRoot, a.parsedCerts(CertRoleRoot), nil)
+	a.checkChains(CertRoleIntermediate, a.parsedCerts(CertRoleRoot), a.parsedCerts(CertRoleIntermediate))
+	a.checkChains(CertRoleAdmin, a.parsedCerts(CertRoleRoot), a.parsedCerts(CertRoleIntermediate))
+	a.checkChains(CertRoleTLSRoot, a.parsedCerts(CertRoleTLSRoot), nil)
+	a.checkChains(CertRoleTLSIntermediate, a.parsedCerts(CertRoleTLSRoot), a.parsedCerts(CertRoleTLSIntermediate))
+
+	a.checkRevocation(cas)
+	a.checkRevocation(tlsCAs)
+	a.checkNodeOUs(cas)
+
+	for _, role := range []CertRole{CertRoleRoot, CertRoleIntermediate, CertRoleAdmin, CertRoleTLSRoot, CertRoleTLSIntermediate} {
+		for _, c := range a.certs[role] {
+			a.report.Certificates = append(a.report.Certificates, c.report)
+		}
+	}
+}
+
+func (a *certAnalyzer) addCert(role CertRole, index int, raw []byte) {
+	c := &analyzedCert{raw: raw, report: &CertReport{Role: role, Index: index}}
+	a.certs[role] = append(a.certs[role], c)
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		c.report.addIssue("no PEM content")
+		return
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		c.report.addIssue("invalid certificate: %s", err)
+		return
+	}
+	c.cert = cert
+
+	c.report.Subject = cert.Subject.String()
+	c.report.Issuer = cert.Issuer.String()
+	c.report.SerialNumber = cert.SerialNumber.String()
+	c.report.NotBefore = cert.NotBefore
+	c.report.NotAfter = cert.NotAfter
+	c.report.KeyAlgorithm = keyAlgorithm(cert)
+
+	now := a.options.now
+	switch {
+	case now.After(cert.NotAfter):
+		c.report.addIssue("expired on %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	case now.Before(cert.NotBefore):
+		c.report.addIssue("not valid before %s", cert.NotBefore.UTC().Format(time.RFC3339))
+	case now.Add(a.options.expiryWarning).After(cert.NotAfter):
+		c.report.addIssue("expires soon, on %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	if role != CertRoleAdmin && !cert.IsCA {
+		c.report.addIssue("not a CA certificate")
+	}
+}
+
+func (a *certAnalyzer) parsedCerts(role CertRole) []*analyzedCert {
+	var certs []*analyzedCert
+	for _, c := range a.certs[role] {
+		if c.cert != nil {
+			certs = append(certs, c)
+		}
+	}
+	return certs
+}
+
+// checkChains checks that the certificates of a role chain to the given roots through the given
+// intermediates. Roots must be self-signed. Validity periods are ignored, expiry is reported separately.
+func (a *certAnalyzer) checkChains(role CertRole, roots, intermediates []*analyzedCert) {
+	for _, c := range a.parsedCerts(role) {
+		if role == CertRoleRoot || role == CertRoleTLSRoot {
+			if err := c.cert.CheckSignatureFrom(c.cert); err != nil {
+				c.report.addIssue("root certificate is not self-signed")
+			}
+			continue
+		}
+		if !chainsTo(c, roots, intermediates) {
+			c.report.addIssue("does not chain to a root certificate of the MSP")
+		}
+	}
+}
+
+func chainsTo(c *analyzedCert, roots, intermediates []*analyzedCert) bool {
+	// each intermediate is used at most once, so that the walk terminates
+	used := make(map[*analyzedCert]bool)
+	for current := c; current != nil; {
+		used[current] = true
+		for _, root := range roots {
+			if current.cert.CheckSignatureFrom(root.cert) == nil {
+				return true
+			}
+		}
+		var next *analyzedCert
+		for _, intermediate := range intermediates {
+			if !used[intermediate] && current.cert.CheckSignatureFrom(intermediate.cert) == nil {
+				next = intermediate
+				break
+			}
+		}
+		current = next
+	}
+	return false
+}
+
+func (a *certAnalyzer) parseCRLs() {
+	for i, raw := range a.config.RevocationList {
+		crl, err := x509.ParseCRL(raw)
+		if err != nil {
+			a.report.Issues = append(a.report.Issues, fmt.Sprintf("revocation list %d is invalid: %s", i, err))
+			continue
+		}
+		a.crls = append(a.crls, crl)
+
+		if crl.TBSCertList.NextUpdate.Before(a.options.now) {
+			a.report.Issues = append(a.report.Issues, fmt.Sprintf("revocation list %d is outdated since %s",
+				i, crl.TBSCertList.NextUpdate.UTC().Format(time.RFC3339)))
+		}
+	}
+}
+
+// checkRevocation marks the CAs which issued revocation lists, and the certificates which are revoked
+// by those lists. Revocation lists which were not issued by a CA of the MSP are reported.
+func (a *certAnalyzer) checkRevocation(cas []*analyzedCert) {
+	for i, crl := range a.crls {
+		for _, ca := range cas {
+			if ca.cert.CheckCRLSignature(crl) != nil {
+				continue
+			}
+			ca.report.HasCRL = true
+
+			for _, role := range []CertRole{CertRoleIntermediate, CertRoleAdmin, CertRoleTLSIntermediate} {
+				for _, c := range a.parsedCerts(role) {
+					if c.report.Revoked || !bytes.Equal(c.cert.RawIssuer, ca.cert.RawSubject) {
+						continue
+					}
+					for _, revoked := range crl.TBSCertList.RevokedCertificates {
+						if revoked.SerialNumber.Cmp(c.cert.SerialNumber) == 0 {
+							c.report.Revoked = true
+							c.report.addIssue("revoked by revocation list %d", i)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// checkNodeOUs checks that the certificates of OU identifiers are CA certificates of the MSP, that
+// the client and peer OUs are defined when NodeOUs are enabled, and that the MSP has admins
+func (a *certAnalyzer) checkNodeOUs(cas []*analyzedCert) {
+	checkOUCert := func(name string, id *msp.FabricOUIdentifier) {
+		if id == nil || len(id.Certificate) == 0 {
+			return
+		}
+		for _, ca := range cas {
+			if bytes.Equal(ca.raw, id.Certificate) {
+				return
+			}
+		}
+		a.report.Issues = append(a.report.Issues, fmt.Sprintf("certificate of %s is not a root or intermediate certificate of the MSP", name))
+	}
+
+	for _, id := range a.config.OrganizationalUnitIdentifiers {
+		checkOUCert("OU "+id.OrganizationalUnitIdentifier, id)
+	}
+
+	nodeOUs := a.config.FabricNodeOus
+	if nodeOUs == nil || !nodeOUs.Enable {
+		if len(a.config.Admins) == 0 {
+			a.report.Issues = append(a.report.Issues, "MSP has no admin certificates and NodeOUs are disabled")
+		}
+		return
+	}
+
+	for _, ou := range []struct {
+		name     string
+		id       *msp.FabricOUIdentifier
+		required bool
+	}{
+		{"client OU", nodeOUs.ClientOuIdentifier, true},
+		{"peer OU", nodeOUs.PeerOuIdentifier, true},
+		{"admin OU", nodeOUs.AdminOuIdentifier, false},
+		{"orderer OU", nodeOUs.OrdererOuIdentifier, false},
+	} {
+		if ou.id == nil || ou.id.OrganizationalUnitIdentifier == "" {
+			if ou.required {
+				a.report.Issues = append(a.report.Issues, fmt.Sprintf("NodeOUs are enabled but the %s is not defined", ou.name))
+			}
+			continue
+		}
+		checkOUCert(ou.name, ou.id)
+	}
+
+	if len(a.config.Admins) == 0 && (nodeOUs.AdminOuIdentifier == nil || nodeOUs.AdminOuIdentifier.OrganizationalUnitIdentifier == "") {
+		a.report.Issues = append(a.report.Issues, "MSP has neither admin certificates nor an admin OU")
+	}
+}
+
+func (r *CertReport) addIssue(format string, args ...interface{}) {
+	r.Issues = append(r.Issues, fmt.Sprintf(format, args...))
+}
+
+func keyAlgorithm(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/stretchr/testify/require"
+	mspcfg "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+)
+
+func TestAnalyzeMSPConfig(t *testing.T) {
+	now := time.Now()
+	root := newTestCertAuthority(t, "ca.org1.example.com", nil, now.Add(365*24*time.Hour), true)
+	intermediate := newTestCertAuthority(t, "ica.org1.example.com", root, now.Add(365*24*time.Hour), true)
+	admin := newTestCertAuthority(t, "admin.org1.example.com", intermediate, now.Add(365*24*time.Hour), false)
+	tlsRoot := newTestCertAuthority(t, "tlsca.org1.example.com", nil, now.Add(365*24*time.Hour), true)
+
+	t.Run("Valid MSP", func(t *testing.T) {
+		report, err := AnalyzeMSPConfig(newTestMSPConfig(t, &msp.FabricMSPConfig{
+			Name:              "Org1MSP",
+			RootCerts:         [][]byte{root.pem},
+			IntermediateCerts: [][]byte{intermediate.pem},
+			Admins:            [][]byte{admin.pem},
+			TlsRootCerts:      [][]byte{tlsRoot.pem},
+			FabricNodeOus: &msp.FabricNodeOUs{
+				Enable:             true,
+				ClientOuIdentifier: &msp.FabricOUIdentifier{Certificate: root.pem, OrganizationalUnitIdentifier: "client"},
+				PeerOuIdentifier:   &msp.FabricOUIdentifier{Certificate: intermediate.pem, OrganizationalUnitIdentifier: "peer"},
+			},
+		}), WithCheckTime(now))
+		require.NoError(t, err)
+
+		require.False(t, report.HasIssues(), "unexpected issues: %v", report.Issues)
+		require.Equal(t, "Org1MSP", report.MSPID)
+		require.Equal(t, "bccsp", report.MSPType)
+		require.Len(t, report.Certificates, 4)
+
+		adminReport := report.Certificates[2]
+		require.Equal(t, CertRoleAdmin, adminReport.Role)
+		require.Equal(t, "CN=admin.org1.example.com", adminReport.Subject)
+		require.Equal(t, "CN=ica.org1.example.com", adminReport.Issuer)
+		require.Equal(t, "ECDSA P-256", adminReport.KeyAlgorithm)
+		require.Equal(t, admin.cert.NotAfter, adminReport.NotAfter)
+	})
+
+	t.Run("Problems", func(t *testing.T) {
+		expiredTLSRoot := newTestCertAuthority(t, "tlsca.org1.example.com", nil, now.Add(-time.Hour), true)
+		expiringIntermediate := newTestCertAuthority(t, "ica2.org1.example.com", root, now.Add(10*24*time.Hour), true)
+		foreignRoot := newTestCertAuthority(t, "ca.org2.example.com", nil, now.Add(365*24*time.Hour), true)
+		foreignAdmin := newTestCertAuthority(t, "admin.org2.example.com", foreignRoot, now.Add(365*24*time.Hour), false)
+
+		report, err := AnalyzeMSPConfig(newTestMSPConfig(t, &msp.FabricMSPConfig{
+			Name:              "Org1MSP",
+			RootCerts:         [][]byte{root.pem},
+			IntermediateCerts: [][]byte{intermediate.pem, expiringIntermediate.pem},
+			Admins:            [][]byte{foreignAdmin.pem, []byte("not a certificate")},
+			TlsRootCerts:      [][]byte{expiredTLSRoot.pem},
+			RevocationList:    [][]byte{root.newCRL(t, intermediate, now.Add(24*time.Hour))},
+			FabricNodeOus: &msp.FabricNodeOUs{
+				Enable:             true,
+				ClientOuIdentifier: &msp.FabricOUIdentifier{Certificate: foreignRoot.pem, OrganizationalUnitIdentifier: "client"},
+			},
+		}), WithCheckTime(now))
+		require.NoError(t, err)
+		require.True(t, report.HasIssues())
+
+		require.Equal(t, []string{
+			"certificate of client OU is not a root or intermediate certificate of the MSP",
+			"NodeOUs are enabled but the peer OU is not defined",
+		}, report.Issues)
+
+		issues := make(map[string][]string)
+		for _, cert := range report.Certificates {
+			issues[string(cert.Role)+"/"+cert.Subject] = cert.Issues
+		}
+		require.Empty(t, issues["root/CN=ca.org1.example.com"])
+		require.Equal(t, []string{"revoked by revocation list 0"}, issues["intermediate/CN=ica.org1.example.com"])
+		require.Len(t, issues["intermediate/CN=ica2.org1.example.com"], 1)
+		require.Contains(t, issues["intermediate/CN=ica2.org1.example.com"][0], "expires soon")
+		require.Equal(t, []string{"does not chain to a root certificate of the MSP"}, issues["admin/CN=admin.org2.example.com"])
+		require.Equal(t, []string{"no PEM content"}, issues["admin/"])
+		require.Len(t, issues["tlsroot/CN=tlsca.org1.example.com"], 1)
+		require.Contains(t, issues["tlsroot/CN=tlsca.org1.example.com"][0], "expired on")
+
+		require.True(t, report.Certificates[0].HasCRL)
+		require.True(t, report.Certificates[1].Revoked)
+	})
+
+	t.Run("Missing admins and outdated revocation list", func(t *testing.T) {
+		report, err := AnalyzeMSPConfig(newTestMSPConfig(t, &msp.FabricMSPConfig{
+			Name:           "Org1MSP",
+			RootCerts:      [][]byte{root.pem},
+			RevocationList: [][]byte{root.newCRL(t, nil, now.Add(-time.Hour)), []byte("not a CRL")},
+		}), WithCheckTime(now))
+		require.NoError(t, err)
+
+		require.Len(t, report.Issues, 3)
+		require.Contains(t, report.Issues[0], "revocation list 0 is outdated since")
+		require.Contains(t, report.Issues[1], "revocation list 1 is invalid")
+		require.Equal(t, "MSP has no admin certificates and NodeOUs are disabled", report.Issues[2])
+	})
+
+	t.Run("Idemix MSP", func(t *testing.T) {
+		config, err := proto.Marshal(&msp.IdemixMSPConfig{Name: "IdemixMSP"})
+		require.NoError(t, err)
+
+		report, err := AnalyzeMSPConfig(&msp.MSPConfig{Type: int32(mspcfg.IDEMIX), Config: config})
+		require.NoError(t, err)
+		require.Equal(t, &MSPCertReport{MSPID: "IdemixMSP", MSPType: "idemix"}, report)
+
+		_, err = AnalyzeMSPConfig(&msp.MSPConfig{Type: int32(mspcfg.OTHER)})
+		require.EqualError(t, err, "unsupported MSP type 2")
+	})
+}
+
+func TestAnalyzeConfigCerts(t *testing.T) {
+	ordererMspDir, cleanup := newTestMspDir(t, "orderer.example.com")
+	defer cleanup()
+
+	org1MspDir, cleanup := newTestMspDir(t, "org1.example.com")
+	defer cleanup()
+
+	policies, _ := channelDefaults()
+	orderer := ordererDefauls()
+	orderer.Organizations = []*genesisconfig.Organization{{
+		Name: "OrdererOrg", ID: "OrdererMSP", MSPDir: ordererMspDir, MSPType: "bccsp", Policies: orgPolicies("OrdererMSP"),
+	}}
+	application := applicationDefaults()
+	application.Organizations = []*genesisconfig.Organization{{
+		Name: "Org1", ID: "Org1MSP", MSPDir: org1MspDir, MSPType: "bccsp", Policies: orgPolicies("Org1MSP"),
+	}}
+	profile := &genesisconfig.Profile{Policies: policies, Orderer: orderer, Application: application}
+
+	checkReports := func(reports []*MSPCertReport) {
+		require.Len(t, reports, 2)
+		require.Equal(t, "Application", reports[0].Group)
+		require.Equal(t, "Org1", reports[0].OrgName)
+		require.Equal(t, "Org1MSP", reports[0].MSPID)
+		require.Equal(t, "Orderer", reports[1].Group)
+		require.Equal(t, "OrdererMSP", reports[1].MSPID)
+
+		// the test certificates are valid for a day
+		require.False(t, reports[0].HasIssues())
+		require.False(t, reports[1].HasIssues())
+	}
+
+	reports, err := AnalyzeProfileCerts(profile, WithExpiryWarning(time.Hour))
+	require.NoError(t, err)
+	checkReports(reports)
+
+	b, err := CreateGenesisBlock(profile, "mychannel")
+	require.NoError(t, err)
+	block := &cb.Block{}
+	require.NoError(t, proto.Unmarshal(b, block))
+
+	reports, err = AnalyzeConfigBlockCerts(block, WithExpiryWarning(time.Hour))
+	require.NoError(t, err)
+	checkReports(reports)
+
+	// with the default warning period, the certificates expire soon
+	reports, err = AnalyzeConfigBlockCerts(block)
+	require.NoError(t, err)
+	require.Contains(t, reports[0].Certificates[0].Issues[0], "expires soon")
+
+	_, err = AnalyzeConfigBlockCerts(nil)
+	require.EqualError(t, err, "missing block")
+}
+
+type testCertAuthority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCertAuthority creates a certificate which is issued by the parent, or self-signed if the parent is nil
+func newTestCertAuthority(t *testing.T, commonName string, parent *testCertAuthority, notAfter time.Time, isCA bool) *testCertAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             notAfter.Add(-2 * 365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	issuer, issuerKey := template, key
+	if parent != nil {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCertAuthority{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// newCRL creates a PEM encoded revocation list which revokes the given certificate, if any
+func (ca *testCertAuthority) newCRL(t *testing.T, revoked *testCertAuthority, nextUpdate time.Time) []byte {
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: nextUpdate.Add(-48 * time.Hour),
+		NextUpdate: nextUpdate,
+	}
+	if revoked != nil {
+		template.RevokedCertificates = []pkix.RevokedCertificate{
+			{SerialNumber: revoked.cert.SerialNumber, RevocationTime: time.Now()},
+		}
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, template, ca.cert, ca.key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+func newTestMSPConfig(t *testing.T, config *msp.FabricMSPConfig) *msp.MSPConfig {
+	configBytes, err := proto.Marshal(config)
+	require.NoError(t, err)
+
+	return &msp.MSPConfig{Type: int32(mspcfg.FABRIC), Config: configBytes}
+}
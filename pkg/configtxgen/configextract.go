@@ -0,0 +1,181 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	mspcfg "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+)
+
+// MspDirManifestFile is the name of the file in which ExtractMspDirs writes its manifest
+const MspDirManifestFile = "manifest.json"
+
+// MspDirManifest lists the MSP directories written by ExtractMspDirs
+type MspDirManifest struct {
+	ChannelID string         `json:"channel_id"`
+	MSPs      []*MspDirEntry `json:"msps"`
+}
+
+// MspDirEntry describes the MSP directory of an organization. Group is the path of the group which
+// contains the organization, e.g. Application or Consortiums/SampleConsortium, and Dir is the path
+// of the MSP directory relative to the output directory.
+type MspDirEntry struct {
+	Group   string `json:"group"`
+	OrgName string `json:"org_name"`
+	MSPID   string `json:"msp_id"`
+	MSPType string `json:"msp_type"`
+	Dir     string `json:"dir"`
+}
+
+// ExtractMspDirs generates the verifying MSP directory of every organization of the Application, Orderer
+// and Consortiums groups of a config block into <outDir>/<group>/<mspid>, see GenerateMspDir. The returned
+// manifest is also written to outDir as MspDirManifestFile.
+func ExtractMspDirs(block *cb.Block, outDir string) (*MspDirManifest, error) {
+	config, err := ConfigFromBlock(block)
+	if err != nil {
+		return nil, err
+	}
+
+	channelID, err := protoutil.GetChainIDFromBlock(block)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not get channel ID from config block")
+	}
+
+	orgs, err := orgMSPs(config.ChannelGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &MspDirManifest{ChannelID: channelID}
+	for _, org := range orgs {
+		mspID, err := mspIDFromConfig(org.config)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid MSP of organization %s of group %s", org.orgName, org.groupPath)
+		}
+
+		dir := filepath.Join(filepath.FromSlash(org.groupPath), mspID)
+		if err := GenerateMspDir(filepath.Join(outDir, dir), org.config); err != nil {
+			return nil, errors.Wrapf(err, "failed to generate MSP directory of organization %s of group %s", org.orgName, org.groupPath)
+		}
+
+		manifest.MSPs = append(manifest.MSPs, &MspDirEntry{
+			Group:   org.groupPath,
+			OrgName: org.orgName,
+			MSPID:   mspID,
+			MSPType: mspcfg.ProviderTypeToString(mspcfg.ProviderType(org.config.Type)),
+			Dir:     dir,
+		})
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal MSP manifest")
+	}
+	if err := os.MkdirAll(outDir, 0750); err != nil {
+		return nil, errors.Wrapf(err, "failed to create directory %s", outDir)
+	}
+	if err := ioutil.WriteFile(filepath.Join(outDir, MspDirManifestFile), manifestBytes, 0640); err != nil {
+		return nil, errors.Wrap(err, "failed to write MSP manifest")
+	}
+
+	return manifest, nil
+}
+
+// orgMSP is the MSP of an organization of a channel config. groupPath is the path of the group which
+// contains the organization, relative to the channel group.
+type orgMSP struct {
+	groupPath string
+	orgName   string
+	config    *msp.MSPConfig
+}
+
+// orgMSPs returns the MSPs of the organizations of the Application, Orderer and Consortiums groups of a
+// channel group, in that order and sorted by organization name within each group
+func orgMSPs(channelGroup *cb.ConfigGroup) ([]*orgMSP, error) {
+	var orgs []*orgMSP
+
+	addOrgs := func(group *cb.ConfigGroup, groupPath string) error {
+		for _, orgName := range sortedGroupKeys(group) {
+			mspValue, ok := group.Groups[orgName].Values[channelconfig.MSPKey]
+			if !ok {
+				return errors.Errorf("organization %s of group %s has no MSP", orgName, groupPath)
+			}
+
+			mspConfig := &msp.MSPConfig{}
+			if err := proto.Unmarshal(mspValue.Value, mspConfig); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal MSP of organization %s of group %s", orgName, groupPath)
+			}
+
+			orgs = append(orgs, &orgMSP{groupPath: groupPath, orgName: orgName, config: mspConfig})
+		}
+		return nil
+	}
+
+	for _, groupKey := range []string{channelconfig.ApplicationGroupKey, channelconfig.OrdererGroupKey} {
+		if group, ok := channelGroup.Groups[groupKey]; ok {
+			if err := addOrgs(group, groupKey); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if consortiums, ok := channelGroup.Groups[channelconfig.ConsortiumsGroupKey]; ok {
+		for _, name := range sortedGroupKeys(consortiums) {
+			if err := addOrgs(consortiums.Groups[name], channelconfig.ConsortiumsGroupKey+"/"+name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return orgs, nil
+}
+
+func mspIDFromConfig(mspConfig *msp.MSPConfig) (string, error) {
+	var mspID string
+	switch mspcfg.ProviderType(mspConfig.Type) {
+	case mspcfg.FABRIC:
+		fabricConfig := &msp.FabricMSPConfig{}
+		if err := proto.Unmarshal(mspConfig.Config, fabricConfig); err != nil {
+			return "", errors.Wrap(err, "failed to unmarshal fabric MSP config")
+		}
+		mspID = fabricConfig.Name
+	case mspcfg.IDEMIX:
+		idemixConfig := &msp.IdemixMSPConfig{}
+		if err := proto.Unmarshal(mspConfig.Config, idemixConfig); err != nil {
+			return "", errors.Wrap(err, "failed to unmarshal idemix MSP config")
+		}
+		mspID = idemixConfig.Name
+	default:
+		return "", errors.Errorf("unsupported MSP type %d", mspConfig.Type)
+	}
+
+	// the MSP ID becomes a directory name
+	if mspID == "" || mspID == "." || mspID == ".." || filepath.Base(mspID) != mspID {
+		return "", errors.Errorf("invalid MSP ID '%s'", mspID)
+	}
+	return mspID, nil
+}
+
+func sortedGroupKeys(group *cb.ConfigGroup) []string {
+	var keys []string
+	for key := range group.Groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
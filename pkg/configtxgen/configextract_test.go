@@ -0,0 +1,135 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	mspcfg "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+)
+
+func TestExtractMspDirs(t *testing.T) {
+	ordererMspDir, cleanup := newTestMspDir(t, "orderer.example.com")
+	defer cleanup()
+
+	org1MspDir, cleanup := newTestMspDir(t, "org1.example.com")
+	defer cleanup()
+
+	newOrg := func(name, mspID, mspDir string) *genesisconfig.Organization {
+		return &genesisconfig.Organization{
+			Name:     name,
+			ID:       mspID,
+			MSPDir:   mspDir,
+			MSPType:  "bccsp",
+			Policies: orgPolicies(mspID),
+		}
+	}
+
+	policies, _ := channelDefaults()
+	orderer := ordererDefauls()
+	orderer.Organizations = []*genesisconfig.Organization{newOrg("OrdererOrg", "OrdererMSP", ordererMspDir)}
+
+	b, err := CreateGenesisBlock(&genesisconfig.Profile{
+		Policies: policies,
+		Orderer:  orderer,
+		Consortiums: map[string]*genesisconfig.Consortium{
+			"SampleConsortium": {Organizations: []*genesisconfig.Organization{newOrg("Org1", "Org1MSP", org1MspDir)}},
+		},
+	}, "system-channel")
+	require.NoError(t, err)
+
+	block := &cb.Block{}
+	require.NoError(t, proto.Unmarshal(b, block))
+
+	outDir, err := ioutil.TempDir("", "mspdirs")
+	require.NoError(t, err)
+	defer os.RemoveAll(outDir)
+
+	manifest, err := ExtractMspDirs(block, outDir)
+	require.NoError(t, err)
+
+	require.Equal(t, &MspDirManifest{
+		ChannelID: "system-channel",
+		MSPs: []*MspDirEntry{
+			{Group: "Orderer", OrgName: "OrdererOrg", MSPID: "OrdererMSP", MSPType: "bccsp", Dir: filepath.Join("Orderer", "OrdererMSP")},
+			{Group: "Consortiums/SampleConsortium", OrgName: "Org1", MSPID: "Org1MSP", MSPType: "bccsp",
+				Dir: filepath.Join("Consortiums", "SampleConsortium", "Org1MSP")},
+		},
+	}, manifest)
+
+	manifestBytes, err := ioutil.ReadFile(filepath.Join(outDir, MspDirManifestFile))
+	require.NoError(t, err)
+	manifest1 := &MspDirManifest{}
+	require.NoError(t, json.Unmarshal(manifestBytes, manifest1))
+	require.Equal(t, manifest, manifest1)
+
+	// the extracted MSP directories reproduce the MSPs of the config
+	config, err := ConfigFromBlock(block)
+	require.NoError(t, err)
+
+	org1Group := config.ChannelGroup.Groups[channelconfig.ConsortiumsGroupKey].Groups["SampleConsortium"].Groups["Org1"]
+	mspConfig := &msp.MSPConfig{}
+	require.NoError(t, proto.Unmarshal(org1Group.Values[channelconfig.MSPKey].Value, mspConfig))
+
+	mspConfig1, err := mspcfg.GetVerifyingMspConfig(filepath.Join(outDir, manifest.MSPs[1].Dir), "Org1MSP", "bccsp")
+	require.NoError(t, err)
+	require.Equal(t, mspConfig.Config, mspConfig1.Config)
+
+	t.Run("Invalid blocks", func(t *testing.T) {
+		_, err := ExtractMspDirs(nil, outDir)
+		require.EqualError(t, err, "missing block")
+
+		block := proto.Clone(block).(*cb.Block)
+		config := proto.Clone(config).(*cb.Config)
+		ordererOrg := config.ChannelGroup.Groups[channelconfig.OrdererGroupKey].Groups["OrdererOrg"]
+		ordererOrg.Values[channelconfig.MSPKey].Value = mustMarshalMSPConfig(t, "../OrdererMSP")
+		setBlockConfig(t, block, config)
+
+		_, err = ExtractMspDirs(block, outDir)
+		require.EqualError(t, err, "invalid MSP of organization OrdererOrg of group Orderer: invalid MSP ID '../OrdererMSP'")
+	})
+}
+
+func mustMarshalMSPConfig(t *testing.T, mspID string) []byte {
+	fabricConfig, err := proto.Marshal(&msp.FabricMSPConfig{Name: mspID})
+	require.NoError(t, err)
+
+	mspConfig, err := proto.Marshal(&msp.MSPConfig{Type: int32(mspcfg.FABRIC), Config: fabricConfig})
+	require.NoError(t, err)
+
+	return mspConfig
+}
+
+// setBlockConfig replaces the config of a config block
+func setBlockConfig(t *testing.T, block *cb.Block, config *cb.Config) {
+	env := &cb.Envelope{}
+	require.NoError(t, proto.Unmarshal(block.Data.Data[0], env))
+	payload := &cb.Payload{}
+	require.NoError(t, proto.Unmarshal(env.Payload, payload))
+
+	configEnv := &cb.ConfigEnvelope{}
+	require.NoError(t, proto.Unmarshal(payload.Data, configEnv))
+	configEnv.Config = config
+
+	var err error
+	payload.Data, err = proto.Marshal(configEnv)
+	require.NoError(t, err)
+	env.Payload, err = proto.Marshal(payload)
+	require.NoError(t, err)
+	block.Data.Data[0], err = proto.Marshal(env)
+	require.NoError(t, err)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/configtxgen/localconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+)
+
+// LoadOption configures how configtx.yaml content is loaded
+type LoadOption func(opts *loadOptions)
+
+type loadOptions struct {
+	envOverrides bool
+}
+
+// WithEnvOverrides applies the CONFIGTX_ prefixed environment variables on top of the loaded content,
+// the way the configtxgen tool does. Within a profile, the variables are relative to the profile,
+// e.g. CONFIGTX_ORDERER_BATCHTIMEOUT.
+func WithEnvOverrides() LoadOption {
+	return func(opts *loadOptions) {
+		opts.envOverrides = true
+	}
+}
+
+// TopLevelFromReader constructs top level configuration from configtx.yaml content. Relative paths in the
+// content, e.g. MSP directories and Raft TLS certificates, are resolved against baseDir; if baseDir is empty
+// they are resolved against the working directory. Environment variables are ignored unless WithEnvOverrides
+// is provided. The profiles are initialized as by ProfileFromReader, so an invalid profile fails the load.
+func TopLevelFromReader(r io.Reader, baseDir string, opts ...LoadOption) (*genesisconfig.TopLevel, error) {
+	options := newLoadOptions(opts)
+
+	config, err := localconfig.LoadTopLevelFromReader(r, baseDir, options.envOverrides)
+	if err != nil {
+		return nil, err
+	}
+	return localToGenesisTopLevel(config)
+}
+
+// TopLevelFromBytes constructs top level configuration from configtx.yaml content, see TopLevelFromReader
+func TopLevelFromBytes(content []byte, baseDir string, opts ...LoadOption) (*genesisconfig.TopLevel, error) {
+	return TopLevelFromReader(bytes.NewReader(content), baseDir, opts...)
+}
+
+// ProfileFromReader constructs the configuration of the given profile from configtx.yaml content, see
+// TopLevelFromReader for the resolution of paths and environment variables
+func ProfileFromReader(r io.Reader, profile string, baseDir string, opts ...LoadOption) (*genesisconfig.Profile, error) {
+	options := newLoadOptions(opts)
+
+	config, err := localconfig.LoadFromReader(profile, r, baseDir, options.envOverrides)
+	if err != nil {
+		return nil, err
+	}
+	return localToGenesisProfile(config)
+}
+
+// ProfileFromBytes constructs the configuration of the given profile from configtx.yaml content, see
+// TopLevelFromReader for the resolution of paths and environment variables
+func ProfileFromBytes(content []byte, profile string, baseDir string, opts ...LoadOption) (*genesisconfig.Profile, error) {
+	return ProfileFromReader(bytes.NewReader(content), profile, baseDir, opts...)
+}
+
+func newLoadOptions(opts []LoadOption) *loadOptions {
+	options := &loadOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+func localToGenesisProfile(config *localconfig.Profile) (*genesisconfig.Profile, error) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	c := &genesisconfig.Profile{}
+	err = json.Unmarshal(b, c)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopLevelFromReader(t *testing.T) {
+	content, err := ioutil.ReadFile(filepath.Join(yamlPath, "configtx.yaml"))
+	require.NoError(t, err)
+
+	baseDir := filepath.Join(os.TempDir(), "configtx")
+
+	config, err := TopLevelFromReader(bytes.NewReader(content), baseDir)
+	require.NoError(t, err)
+
+	require.Equal(t, "SampleOrg", config.Organizations[0].Name)
+	require.Equal(t, filepath.Join(baseDir, "msp"), config.Organizations[0].MSPDir)
+	require.Equal(t, 2*time.Second, config.Orderer.BatchTimeout)
+
+	// Paths within profiles are resolved as well; note that viper lowercases the keys of the config
+	profile := config.Profiles["samplesinglemspsolo"]
+	require.NotNil(t, profile)
+	require.Equal(t, filepath.Join(baseDir, "msp"), profile.Orderer.Organizations[0].MSPDir)
+
+	t.Run("Env overrides", func(t *testing.T) {
+		require.NoError(t, os.Setenv("CONFIGTX_ORDERER_BATCHTIMEOUT", "5s"))
+		defer os.Unsetenv("CONFIGTX_ORDERER_BATCHTIMEOUT")
+
+		config, err := TopLevelFromBytes(content, baseDir)
+		require.NoError(t, err)
+		require.Equal(t, 2*time.Second, config.Orderer.BatchTimeout)
+
+		config, err = TopLevelFromBytes(content, baseDir, WithEnvOverrides())
+		require.NoError(t, err)
+		require.Equal(t, 5*time.Second, config.Orderer.BatchTimeout)
+	})
+
+	t.Run("Invalid content", func(t *testing.T) {
+		_, err := TopLevelFromBytes([]byte("Organizations: ["), baseDir)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Error reading configuration")
+
+		_, err = TopLevelFromBytes([]byte("Unknown: value"), baseDir)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Error unmarshaling config into struct")
+	})
+}
+
+func TestProfileFromReader(t *testing.T) {
+	content, err := ioutil.ReadFile(filepath.Join(yamlPath, "configtx.yaml"))
+	require.NoError(t, err)
+
+	baseDir := filepath.Join(os.TempDir(), "configtx")
+
+	profile, err := ProfileFromReader(bytes.NewReader(content), "SampleSingleMSPSolo", baseDir)
+	require.NoError(t, err)
+
+	require.Equal(t, "solo", profile.Orderer.OrdererType)
+	require.Equal(t, filepath.Join(baseDir, "msp"), profile.Orderer.Organizations[0].MSPDir)
+	require.Equal(t, filepath.Join(baseDir, "msp"), profile.Consortiums["sampleconsortium"].Organizations[0].MSPDir)
+
+	t.Run("Env overrides", func(t *testing.T) {
+		require.NoError(t, os.Setenv("CONFIGTX_ORDERER_BATCHTIMEOUT", "5s"))
+		defer os.Unsetenv("CONFIGTX_ORDERER_BATCHTIMEOUT")
+
+		profile, err := ProfileFromBytes(content, "SampleSingleMSPSolo", baseDir)
+		require.NoError(t, err)
+		require.Equal(t, 2*time.Second, profile.Orderer.BatchTimeout)
+
+		profile, err = ProfileFromBytes(content, "SampleSingleMSPSolo", baseDir, WithEnvOverrides())
+		require.NoError(t, err)
+		require.Equal(t, 5*time.Second, profile.Orderer.BatchTimeout)
+	})
+
+	t.Run("Unknown profile", func(t *testing.T) {
+		_, err := ProfileFromBytes(content, "Unknown", baseDir)
+		require.EqualError(t, err, "Could not find profile: Unknown")
+	})
+}
@@ -0,0 +1,242 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"bytes"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/pkg/identity"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+)
+
+// SignerSerializer signs messages and serializes the identity of the signer
+type SignerSerializer = identity.SignerSerializer
+
+// SignedData is a signature collected for a config update together with the data it signs
+type SignedData = protoutil.SignedData
+
+// ConfigUpdateSignature describes a signature attached to a ConfigUpdateEnvelope
+type ConfigUpdateSignature struct {
+	// MSPID is the MSP of the creator of the signature
+	MSPID string
+	// IDBytes is the certificate of the creator of the signature
+	IDBytes []byte
+	// SignedData is the signed data as verified by the config update policies
+	SignedData *SignedData
+}
+
+// SignConfigUpdate appends a signature by the given signer to the serialized ConfigUpdateEnvelope.
+// A signature already collected from the same creator is replaced.
+func SignConfigUpdate(configUpdateEnvBytes []byte, signer SignerSerializer) ([]byte, error) {
+	configUpdateEnv, err := unmarshalConfigUpdateEnvelope(configUpdateEnvBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	configSig, err := NewConfigSignature(configUpdateEnv.ConfigUpdate, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	creator, err := signatureCreator(configSig)
+	if err != nil {
+		return nil, err
+	}
+
+	var signatures []*cb.ConfigSignature
+	for _, sig := range configUpdateEnv.Signatures {
+		c, err := signatureCreator(sig)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(creator, c) {
+			signatures = append(signatures, sig)
+		}
+	}
+	configUpdateEnv.Signatures = append(signatures, configSig)
+
+	return proto.Marshal(configUpdateEnv)
+}
+
+// NewConfigSignature creates a signature by the given signer over the serialized ConfigUpdate
+func NewConfigSignature(configUpdateBytes []byte, signer SignerSerializer) (*cb.ConfigSignature, error) {
+	if signer == nil {
+		return nil, errors.New("signer is required")
+	}
+
+	sigHeader, err := protoutil.NewSignatureHeader(signer)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not create signature header")
+	}
+
+	sigHeaderBytes, err := proto.Marshal(sigHeader)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal signature header")
+	}
+
+	signature, err := signer.Sign(bytes.Join([][]byte{sigHeaderBytes, configUpdateBytes}, nil))
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not sign config update")
+	}
+
+	return &cb.ConfigSignature{
+		SignatureHeader: sigHeaderBytes,
+		Signature:       signature,
+	}, nil
+}
+
+// MergeConfigUpdateSignatures merges the signatures of serialized ConfigUpdateEnvelopes which were
+// signed separately, e.g. offline by the admins of different orgs. All envelopes must carry the same
+// ConfigUpdate. Signatures are deduplicated by creator, the first signature of a creator is retained.
+func MergeConfigUpdateSignatures(configUpdateEnvBytes ...[]byte) ([]byte, error) {
+	if len(configUpdateEnvBytes) == 0 {
+		return nil, errors.New("no config update envelopes to merge")
+	}
+
+	merged, err := unmarshalConfigUpdateEnvelope(configUpdateEnvBytes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	signatures, err := mergeSignatures(nil, merged.Signatures...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, envBytes := range configUpdateEnvBytes[1:] {
+		configUpdateEnv, err := unmarshalConfigUpdateEnvelope(envBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(merged.ConfigUpdate, configUpdateEnv.ConfigUpdate) {
+			return nil, errors.Errorf("config update of envelope %d does not match the config update of the first envelope", i+1)
+		}
+
+		signatures, err = mergeSignatures(signatures, configUpdateEnv.Signatures...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	merged.Signatures = signatures
+
+	return proto.Marshal(merged)
+}
+
+// CreateSignedConfigUpdateEnvelope wraps the serialized ConfigUpdateEnvelope in a CONFIG_UPDATE Envelope
+// signed by the given signer, ready to be submitted to the ordering service
+func CreateSignedConfigUpdateEnvelope(configUpdateEnvBytes []byte, signer SignerSerializer) (*cb.Envelope, error) {
+	if signer == nil {
+		return nil, errors.New("signer is required")
+	}
+
+	configUpdateEnv, err := unmarshalConfigUpdateEnvelope(configUpdateEnvBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	configUpdate := &cb.ConfigUpdate{}
+	if err := proto.Unmarshal(configUpdateEnv.ConfigUpdate, configUpdate); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal config update")
+	}
+
+	if configUpdate.ChannelId == "" {
+		return nil, errors.New("config update does not specify a channel ID")
+	}
+
+	env, err := protoutil.CreateSignedEnvelope(cb.HeaderType_CONFIG_UPDATE, configUpdate.ChannelId, signer, configUpdateEnv, 0, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not create signed envelope")
+	}
+
+	return env, nil
+}
+
+// ConfigUpdateSignatures lists the signatures collected in the serialized ConfigUpdateEnvelope
+func ConfigUpdateSignatures(configUpdateEnvBytes []byte) ([]*ConfigUpdateSignature, error) {
+	configUpdateEnv, err := unmarshalConfigUpdateEnvelope(configUpdateEnvBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signedData, err := protoutil.ConfigUpdateEnvelopeAsSignedData(configUpdateEnv)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get signed data from config update envelope")
+	}
+
+	signatures := make([]*ConfigUpdateSignature, len(signedData))
+	for i, sd := range signedData {
+		sid := &msp.SerializedIdentity{}
+		if err := proto.Unmarshal(sd.Identity, sid); err != nil {
+			return nil, errors.Wrapf(err, "could not unmarshal creator of signature %d", i)
+		}
+
+		signatures[i] = &ConfigUpdateSignature{
+			MSPID:      sid.Mspid,
+			IDBytes:    sid.IdBytes,
+			SignedData: sd,
+		}
+	}
+
+	return signatures, nil
+}
+
+func unmarshalConfigUpdateEnvelope(configUpdateEnvBytes []byte) (*cb.ConfigUpdateEnvelope, error) {
+	configUpdateEnv := &cb.ConfigUpdateEnvelope{}
+	if err := proto.Unmarshal(configUpdateEnvBytes, configUpdateEnv); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal config update envelope")
+	}
+
+	if len(configUpdateEnv.ConfigUpdate) == 0 {
+		return nil, errors.New("config update envelope does not contain a config update")
+	}
+
+	return configUpdateEnv, nil
+}
+
+// mergeSignatures adds the given signatures to the existing ones, skipping signatures
+// from creators which have already signed
+func mergeSignatures(existing []*cb.ConfigSignature, sigs ...*cb.ConfigSignature) ([]*cb.ConfigSignature, error) {
+	merged := existing
+	for _, sig := range sigs {
+		creator, err := signatureCreator(sig)
+		if err != nil {
+			return nil, err
+		}
+
+		duplicate := false
+		for _, s := range merged {
+			c, err := signatureCreator(s)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Equal(creator, c) {
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			merged = append(merged, sig)
+		}
+	}
+
+	return merged, nil
+}
+
+func signatureCreator(sig *cb.ConfigSignature) ([]byte, error) {
+	sigHeader := &cb.SignatureHeader{}
+	if err := proto.Unmarshal(sig.SignatureHeader, sigHeader); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal signature header")
+	}
+	return sigHeader.Creator, nil
+}
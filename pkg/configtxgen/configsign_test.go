@@ -0,0 +1,218 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+)
+
+func TestSignConfigUpdate(t *testing.T) {
+	configUpdateEnvBytes := newTestConfigUpdateEnvelope(t)
+	org1Signer := newTestSigner(t, "Org1MSP")
+	org2Signer := newTestSigner(t, "Org2MSP")
+
+	signed, err := SignConfigUpdate(configUpdateEnvBytes, org1Signer)
+	require.NoError(t, err)
+
+	signed, err = SignConfigUpdate(signed, org2Signer)
+	require.NoError(t, err)
+
+	// Signing again with the same identity replaces the existing signature
+	signed, err = SignConfigUpdate(signed, org1Signer)
+	require.NoError(t, err)
+
+	signatures, err := ConfigUpdateSignatures(signed)
+	require.NoError(t, err)
+	require.Len(t, signatures, 2)
+	require.Equal(t, "Org2MSP", signatures[0].MSPID)
+	require.Equal(t, "Org1MSP", signatures[1].MSPID)
+	require.Equal(t, org1Signer.certPEM, signatures[1].IDBytes)
+
+	for _, sig := range signatures {
+		require.NoError(t, verifyTestSignature(sig))
+	}
+
+	t.Run("Nil signer", func(t *testing.T) {
+		_, err := SignConfigUpdate(configUpdateEnvBytes, nil)
+		require.EqualError(t, err, "signer is required")
+	})
+
+	t.Run("Signer error", func(t *testing.T) {
+		_, err := SignConfigUpdate(configUpdateEnvBytes, &testSigner{mspID: "Org1MSP", signErr: errors.New("sign error")})
+		require.EqualError(t, err, "could not sign config update: sign error")
+	})
+
+	t.Run("Invalid envelope", func(t *testing.T) {
+		_, err := SignConfigUpdate([]byte("invalid"), org1Signer)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "could not unmarshal config update envelope")
+
+		_, err = SignConfigUpdate(protoutil.MarshalOrPanic(&cb.ConfigUpdateEnvelope{}), org1Signer)
+		require.EqualError(t, err, "config update envelope does not contain a config update")
+	})
+}
+
+func TestMergeConfigUpdateSignatures(t *testing.T) {
+	configUpdateEnvBytes := newTestConfigUpdateEnvelope(t)
+	org1Signer := newTestSigner(t, "Org1MSP")
+	org2Signer := newTestSigner(t, "Org2MSP")
+	org3Signer := newTestSigner(t, "Org3MSP")
+
+	signed1, err := SignConfigUpdate(configUpdateEnvBytes, org1Signer)
+	require.NoError(t, err)
+
+	signed2, err := SignConfigUpdate(configUpdateEnvBytes, org2Signer)
+	require.NoError(t, err)
+	signed2, err = SignConfigUpdate(signed2, org1Signer)
+	require.NoError(t, err)
+
+	signed3, err := SignConfigUpdate(configUpdateEnvBytes, org3Signer)
+	require.NoError(t, err)
+
+	merged, err := MergeConfigUpdateSignatures(signed1, signed2, signed3)
+	require.NoError(t, err)
+
+	signatures, err := ConfigUpdateSignatures(merged)
+	require.NoError(t, err)
+	require.Len(t, signatures, 3)
+	require.Equal(t, "Org1MSP", signatures[0].MSPID)
+	require.Equal(t, "Org2MSP", signatures[1].MSPID)
+	require.Equal(t, "Org3MSP", signatures[2].MSPID)
+
+	// The first signature of Org1 is retained
+	signatures1, err := ConfigUpdateSignatures(signed1)
+	require.NoError(t, err)
+	require.Equal(t, signatures1[0].SignedData.Signature, signatures[0].SignedData.Signature)
+
+	t.Run("No envelopes", func(t *testing.T) {
+		_, err := MergeConfigUpdateSignatures()
+		require.EqualError(t, err, "no config update envelopes to merge")
+	})
+
+	t.Run("Different config update", func(t *testing.T) {
+		other, err := SignConfigUpdate(newTestConfigUpdateEnvelope(t, "otherchannel"), org3Signer)
+		require.NoError(t, err)
+
+		_, err = MergeConfigUpdateSignatures(signed1, other)
+		require.EqualError(t, err, "config update of envelope 1 does not match the config update of the first envelope")
+	})
+}
+
+func TestCreateSignedConfigUpdateEnvelope(t *testing.T) {
+	signer := newTestSigner(t, "Org1MSP")
+
+	signed, err := SignConfigUpdate(newTestConfigUpdateEnvelope(t), signer)
+	require.NoError(t, err)
+
+	env, err := CreateSignedConfigUpdateEnvelope(signed, signer)
+	require.NoError(t, err)
+
+	configUpdateEnv := &cb.ConfigUpdateEnvelope{}
+	chdr, err := protoutil.UnmarshalEnvelopeOfType(env, cb.HeaderType_CONFIG_UPDATE, configUpdateEnv)
+	require.NoError(t, err)
+	require.Len(t, configUpdateEnv.Signatures, 1)
+	require.Equal(t, "mychannel", chdr.ChannelId)
+
+	require.NoError(t, verifyTestSignature(&ConfigUpdateSignature{
+		IDBytes:    signer.certPEM,
+		SignedData: &SignedData{Data: env.Payload, Signature: env.Signature},
+	}))
+
+	_, err = CreateSignedConfigUpdateEnvelope(signed, nil)
+	require.EqualError(t, err, "signer is required")
+
+	_, err = CreateSignedConfigUpdateEnvelope(newTestConfigUpdateEnvelope(t, ""), signer)
+	require.EqualError(t, err, "config update does not specify a channel ID")
+}
+
+func newTestConfigUpdateEnvelope(t *testing.T, channelID ...string) []byte {
+	id := "mychannel"
+	if len(channelID) > 0 {
+		id = channelID[0]
+	}
+
+	configUpdate := &cb.ConfigUpdate{
+		ChannelId: id,
+		ReadSet:   &cb.ConfigGroup{},
+		WriteSet:  &cb.ConfigGroup{Version: 1},
+	}
+
+	envBytes, err := proto.Marshal(&cb.ConfigUpdateEnvelope{ConfigUpdate: protoutil.MarshalOrPanic(configUpdate)})
+	require.NoError(t, err)
+	return envBytes
+}
+
+type testSigner struct {
+	mspID   string
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	signErr error
+}
+
+func newTestSigner(t *testing.T, mspID string) *testSigner {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "admin@" + mspID},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return &testSigner{
+		mspID:   mspID,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (s *testSigner) Sign(message []byte) ([]byte, error) {
+	if s.signErr != nil {
+		return nil, s.signErr
+	}
+	digest := sha256.Sum256(message)
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{r, ss})
+}
+
+func (s *testSigner) Serialize() ([]byte, error) {
+	return proto.Marshal(&msp.SerializedIdentity{Mspid: s.mspID, IdBytes: s.certPEM})
+}
+
+func verifyTestSignature(sig *ConfigUpdateSignature) error {
+	block, _ := pem.Decode(sig.IDBytes)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+	return cert.CheckSignature(x509.ECDSAWithSHA256, sig.SignedData.Data, sig.SignedData.Signature)
+}
@@ -10,12 +10,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-protos-go/common"
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/capabilities"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/tools/protolator"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/tools/protolator/protoext/commonext"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/configtxgen/encoder"
@@ -23,6 +26,7 @@ import (
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/configtxlator/update"
 	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
 	"github.com/trustbloc/fabric-lib-go-ext/pkg/common/logging"
+	extprotolator "github.com/trustbloc/fabric-lib-go-ext/pkg/common/tools/protolator"
 	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
 )
 
@@ -30,23 +34,22 @@ import (
 
 var logger = logging.NewLogger("fablibgoext")
 
+const (
+	// lifecycleEndorsementPolicyKey is the name of the application policy which governs the approval
+	// of chaincode definitions by the Fabric 2.x lifecycle
+	lifecycleEndorsementPolicyKey = "LifecycleEndorsement"
+
+	// endorsementPolicyKey is the name of the default chaincode endorsement policy of the Fabric 2.x lifecycle
+	endorsementPolicyKey = "Endorsement"
+)
+
 // CreateGenesisBlock creates a genesis block for a channel
 func CreateGenesisBlock(config *genesisconfig.Profile, channelID string) ([]byte, error) {
-	localConfig, err := genesisToLocalConfig(config)
-	if err != nil {
-		return nil, err
-	}
-	pgen, err := encoder.NewBootstrapper(localConfig)
-	if err != nil {
-		return nil, errors.WithMessage(err, "could not create bootstrapper")
-	}
-	logger.Debug("Generating genesis block")
 	if config.Orderer == nil {
 		return nil, errors.Errorf("refusing to generate block which is missing orderer section")
 	}
-	genesisBlock := pgen.GenesisBlockForChannel(channelID)
-	logger.Debug("Writing genesis block")
-	return protoutil.Marshal(genesisBlock)
+	logger.Debug("Generating genesis block")
+	return genesisBlock(config, channelID)
 }
 
 // CreateGenesisBlockForOrderer creates a genesis block for a channel
@@ -57,6 +60,78 @@ func CreateGenesisBlockForOrderer(config *genesisconfig.Profile, channelID strin
 	return CreateGenesisBlock(config, channelID)
 }
 
+// CreateApplicationChannelGenesisBlock creates the genesis block of an application channel which is not bootstrapped
+// from a system channel, i.e. a block which may be used by orderers to join the channel through the channel
+// participation API (osnadmin channel join). The profile must define the Orderer and Application sections and must
+// not define Consortiums or a Consortium.
+func CreateApplicationChannelGenesisBlock(config *genesisconfig.Profile, channelID string) ([]byte, error) {
+	switch {
+	case config.Orderer == nil:
+		return nil, errors.Errorf("refusing to generate application channel block which is missing orderer section")
+	case config.Application == nil:
+		return nil, errors.Errorf("refusing to generate application channel block which is missing application section")
+	case config.Consortiums != nil:
+		return nil, errors.Errorf("refusing to generate application channel block which contains a consortiums section")
+	case config.Consortium != "":
+		return nil, errors.Errorf("refusing to generate application channel block which contains a consortium value")
+	}
+
+	logger.Debug("Generating application channel genesis block")
+	return genesisBlock(config, channelID)
+}
+
+// genesisBlock encodes the channel group of the profile into the marshaled genesis block of the channel
+func genesisBlock(config *genesisconfig.Profile, channelID string) ([]byte, error) {
+	if err := validateLifecyclePolicies(config); err != nil {
+		return nil, err
+	}
+	localConfig, err := genesisToLocalConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	pgen, err := encoder.NewBootstrapper(localConfig)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not create bootstrapper")
+	}
+	return protoutil.Marshal(pgen.GenesisBlockForChannel(channelID))
+}
+
+// validateLifecyclePolicies checks that the application of a profile with the V2_0 capability defines the LifecycleEndorsement and
+// Endorsement policies which the Fabric 2.x lifecycle requires, as they are not generated. The sub-policy referenced
+// by an ImplicitMeta lifecycle policy must be defined by every application organization, or it cannot be satisfied.
+func validateLifecyclePolicies(profile *genesisconfig.Profile) error {
+	if profile == nil || profile.Application == nil || !profile.Application.Capabilities[capabilities.ApplicationV2_0] {
+		return nil
+	}
+
+	app := profile.Application
+
+	for _, name := range []string{lifecycleEndorsementPolicyKey, endorsementPolicyKey} {
+		policy, ok := app.Policies[name]
+		if !ok {
+			return errors.Errorf("application policy %s is required by the %s capability but is not defined", name, capabilities.ApplicationV2_0)
+		}
+		if policy.Type != encoder.ImplicitMetaPolicyType {
+			continue
+		}
+
+		implicitMeta, err := policies.ImplicitMetaFromString(policy.Rule)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid application policy %s", name)
+		}
+		for _, org := range app.Organizations {
+			if org.SkipAsForeign {
+				continue
+			}
+			if _, ok := org.Policies[implicitMeta.SubPolicy]; !ok {
+				return errors.Errorf("application policy %s requires policy %s of organization %s, which is not defined", name, implicitMeta.SubPolicy, org.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
 func genesisToLocalConfig(config *genesisconfig.Profile) (*localconfig.Profile, error) {
 	b, err := json.Marshal(config)
 	if err != nil {
@@ -67,9 +142,32 @@ func genesisToLocalConfig(config *genesisconfig.Profile) (*localconfig.Profile,
 	if err != nil {
 		return nil, err
 	}
+	if err := resolveMSPConfigs(profileOrganizations(c)...); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
+// profileOrganizations returns the organizations of all sections of the profile
+func profileOrganizations(profile *localconfig.Profile) []*localconfig.Organization {
+	if profile == nil {
+		return nil
+	}
+	var orgs []*localconfig.Organization
+	if profile.Orderer != nil {
+		orgs = append(orgs, profile.Orderer.Organizations...)
+	}
+	if profile.Application != nil {
+		orgs = append(orgs, profile.Application.Organizations...)
+	}
+	for _, consortium := range profile.Consortiums {
+		if consortium != nil {
+			orgs = append(orgs, consortium.Organizations...)
+		}
+	}
+	return orgs
+}
+
 // InspectBlock inspects a block
 func InspectBlock(data []byte) (string, error) {
 	if len(data) == 0 {
@@ -88,10 +186,29 @@ func InspectBlock(data []byte) (string, error) {
 	return buf.String(), nil
 }
 
+// InspectBlockTo inspects a block as InspectBlock does, but writes the JSON to w one envelope at a time instead of
+// returning it as a string. The options may be used to truncate or hash large bytes fields.
+func InspectBlockTo(w io.Writer, data []byte, opts ...extprotolator.BlockOption) error {
+	if len(data) == 0 {
+		return fmt.Errorf("missing block")
+	}
+	block, err := protoutil.UnmarshalBlock(data)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling to block: %s", err)
+	}
+	if err := extprotolator.DeepMarshalBlockJSON(w, block, opts...); err != nil {
+		return fmt.Errorf("malformed block contents: %s", err)
+	}
+	return nil
+}
+
 // CreateChannelCreateTx creates a Fabric transaction for creating a channel
 func CreateChannelCreateTx(conf, baseProfile *genesisconfig.Profile, channelID string) ([]byte, error) {
 	logger.Debug("Generating new channel configtx")
 
+	if err := validateLifecyclePolicies(conf); err != nil {
+		return nil, err
+	}
 	localConf, err := genesisToLocalConfig(conf)
 	if err != nil {
 		return nil, err
@@ -189,6 +306,9 @@ func InspectOrg(conf *genesisconfig.TopLevel, orgName string) (string, error) {
 
 	for _, org := range localConf.Organizations {
 		if org.Name == orgName {
+			if err := resolveMSPConfigs(org); err != nil {
+				return "", errors.Wrapf(err, "bad org definition for org %s", org.Name)
+			}
 			og, err := encoder.NewConsortiumOrgGroup(org)
 			if err != nil {
 				return "", errors.Wrapf(err, "bad org definition for org %s", org.Name)
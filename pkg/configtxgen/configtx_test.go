@@ -7,18 +7,26 @@ SPDX-License-Identifier: Apache-2.0
 package configtxgen
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/orderer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
 	mspcfg "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/common/tools/protolator"
 	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/protos/bft"
 	"github.com/trustbloc/fabric-lib-go-ext/test/metadata"
 )
 
@@ -274,6 +282,24 @@ func TestCreateAndInspectGenesiBlock(t *testing.T) {
 	require.False(t, s == "", "Failed to inspect genesis block")
 }
 
+func TestInspectBlockTo(t *testing.T) {
+	b, err := CreateGenesisBlock(sampleSingleMSPSolo(), "mychannel")
+	require.NoError(t, err, "Failed to create genesis block")
+
+	s, err := InspectBlock(b)
+	require.NoError(t, err, "Failed to inspect genesis block")
+
+	var buf bytes.Buffer
+	require.NoError(t, InspectBlockTo(&buf, b))
+	require.Equal(t, s, buf.String())
+
+	buf.Reset()
+	require.NoError(t, InspectBlockTo(&buf, b, protolator.WithHashedBytes(32)))
+	require.Contains(t, buf.String(), "sha256")
+
+	require.Error(t, InspectBlockTo(&buf, nil), "Missing block")
+}
+
 func TestCreateAndInspectGenesiBlockForOrderer(t *testing.T) {
 
 	b, err := CreateGenesisBlockForOrderer(sampleSingleMSPSolo(), "mychannel")
@@ -365,3 +391,466 @@ func TestInspectOrg(t *testing.T) {
 	assert.Regexp(t, "bad org definition", err.Error())
 	require.Empty(t, sampleOrgJson)
 }
+
+func TestCreateGenesisBlockV2(t *testing.T) {
+	ordererMspDir, cleanup := newTestMspDir(t, "orderer.example.com")
+	defer cleanup()
+
+	org1MspDir, cleanup := newTestMspDir(t, "org1.example.com")
+	defer cleanup()
+
+	policies, _ := channelDefaults()
+	orderer := ordererDefauls()
+	orderer.Policies["BlockValidation"] = &genesisconfig.Policy{Type: "ImplicitMeta", Rule: "MAJORITY Writers"}
+	orderer.Organizations = []*genesisconfig.Organization{{
+		Name:             "OrdererMSP",
+		ID:               "OrdererMSP",
+		MSPDir:           ordererMspDir,
+		MSPType:          "bccsp",
+		Policies:         orgPolicies("OrdererMSP"),
+		OrdererEndpoints: []string{"orderer0.example.com:7050", "orderer1.example.com:7050"},
+	}}
+
+	org1Policies := orgPolicies("Org1MSP")
+	org1Policies["Endorsement"] = &genesisconfig.Policy{Type: "Signature", Rule: "OR('Org1MSP.peer')"}
+
+	application := applicationDefaults()
+	application.Capabilities = map[string]bool{"V2_0": true}
+	application.Policies["Endorsement"] = &genesisconfig.Policy{Type: "ImplicitMeta", Rule: "ANY Endorsement"}
+	application.Organizations = []*genesisconfig.Organization{{
+		Name:     "Org1MSP",
+		ID:       "Org1MSP",
+		MSPDir:   org1MspDir,
+		MSPType:  "bccsp",
+		Policies: org1Policies,
+	}}
+
+	t.Run("Missing lifecycle policy", func(t *testing.T) {
+		lifecycleEndorsement := application.Policies["LifecycleEndorsement"]
+		delete(application.Policies, "LifecycleEndorsement")
+		defer func() { application.Policies["LifecycleEndorsement"] = lifecycleEndorsement }()
+
+		_, err := CreateGenesisBlock(&genesisconfig.Profile{
+			Policies:    policies,
+			Orderer:     orderer,
+			Application: application,
+		}, "mychannel")
+		require.EqualError(t, err, "application policy LifecycleEndorsement is required by the V2_0 capability but is not defined")
+	})
+
+	t.Run("Missing organization policy", func(t *testing.T) {
+		delete(org1Policies, "Endorsement")
+		defer func() {
+			org1Policies["Endorsement"] = &genesisconfig.Policy{Type: "Signature", Rule: "OR('Org1MSP.peer')"}
+		}()
+
+		_, err := CreateGenesisBlock(&genesisconfig.Profile{
+			Policies:    policies,
+			Orderer:     orderer,
+			Application: application,
+		}, "mychannel")
+		require.EqualError(t, err, "application policy LifecycleEndorsement requires policy Endorsement of organization Org1MSP, which is not defined")
+	})
+
+	b, err := CreateGenesisBlock(&genesisconfig.Profile{
+		Policies:    policies,
+		Orderer:     orderer,
+		Application: application,
+	}, "mychannel")
+	require.NoError(t, err)
+
+	block := &cb.Block{}
+	require.NoError(t, proto.Unmarshal(b, block))
+
+	config, err := ConfigFromBlock(block)
+	require.NoError(t, err)
+
+	ordererGroup := config.ChannelGroup.Groups[channelconfig.OrdererGroupKey]
+	blockValidation := &cb.ImplicitMetaPolicy{}
+	require.NoError(t, proto.Unmarshal(ordererGroup.Policies["BlockValidation"].Policy.Value, blockValidation))
+	require.Equal(t, cb.ImplicitMetaPolicy_MAJORITY, blockValidation.Rule)
+
+	endpoints := &cb.OrdererAddresses{}
+	require.NoError(t, proto.Unmarshal(ordererGroup.Groups["OrdererMSP"].Values[channelconfig.EndpointsKey].Value, endpoints))
+	require.Equal(t, []string{"orderer0.example.com:7050", "orderer1.example.com:7050"}, endpoints.Addresses)
+
+	applicationGroup := config.ChannelGroup.Groups[channelconfig.ApplicationGroupKey]
+
+	// both lifecycle policies are taken from the profile
+	lifecycleEndorsement := &cb.ImplicitMetaPolicy{}
+	require.NoError(t, proto.Unmarshal(applicationGroup.Policies["LifecycleEndorsement"].Policy.Value, lifecycleEndorsement))
+	require.Equal(t, &cb.ImplicitMetaPolicy{SubPolicy: "Endorsement", Rule: cb.ImplicitMetaPolicy_MAJORITY}, lifecycleEndorsement)
+	require.Equal(t, channelconfig.AdminsPolicyKey, applicationGroup.Policies["LifecycleEndorsement"].ModPolicy)
+
+	endorsement := &cb.ImplicitMetaPolicy{}
+	require.NoError(t, proto.Unmarshal(applicationGroup.Policies["Endorsement"].Policy.Value, endorsement))
+	require.Equal(t, cb.ImplicitMetaPolicy_ANY, endorsement.Rule)
+
+	require.Equal(t, int32(cb.Policy_SIGNATURE), applicationGroup.Groups["Org1MSP"].Policies["Endorsement"].Policy.Type)
+
+	t.Run("Without V2_0 capability", func(t *testing.T) {
+		application.Capabilities = map[string]bool{"V1_4_2": true}
+		delete(application.Policies, "LifecycleEndorsement")
+		orderer.Organizations[0].OrdererEndpoints = nil
+		delete(orderer.Policies, "BlockValidation")
+
+		b, err := CreateGenesisBlock(&genesisconfig.Profile{
+			Policies:    policies,
+			Orderer:     orderer,
+			Application: application,
+		}, "mychannel")
+		require.NoError(t, err)
+
+		block := &cb.Block{}
+		require.NoError(t, proto.Unmarshal(b, block))
+
+		config, err := ConfigFromBlock(block)
+		require.NoError(t, err)
+
+		require.NotContains(t, config.ChannelGroup.Groups[channelconfig.ApplicationGroupKey].Policies, "LifecycleEndorsement")
+
+		ordererGroup := config.ChannelGroup.Groups[channelconfig.OrdererGroupKey]
+		require.NotContains(t, ordererGroup.Groups["OrdererMSP"].Values, channelconfig.EndpointsKey)
+
+		blockValidation := &cb.ImplicitMetaPolicy{}
+		require.NoError(t, proto.Unmarshal(ordererGroup.Policies["BlockValidation"].Policy.Value, blockValidation))
+		require.Equal(t, &cb.ImplicitMetaPolicy{SubPolicy: "Writers", Rule: cb.ImplicitMetaPolicy_ANY}, blockValidation)
+	})
+}
+
+func TestCreateApplicationChannelGenesisBlock(t *testing.T) {
+	ordererMspDir, cleanup := newTestMspDir(t, "orderer.example.com")
+	defer cleanup()
+
+	org1MspDir, cleanup := newTestMspDir(t, "org1.example.com")
+	defer cleanup()
+
+	newProfile := func() *genesisconfig.Profile {
+		policies, _ := channelDefaults()
+		orderer := ordererDefauls()
+		orderer.Organizations = []*genesisconfig.Organization{{
+			Name:             "OrdererMSP",
+			ID:               "OrdererMSP",
+			MSPDir:           ordererMspDir,
+			MSPType:          "bccsp",
+			Policies:         orgPolicies("OrdererMSP"),
+			OrdererEndpoints: []string{"orderer.example.com:7050"},
+		}}
+
+		application := applicationDefaults()
+		application.Organizations = []*genesisconfig.Organization{{
+			Name:     "Org1MSP",
+			ID:       "Org1MSP",
+			MSPDir:   org1MspDir,
+			MSPType:  "bccsp",
+			Policies: orgPolicies("Org1MSP"),
+		}}
+
+		return &genesisconfig.Profile{
+			Policies:    policies,
+			Orderer:     orderer,
+			Application: application,
+		}
+	}
+
+	b, err := CreateApplicationChannelGenesisBlock(newProfile(), "mychannel")
+	require.NoError(t, err)
+
+	block := &cb.Block{}
+	require.NoError(t, proto.Unmarshal(b, block))
+	require.Equal(t, uint64(0), block.Header.Number)
+
+	config, err := ConfigFromBlock(block)
+	require.NoError(t, err)
+
+	require.Contains(t, config.ChannelGroup.Groups, channelconfig.OrdererGroupKey)
+	require.Contains(t, config.ChannelGroup.Groups, channelconfig.ApplicationGroupKey)
+	require.NotContains(t, config.ChannelGroup.Groups, channelconfig.ConsortiumsGroupKey)
+	require.NotContains(t, config.ChannelGroup.Values, channelconfig.ConsortiumKey)
+	require.Contains(t, config.ChannelGroup.Groups[channelconfig.ApplicationGroupKey].Groups, "Org1MSP")
+
+	_, err = InspectBlock(b)
+	require.NoError(t, err)
+
+	t.Run("Invalid profile", func(t *testing.T) {
+		config := newProfile()
+		config.Orderer = nil
+		_, err := CreateApplicationChannelGenesisBlock(config, "mychannel")
+		require.EqualError(t, err, "refusing to generate application channel block which is missing orderer section")
+
+		config = newProfile()
+		config.Application = nil
+		_, err = CreateApplicationChannelGenesisBlock(config, "mychannel")
+		require.EqualError(t, err, "refusing to generate application channel block which is missing application section")
+
+		config = newProfile()
+		config.Consortiums = map[string]*genesisconfig.Consortium{}
+		_, err = CreateApplicationChannelGenesisBlock(config, "mychannel")
+		require.EqualError(t, err, "refusing to generate application channel block which contains a consortiums section")
+
+		config = newProfile()
+		config.Consortium = "SampleConsortium"
+		_, err = CreateApplicationChannelGenesisBlock(config, "mychannel")
+		require.EqualError(t, err, "refusing to generate application channel block which contains a consortium value")
+	})
+}
+
+func TestCreateGenesisBlockBFT(t *testing.T) {
+	ordererMspDir, cleanup := newTestMspDir(t, "orderer.example.com")
+	defer cleanup()
+
+	certDir, err := ioutil.TempDir("", "certs")
+	require.NoError(t, err)
+	defer os.RemoveAll(certDir)
+
+	var consenters []*genesisconfig.Consenter
+	for i := 1; i <= 4; i++ {
+		certPath := filepath.Join(certDir, fmt.Sprintf("orderer%d.pem", i))
+		require.NoError(t, ioutil.WriteFile(certPath, newTestCertPEM(t, fmt.Sprintf("orderer%d.example.com", i)), 0640))
+
+		consenters = append(consenters, &genesisconfig.Consenter{
+			ID:            uint32(i),
+			Host:          fmt.Sprintf("orderer%d.example.com", i),
+			Port:          7050,
+			MSPID:         "OrdererMSP",
+			ClientTLSCert: certPath,
+			ServerTLSCert: certPath,
+			Identity:      certPath,
+		})
+	}
+
+	newProfile := func() *genesisconfig.Profile {
+		policies, _ := channelDefaults()
+		orderer := ordererDefauls()
+		orderer.OrdererType = "BFT"
+		orderer.ConsenterMapping = consenters
+		orderer.SmartBFT = &genesisconfig.SmartBFTOptions{
+			RequestBatchMaxCount:    100,
+			RequestBatchMaxInterval: "50ms",
+			LeaderRotation:          genesisconfig.SmartBFTLeaderRotationOff,
+		}
+		orderer.Organizations = []*genesisconfig.Organization{{
+			Name:     "OrdererMSP",
+			ID:       "OrdererMSP",
+			MSPDir:   ordererMspDir,
+			MSPType:  "bccsp",
+			Policies: orgPolicies("OrdererMSP"),
+		}}
+		return &genesisconfig.Profile{
+			Policies: policies,
+			Orderer:  orderer,
+			Consortiums: map[string]*genesisconfig.Consortium{
+				"SampleConsortium": {},
+			},
+		}
+	}
+
+	b, err := CreateGenesisBlockForOrderer(newProfile(), "system-channel")
+	require.NoError(t, err)
+
+	block := &cb.Block{}
+	require.NoError(t, proto.Unmarshal(b, block))
+
+	config, err := ConfigFromBlock(block)
+	require.NoError(t, err)
+
+	ordererGroup := config.ChannelGroup.Groups[channelconfig.OrdererGroupKey]
+
+	consensusType := &orderer.ConsensusType{}
+	require.NoError(t, proto.Unmarshal(ordererGroup.Values[channelconfig.ConsensusTypeKey].Value, consensusType))
+	require.Equal(t, "BFT", consensusType.Type)
+
+	options := &genesisconfig.SmartBFTOptions{}
+	require.NoError(t, proto.Unmarshal(consensusType.Metadata, options))
+	require.Equal(t, uint64(100), options.RequestBatchMaxCount)
+	require.Equal(t, "50ms", options.RequestBatchMaxInterval)
+	require.Equal(t, genesisconfig.SmartBFTLeaderRotationOff, options.LeaderRotation)
+
+	orderers := &bft.Orderers{}
+	require.NoError(t, proto.Unmarshal(ordererGroup.Values[channelconfig.OrderersKey].Value, orderers))
+	require.Len(t, orderers.ConsenterMapping, 4)
+	require.Equal(t, uint32(1), orderers.ConsenterMapping[0].Id)
+	require.Equal(t, "orderer1.example.com", orderers.ConsenterMapping[0].Host)
+	require.Equal(t, "OrdererMSP", orderers.ConsenterMapping[0].MspId)
+	require.NotEmpty(t, orderers.ConsenterMapping[0].Identity)
+	require.NotEmpty(t, orderers.ConsenterMapping[0].ClientTlsCert)
+
+	// With 4 consenters one faulty node is tolerated and blocks must be signed by 3 of them
+	blockValidation := ordererGroup.Policies["BlockValidation"].Policy
+	require.Equal(t, int32(cb.Policy_SIGNATURE), blockValidation.Type)
+	sp := &cb.SignaturePolicyEnvelope{}
+	require.NoError(t, proto.Unmarshal(blockValidation.Value, sp))
+	require.Len(t, sp.Identities, 4)
+	require.Equal(t, int32(3), sp.Rule.GetNOutOf().N)
+
+	s, err := InspectBlock(b)
+	require.NoError(t, err)
+	require.Contains(t, s, `"request_batch_max_interval": "50ms"`)
+	require.Contains(t, s, `"consenter_mapping"`)
+
+	decoded := &cb.Block{}
+	require.NoError(t, protolator.DeepUnmarshalJSON(strings.NewReader(s), decoded))
+	decodedConfig, err := ConfigFromBlock(decoded)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(config, decodedConfig))
+
+	t.Run("Missing options", func(t *testing.T) {
+		config := newProfile()
+		config.Orderer.SmartBFT = nil
+		_, err := CreateGenesisBlock(config, "system-channel")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "consenter options read failed with error consenter options are missing for orderer type BFT")
+	})
+
+	t.Run("Missing consenters", func(t *testing.T) {
+		config := newProfile()
+		config.Orderer.ConsenterMapping = nil
+		_, err := CreateGenesisBlock(config, "system-channel")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cannot load consenter config for orderer type BFT: no consenters defined")
+	})
+
+	t.Run("Missing certificate", func(t *testing.T) {
+		config := newProfile()
+		config.Orderer.ConsenterMapping = []*genesisconfig.Consenter{{Host: "orderer1.example.com", Port: 7050, Identity: "missing.pem"}}
+		_, err := CreateGenesisBlock(config, "system-channel")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cannot load identity for consenter orderer1.example.com:7050")
+	})
+}
+
+func TestCreateGenesisBlockInMemoryMSP(t *testing.T) {
+	ordererMspDir, cleanup := newTestMspDir(t, "orderer.example.com")
+	defer cleanup()
+
+	rootCert := newTestCertPEM(t, "ca.org1.example.com")
+	adminCert := newTestCertPEM(t, "admin.org1.example.com")
+	tlsRootCert := newTestCertPEM(t, "tlsca.org1.example.com")
+
+	newProfile := func(def *genesisconfig.MSPDefinition) *genesisconfig.Profile {
+		policies, _ := channelDefaults()
+		orderer := ordererDefauls()
+		orderer.Organizations = []*genesisconfig.Organization{{
+			Name:     "OrdererMSP",
+			ID:       "OrdererMSP",
+			MSPDir:   ordererMspDir,
+			MSPType:  "bccsp",
+			Policies: orgPolicies("OrdererMSP"),
+		}}
+
+		application := applicationDefaults()
+		application.Organizations = []*genesisconfig.Organization{{
+			Name:     "Org1MSP",
+			ID:       "Org1MSP",
+			MSPDir:   "/does/not/exist",
+			MSPType:  "bccsp",
+			Policies: orgPolicies("Org1MSP"),
+			MSP:      def,
+		}}
+
+		return &genesisconfig.Profile{Policies: policies, Orderer: orderer, Application: application}
+	}
+
+	b, err := CreateGenesisBlock(newProfile(&genesisconfig.MSPDefinition{
+		RootCerts:    [][]byte{rootCert},
+		Admins:       [][]byte{adminCert},
+		TLSRootCerts: [][]byte{tlsRootCert},
+		NodeOUs: &genesisconfig.NodeOUs{
+			Enable:             true,
+			ClientOUIdentifier: &genesisconfig.OUIdentifier{Certificate: rootCert, OrganizationalUnitIdentifier: "client"},
+			PeerOUIdentifier:   &genesisconfig.OUIdentifier{Certificate: rootCert, OrganizationalUnitIdentifier: "peer"},
+		},
+	}), "mychannel")
+	require.NoError(t, err)
+
+	block := &cb.Block{}
+	require.NoError(t, proto.Unmarshal(b, block))
+
+	config, err := ConfigFromBlock(block)
+	require.NoError(t, err)
+
+	orgGroup := config.ChannelGroup.Groups[channelconfig.ApplicationGroupKey].Groups["Org1MSP"]
+	mspConfig := &msp.MSPConfig{}
+	require.NoError(t, proto.Unmarshal(orgGroup.Values[channelconfig.MSPKey].Value, mspConfig))
+	require.Equal(t, int32(mspcfg.FABRIC), mspConfig.Type)
+
+	fabricMSPConfig := &msp.FabricMSPConfig{}
+	require.NoError(t, proto.Unmarshal(mspConfig.Config, fabricMSPConfig))
+	require.Equal(t, "Org1MSP", fabricMSPConfig.Name)
+	require.Equal(t, [][]byte{rootCert}, fabricMSPConfig.RootCerts)
+	require.Equal(t, [][]byte{adminCert}, fabricMSPConfig.Admins)
+	require.Equal(t, [][]byte{tlsRootCert}, fabricMSPConfig.TlsRootCerts)
+	require.True(t, fabricMSPConfig.FabricNodeOus.Enable)
+	require.Equal(t, "peer", fabricMSPConfig.FabricNodeOus.PeerOuIdentifier.OrganizationalUnitIdentifier)
+	require.Nil(t, fabricMSPConfig.FabricNodeOus.AdminOuIdentifier)
+	require.Equal(t, mspcfg.SHA2, fabricMSPConfig.CryptoConfig.SignatureHashFamily)
+
+	t.Run("Invalid definitions", func(t *testing.T) {
+		_, err := CreateGenesisBlock(newProfile(&genesisconfig.MSPDefinition{Admins: [][]byte{adminCert}}), "mychannel")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no root certificates provided")
+
+		_, err = CreateGenesisBlock(newProfile(&genesisconfig.MSPDefinition{
+			RootCerts: [][]byte{rootCert},
+			Admins:    [][]byte{[]byte("not a certificate")},
+		}), "mychannel")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid admin certificate for MSP Org1MSP: no PEM content")
+
+		config := newProfile(&genesisconfig.MSPDefinition{RootCerts: [][]byte{rootCert}})
+		config.Application.Organizations[0].MSPType = "idemix"
+		_, err = CreateGenesisBlock(config, "mychannel")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "in-memory MSP definitions are not supported for MSP type idemix")
+	})
+}
+
+func TestCreateGenesisBlockIdemixOrg(t *testing.T) {
+	ordererMspDir, cleanup := newTestMspDir(t, "orderer.example.com")
+	defer cleanup()
+
+	idemixConfig := &msp.IdemixMSPConfig{Name: "IdemixMSP", Ipk: []byte("ipk"), RevocationPk: []byte("revocation pk")}
+	idemixConfigBytes, err := proto.Marshal(idemixConfig)
+	require.NoError(t, err)
+
+	idemixMspDir := randomMspDir()
+	defer os.RemoveAll(idemixMspDir)
+	require.NoError(t, GenerateMspDir(idemixMspDir, &msp.MSPConfig{Type: int32(mspcfg.IDEMIX), Config: idemixConfigBytes}))
+
+	policies, _ := channelDefaults()
+	orderer := ordererDefauls()
+	orderer.Organizations = []*genesisconfig.Organization{{
+		Name:     "OrdererMSP",
+		ID:       "OrdererMSP",
+		MSPDir:   ordererMspDir,
+		MSPType:  "bccsp",
+		Policies: orgPolicies("OrdererMSP"),
+	}}
+
+	application := applicationDefaults()
+	application.Organizations = []*genesisconfig.Organization{{
+		Name:     "IdemixMSP",
+		ID:       "IdemixMSP",
+		MSPDir:   idemixMspDir,
+		MSPType:  "idemix",
+		Policies: orgPolicies("IdemixMSP"),
+	}}
+
+	b, err := CreateGenesisBlock(&genesisconfig.Profile{Policies: policies, Orderer: orderer, Application: application}, "mychannel")
+	require.NoError(t, err)
+
+	block := &cb.Block{}
+	require.NoError(t, proto.Unmarshal(b, block))
+
+	config, err := ConfigFromBlock(block)
+	require.NoError(t, err)
+
+	orgGroup := config.ChannelGroup.Groups[channelconfig.ApplicationGroupKey].Groups["IdemixMSP"]
+	mspConfig := &msp.MSPConfig{}
+	require.NoError(t, proto.Unmarshal(orgGroup.Values[channelconfig.MSPKey].Value, mspConfig))
+	require.Equal(t, int32(mspcfg.IDEMIX), mspConfig.Type)
+
+	idemixConfig1 := &msp.IdemixMSPConfig{}
+	require.NoError(t, proto.Unmarshal(mspConfig.Config, idemixConfig1))
+	require.True(t, proto.Equal(idemixConfig, idemixConfig1))
+}
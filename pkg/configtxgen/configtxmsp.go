@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package configtxgen
 
 import (
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -15,7 +16,10 @@ import (
 	"github.com/golang/protobuf/proto"
 
 	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/configtxgen/localconfig"
 	mspcfg "github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/msp"
+	"gopkg.in/yaml.v2"
 )
 
 const (
@@ -25,17 +29,181 @@ const (
 	crlsfolder           = "crls"
 	tlscacerts           = "tlscacerts"
 	tlsintermediatecerts = "tlsintermediatecerts"
+	oucerts              = "oucerts"
+	configfilename       = "config.yaml"
+
+	// layout of the files of an Idemix MSP directory
+	idemixmsp                 = "msp"
+	idemixissuerpublickey     = "IssuerPublicKey"
+	idemixrevocationpublickey = "RevocationPublicKey"
 )
 
 // GenerateMspDir generates a MSP directory, using values from the provided MSP config.
 // The intended usage is within the scope of creating a genesis block. This means
-// private keys are currently not handled.
+// private keys are currently not handled. The OU identifiers and NodeOUs of X.509 MSPs
+// are written to config.yaml, so that reading the directory reproduces the MSP config
+// as long as it uses the default crypto config. For Idemix MSPs the issuer public key
+// and the revocation public key are written, but not the signer config.
 func GenerateMspDir(mspDir string, config *msp.MSPConfig) error {
 
-	if mspcfg.ProviderTypeToString(mspcfg.ProviderType(config.Type)) != "bccsp" {
+	switch mspcfg.ProviderType(config.Type) {
+	case mspcfg.FABRIC:
+		return generateFabricMspDir(mspDir, config)
+	case mspcfg.IDEMIX:
+		return generateIdemixMspDir(mspDir, config)
+	default:
 		return fmt.Errorf("Unsupported MSP config type")
 	}
+}
+
+// GetVerifyingMspConfig returns an MSP config given directory, ID and type. Unlike the MSP package, it reads
+// Idemix MSP directories as well, i.e. the issuer public key and the revocation public key which GenerateMspDir
+// writes. The signer config is not read, since a verifying config must not carry secret material.
+func GetVerifyingMspConfig(dir, ID, mspType string) (*msp.MSPConfig, error) {
+	if mspType != mspcfg.ProviderTypeToString(mspcfg.IDEMIX) {
+		return mspcfg.GetVerifyingMspConfig(dir, ID, mspType)
+	}
+
+	ipkBytes, err := ioutil.ReadFile(filepath.Join(dir, idemixmsp, idemixissuerpublickey))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read issuer public key file")
+	}
+
+	revocationPkBytes, err := ioutil.ReadFile(filepath.Join(dir, idemixmsp, idemixrevocationpublickey))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read revocation public key file")
+	}
+
+	confBytes, err := proto.Marshal(&msp.IdemixMSPConfig{
+		Name:         ID,
+		Ipk:          ipkBytes,
+		RevocationPk: revocationPkBytes,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal Idemix MSP config")
+	}
+
+	return &msp.MSPConfig{Config: confBytes, Type: int32(mspcfg.IDEMIX)}, nil
+}
+
+// GetVerifyingMspConfigFromMaterial returns an MSP config for MSP material which is held in memory
+// rather than in an MSP directory. The certificates of the given FabricMSPConfig must be PEM encoded;
+// the crypto config is set to the defaults used for MSP directories if it is missing.
+func GetVerifyingMspConfigFromMaterial(conf *msp.FabricMSPConfig) (*msp.MSPConfig, error) {
+	if len(conf.RootCerts) == 0 {
+		return nil, errors.New("could not load a valid ca certificate: no root certificates provided")
+	}
+
+	for name, material := range map[string][][]byte{
+		"root certificate":             conf.RootCerts,
+		"intermediate certificate":     conf.IntermediateCerts,
+		"admin certificate":            conf.Admins,
+		"revocation list":              conf.RevocationList,
+		"TLS root certificate":         conf.TlsRootCerts,
+		"TLS intermediate certificate": conf.TlsIntermediateCerts,
+	} {
+		for _, raw := range material {
+			if block, _ := pem.Decode(raw); block == nil {
+				return nil, errors.Errorf("invalid %s for MSP %s: no PEM content", name, conf.Name)
+			}
+		}
+	}
+
+	conf = proto.Clone(conf).(*msp.FabricMSPConfig)
+	if conf.CryptoConfig == nil {
+		conf.CryptoConfig = &msp.FabricCryptoConfig{
+			SignatureHashFamily:            mspcfg.SHA2,
+			IdentityIdentifierHashFunction: mspcfg.SHA256,
+		}
+	}
+
+	fmpsjs, err := proto.Marshal(conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal MSP config")
+	}
+
+	return &msp.MSPConfig{Config: fmpsjs, Type: int32(mspcfg.FABRIC)}, nil
+}
+
+// resolveMSPConfigs builds the MSP configs of the organizations which define their MSP in memory or use Idemix
+// MSP directories, which the encoder cannot read, so that the encoder uses them instead of reading the MSP directories
+func resolveMSPConfigs(orgs ...*localconfig.Organization) error {
+	for _, org := range orgs {
+		if org == nil {
+			continue
+		}
+
+		var mspConfig *msp.MSPConfig
+		var err error
+		switch {
+		case org.MSP != nil:
+			mspConfig, err = mspConfigFromDefinition(org)
+		case org.MSPType == mspcfg.ProviderTypeToString(mspcfg.IDEMIX):
+			mspConfig, err = GetVerifyingMspConfig(org.MSPDir, org.ID, org.MSPType)
+		default:
+			continue
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "error loading MSP configuration for org %s", org.Name)
+		}
+		org.MSPConfig = mspConfig
+	}
+	return nil
+}
+
+// mspConfigFromDefinition returns the MSP config of an organization which defines its MSP in memory
+func mspConfigFromDefinition(org *localconfig.Organization) (*msp.MSPConfig, error) {
+	if org.MSPType != "" && org.MSPType != mspcfg.ProviderTypeToString(mspcfg.FABRIC) {
+		return nil, errors.Errorf("in-memory MSP definitions are not supported for MSP type %s", org.MSPType)
+	}
+
+	def := org.MSP
+	fabricMSPConfig := &msp.FabricMSPConfig{
+		Name:                          org.ID,
+		RootCerts:                     def.RootCerts,
+		IntermediateCerts:             def.IntermediateCerts,
+		Admins:                        def.Admins,
+		RevocationList:                def.RevocationList,
+		TlsRootCerts:                  def.TLSRootCerts,
+		TlsIntermediateCerts:          def.TLSIntermediateCerts,
+		OrganizationalUnitIdentifiers: ouIdentifierProtos(def.OrganizationalUnitIdentifiers...),
+	}
+
+	if nodeOUs := def.NodeOUs; nodeOUs != nil {
+		fabricMSPConfig.FabricNodeOus = &msp.FabricNodeOUs{
+			Enable:              nodeOUs.Enable,
+			ClientOuIdentifier:  ouIdentifierProto(nodeOUs.ClientOUIdentifier),
+			PeerOuIdentifier:    ouIdentifierProto(nodeOUs.PeerOUIdentifier),
+			AdminOuIdentifier:   ouIdentifierProto(nodeOUs.AdminOUIdentifier),
+			OrdererOuIdentifier: ouIdentifierProto(nodeOUs.OrdererOUIdentifier),
+		}
+	}
+
+	return GetVerifyingMspConfigFromMaterial(fabricMSPConfig)
+}
+
+func ouIdentifierProtos(ids ...*localconfig.OUIdentifier) []*msp.FabricOUIdentifier {
+	var protos []*msp.FabricOUIdentifier
+	for _, id := range ids {
+		if id == nil {
+			continue
+		}
+		protos = append(protos, ouIdentifierProto(id))
+	}
+	return protos
+}
 
+func ouIdentifierProto(id *localconfig.OUIdentifier) *msp.FabricOUIdentifier {
+	if id == nil {
+		return nil
+	}
+	return &msp.FabricOUIdentifier{
+		Certificate:                  id.Certificate,
+		OrganizationalUnitIdentifier: id.OrganizationalUnitIdentifier,
+	}
+}
+
+func generateFabricMspDir(mspDir string, config *msp.MSPConfig) error {
 	cfg := &msp.FabricMSPConfig{}
 	err := proto.Unmarshal(config.Config, cfg)
 	if err != nil {
@@ -54,30 +222,125 @@ func GenerateMspDir(mspDir string, config *msp.MSPConfig) error {
 		{tlsintermediatecerts, cfg.TlsIntermediateCerts},
 		{crlsfolder, cfg.RevocationList},
 	}
+	// OU certificates usually are root or intermediate certificates, in which case
+	// config.yaml references the files of those
+	certFiles := make(map[string]string)
 	for _, d := range defs {
-		errGen := generateCertDir(filepath.Join(mspDir, d.dir), d.certs)
+		fileNames, errGen := generateCertDir(filepath.Join(mspDir, d.dir), d.certs)
 		if errGen != nil {
 			return errGen
 		}
+		if d.dir != cacerts && d.dir != intermediatecerts {
+			continue
+		}
+		for i, fileName := range fileNames {
+			if _, ok := certFiles[string(d.certs[i])]; !ok {
+				certFiles[string(d.certs[i])] = filepath.Join(d.dir, fileName)
+			}
+		}
 	}
 
-	return err
+	return generateMspConfigFile(mspDir, cfg, certFiles)
 }
 
-func generateCertDir(certDir string, certs [][]byte) error {
-	err := os.MkdirAll(certDir, 0750)
+// generateMspConfigFile writes the config.yaml file which defines the OU identifiers and the NodeOUs of
+// the MSP, if it has any. OU certificates which are not found in certFiles are written to the oucerts folder.
+func generateMspConfigFile(mspDir string, cfg *msp.FabricMSPConfig, certFiles map[string]string) error {
+	if len(cfg.OrganizationalUnitIdentifiers) == 0 && cfg.FabricNodeOus == nil {
+		return nil
+	}
+
+	var ouCerts [][]byte
+	certFile := func(cert []byte) string {
+		if len(cert) == 0 {
+			return ""
+		}
+		if fileName, ok := certFiles[string(cert)]; ok {
+			return fileName
+		}
+		fileName := filepath.Join(oucerts, fmt.Sprintf("cert%d.pem", len(ouCerts)))
+		certFiles[string(cert)] = fileName
+		ouCerts = append(ouCerts, cert)
+		return fileName
+	}
+	ouIdentifier := func(id *msp.FabricOUIdentifier) *mspcfg.OrganizationalUnitIdentifiersConfiguration {
+		if id == nil {
+			return nil
+		}
+		return &mspcfg.OrganizationalUnitIdentifiersConfiguration{
+			Certificate:                  certFile(id.Certificate),
+			OrganizationalUnitIdentifier: id.OrganizationalUnitIdentifier,
+		}
+	}
+
+	configuration := &mspcfg.Configuration{}
+	for _, id := range cfg.OrganizationalUnitIdentifiers {
+		if len(id.Certificate) == 0 {
+			return fmt.Errorf("OU identifier %s has no certificate", id.OrganizationalUnitIdentifier)
+		}
+		configuration.OrganizationalUnitIdentifiers = append(configuration.OrganizationalUnitIdentifiers, ouIdentifier(id))
+	}
+	if nodeOUs := cfg.FabricNodeOus; nodeOUs != nil {
+		configuration.NodeOUs = &mspcfg.NodeOUs{
+			Enable:              nodeOUs.Enable,
+			ClientOUIdentifier:  ouIdentifier(nodeOUs.ClientOuIdentifier),
+			PeerOUIdentifier:    ouIdentifier(nodeOUs.PeerOuIdentifier),
+			AdminOUIdentifier:   ouIdentifier(nodeOUs.AdminOuIdentifier),
+			OrdererOUIdentifier: ouIdentifier(nodeOUs.OrdererOuIdentifier),
+		}
+	}
+
+	if len(ouCerts) != 0 {
+		if _, err := generateCertDir(filepath.Join(mspDir, oucerts), ouCerts); err != nil {
+			return err
+		}
+	}
+
+	configBytes, err := yaml.Marshal(configuration)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(mspDir, configfilename), configBytes, 0640)
+}
+
+func generateIdemixMspDir(mspDir string, config *msp.MSPConfig) error {
+	cfg := &msp.IdemixMSPConfig{}
+	err := proto.Unmarshal(config.Config, cfg)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(mspDir, idemixmsp)
+	err = os.MkdirAll(dir, 0750)
 	if err != nil {
 		return err
 	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, idemixissuerpublickey), cfg.Ipk, 0640)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, idemixrevocationpublickey), cfg.RevocationPk, 0640)
+}
+
+// generateCertDir writes the certificates to the directory and returns the names of their files. The
+// names are zero padded, so that reading the directory returns the certificates in the same order.
+func generateCertDir(certDir string, certs [][]byte) ([]string, error) {
+	err := os.MkdirAll(certDir, 0750)
+	if err != nil {
+		return nil, err
+	}
 	if len(certs) == 0 {
-		return nil
+		return nil, nil
 	}
+	width := len(fmt.Sprintf("%d", len(certs)-1))
+	fileNames := make([]string, len(certs))
 	for counter, certBytes := range certs {
-		fileName := filepath.Join(certDir, "cert"+fmt.Sprintf("%d", counter)+".pem")
-		err = ioutil.WriteFile(fileName, certBytes, 0640)
+		fileNames[counter] = fmt.Sprintf("cert%0*d.pem", width, counter)
+		err = ioutil.WriteFile(filepath.Join(certDir, fileNames[counter]), certBytes, 0640)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	return fileNames, nil
 }
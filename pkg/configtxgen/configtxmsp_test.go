@@ -9,6 +9,7 @@ package configtxgen
 import (
 	"fmt"
 	"math/rand"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -52,3 +53,93 @@ func TestGenerateMspDir(t *testing.T) {
 	require.Equal(t, mspConfig.IntermediateCerts, mspConfig1.IntermediateCerts, "IntermediateCerts are different")
 	require.Equal(t, mspConfig.Admins, mspConfig1.Admins, "Admins are different")
 }
+
+func TestGenerateIdemixMspDir(t *testing.T) {
+	cfg := &msp.IdemixMSPConfig{
+		Name:         "IdemixOrg",
+		Ipk:          []byte("issuer public key"),
+		RevocationPk: []byte("revocation public key"),
+	}
+	cfgBytes, err := proto.Marshal(cfg)
+	require.NoError(t, err)
+
+	dir := randomMspDir()
+	defer os.RemoveAll(dir)
+
+	err = GenerateMspDir(dir, &msp.MSPConfig{Type: int32(mspcfg.IDEMIX), Config: cfgBytes})
+	require.NoError(t, err, "Error generating idemix msp dir")
+
+	mspConfig, err := GetVerifyingMspConfig(dir, "IdemixOrg", "idemix")
+	require.NoError(t, err, "Error generating msp config from idemix dir")
+	require.Equal(t, int32(mspcfg.IDEMIX), mspConfig.Type)
+
+	cfg1 := &msp.IdemixMSPConfig{}
+	require.NoError(t, proto.Unmarshal(mspConfig.Config, cfg1))
+	require.True(t, proto.Equal(cfg, cfg1), "Idemix MSP configs are different")
+
+	_, err = GetVerifyingMspConfig(randomMspDir(), "IdemixOrg", "idemix")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to read issuer public key file")
+
+	err = GenerateMspDir(dir, &msp.MSPConfig{Type: int32(mspcfg.OTHER)})
+	require.EqualError(t, err, "Unsupported MSP config type")
+}
+
+func TestGenerateMspDirRoundTrip(t *testing.T) {
+	rootCert := newTestCertPEM(t, "ca.org1.example.com")
+	intermediateCert := newTestCertPEM(t, "ica.org1.example.com")
+	ouCert := newTestCertPEM(t, "ouca.org1.example.com")
+
+	// more than ten admins, to check that the order of the certificates is kept
+	var admins [][]byte
+	for i := 0; i < 12; i++ {
+		admins = append(admins, newTestCertPEM(t, fmt.Sprintf("admin%d.org1.example.com", i)))
+	}
+
+	cfg, err := GetVerifyingMspConfigFromMaterial(&msp.FabricMSPConfig{
+		Name:              "Org1MSP",
+		RootCerts:         [][]byte{rootCert},
+		IntermediateCerts: [][]byte{intermediateCert},
+		Admins:            admins,
+		TlsRootCerts:      [][]byte{newTestCertPEM(t, "tlsca.org1.example.com")},
+		OrganizationalUnitIdentifiers: []*msp.FabricOUIdentifier{
+			{Certificate: rootCert, OrganizationalUnitIdentifier: "COP"},
+			{Certificate: ouCert, OrganizationalUnitIdentifier: "Blockchain"},
+		},
+		FabricNodeOus: &msp.FabricNodeOUs{
+			Enable:              true,
+			ClientOuIdentifier:  &msp.FabricOUIdentifier{Certificate: intermediateCert, OrganizationalUnitIdentifier: "client"},
+			PeerOuIdentifier:    &msp.FabricOUIdentifier{Certificate: ouCert, OrganizationalUnitIdentifier: "peer"},
+			AdminOuIdentifier:   &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "admin"},
+			OrdererOuIdentifier: &msp.FabricOUIdentifier{Certificate: rootCert, OrganizationalUnitIdentifier: "orderer"},
+		},
+	})
+	require.NoError(t, err)
+
+	dir := randomMspDir()
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, GenerateMspDir(dir, cfg), "Error generating msp dir")
+	require.FileExists(t, filepath.Join(dir, "config.yaml"))
+	require.FileExists(t, filepath.Join(dir, "admincerts", "cert00.pem"))
+	require.FileExists(t, filepath.Join(dir, "oucerts", "cert0.pem"))
+
+	cfg1, err := mspcfg.GetVerifyingMspConfig(dir, "Org1MSP", "bccsp")
+	require.NoError(t, err, "Error generating msp config from dir")
+	require.Equal(t, cfg.Config, cfg1.Config, "MSP configs are different")
+
+	t.Run("OU identifier without certificate", func(t *testing.T) {
+		cfg, err := GetVerifyingMspConfigFromMaterial(&msp.FabricMSPConfig{
+			Name:                          "Org1MSP",
+			RootCerts:                     [][]byte{rootCert},
+			OrganizationalUnitIdentifiers: []*msp.FabricOUIdentifier{{OrganizationalUnitIdentifier: "COP"}},
+		})
+		require.NoError(t, err)
+
+		dir := randomMspDir()
+		defer os.RemoveAll(dir)
+
+		err = GenerateMspDir(dir, cfg)
+		require.EqualError(t, err, "OU identifier COP has no certificate")
+	})
+}
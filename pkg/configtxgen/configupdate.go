@@ -0,0 +1,323 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/configtxgen/encoder"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/configtxgen/localconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/libinternal/configtxlator/update"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/protoutil"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+)
+
+// ConfigEdit is a typed modification applied to a copy of the channel group
+// when computing a config update
+type ConfigEdit func(channelGroup *cb.ConfigGroup) error
+
+// CreateConfigUpdateFromBlock computes a config update for the channel of the given config block.
+// The edits are applied to a copy of the block's config, and the difference between the original
+// and the edited config is returned as an unsigned ConfigUpdateEnvelope.
+func CreateConfigUpdateFromBlock(block *cb.Block, edits ...ConfigEdit) (*cb.ConfigUpdateEnvelope, error) {
+	channelID, err := protoutil.GetChainIDFromBlock(block)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not get channel ID from config block")
+	}
+
+	config, err := ConfigFromBlock(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return CreateConfigUpdate(channelID, config, edits...)
+}
+
+// CreateConfigUpdate computes a config update for the given channel.
+// The edits are applied to a copy of the current config, and the difference between the current
+// and the edited config is returned as an unsigned ConfigUpdateEnvelope.
+func CreateConfigUpdate(channelID string, current *cb.Config, edits ...ConfigEdit) (*cb.ConfigUpdateEnvelope, error) {
+	logger.Debug("Generating config update")
+	if channelID == "" {
+		return nil, errors.New("channel ID is required")
+	}
+
+	if current == nil || current.ChannelGroup == nil {
+		return nil, errors.New("current config has no channel group")
+	}
+
+	updated := proto.Clone(current).(*cb.Config)
+	for _, edit := range edits {
+		if err := edit(updated.ChannelGroup); err != nil {
+			return nil, errors.WithMessage(err, "could not apply config edit")
+		}
+	}
+
+	updt, err := update.Compute(current, updated)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not compute update")
+	}
+	updt.ChannelId = channelID
+
+	return &cb.ConfigUpdateEnvelope{
+		ConfigUpdate: protoutil.MarshalOrPanic(updt),
+	}, nil
+}
+
+// ConfigFromBlock extracts the channel config from a config block
+func ConfigFromBlock(block *cb.Block) (*cb.Config, error) {
+	if block == nil {
+		return nil, errors.New("missing block")
+	}
+
+	env, err := protoutil.ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not extract envelope from config block")
+	}
+
+	configEnv := &cb.ConfigEnvelope{}
+	if _, err := protoutil.UnmarshalEnvelopeOfType(env, cb.HeaderType_CONFIG, configEnv); err != nil {
+		return nil, errors.WithMessage(err, "block is not a config block")
+	}
+
+	if configEnv.Config == nil || configEnv.Config.ChannelGroup == nil {
+		return nil, errors.New("config block does not contain a channel group")
+	}
+
+	return configEnv.Config, nil
+}
+
+// AddApplicationOrg adds the given organization to the application group
+func AddApplicationOrg(org *genesisconfig.Organization) ConfigEdit {
+	return func(channelGroup *cb.ConfigGroup) error {
+		appGroup, err := subGroup(channelGroup, channelconfig.ApplicationGroupKey)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := appGroup.Groups[org.Name]; ok {
+			return errors.Errorf("org '%s' already exists in the application group", org.Name)
+		}
+
+		localOrg, err := genesisToLocalOrganization(org)
+		if err != nil {
+			return err
+		}
+
+		orgGroup, err := encoder.NewApplicationOrgGroup(localOrg)
+		if err != nil {
+			return errors.WithMessagef(err, "bad org definition for org %s", org.Name)
+		}
+
+		if appGroup.Groups == nil {
+			appGroup.Groups = make(map[string]*cb.ConfigGroup)
+		}
+		appGroup.Groups[org.Name] = orgGroup
+		return nil
+	}
+}
+
+// RemoveApplicationOrg removes the named organization from the application group
+func RemoveApplicationOrg(orgName string) ConfigEdit {
+	return func(channelGroup *cb.ConfigGroup) error {
+		appGroup, err := subGroup(channelGroup, channelconfig.ApplicationGroupKey)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := appGroup.Groups[orgName]; !ok {
+			return errors.Errorf("org '%s' does not exist in the application group", orgName)
+		}
+
+		delete(appGroup.Groups, orgName)
+		return nil
+	}
+}
+
+// SetBatchSize changes the batch size of the ordering service
+func SetBatchSize(batchSize genesisconfig.BatchSize) ConfigEdit {
+	return func(channelGroup *cb.ConfigGroup) error {
+		ordererGroup, err := subGroup(channelGroup, channelconfig.OrdererGroupKey)
+		if err != nil {
+			return err
+		}
+
+		return setValue(ordererGroup, channelconfig.BatchSizeValue(
+			batchSize.MaxMessageCount,
+			batchSize.AbsoluteMaxBytes,
+			batchSize.PreferredMaxBytes,
+		), channelconfig.AdminsPolicyKey)
+	}
+}
+
+// SetOrdererAddresses replaces the channel wide list of orderer addresses
+func SetOrdererAddresses(addresses []string) ConfigEdit {
+	return func(channelGroup *cb.ConfigGroup) error {
+		if len(addresses) == 0 {
+			return errors.New("at least one orderer address is required")
+		}
+
+		return setValue(channelGroup, channelconfig.OrdererAddressesValue(addresses), policies.ChannelOrdererAdmins)
+	}
+}
+
+// SetOrdererOrgEndpoints replaces the orderer endpoints of the named orderer organization
+func SetOrdererOrgEndpoints(orgName string, endpoints []string) ConfigEdit {
+	return func(channelGroup *cb.ConfigGroup) error {
+		if len(endpoints) == 0 {
+			return errors.New("at least one orderer endpoint is required")
+		}
+
+		orgGroup, err := subGroup(channelGroup, channelconfig.OrdererGroupKey, orgName)
+		if err != nil {
+			return err
+		}
+
+		return setValue(orgGroup, channelconfig.EndpointsValue(endpoints), channelconfig.AdminsPolicyKey)
+	}
+}
+
+// SetPolicy adds or replaces the named policy of the group at the given path. The path is relative
+// to the channel group, e.g. []string{"Application", "Org1MSP"}. The mod_policy of a replaced policy
+// is retained, new policies are given the Admins mod_policy.
+func SetPolicy(groupPath []string, policyName string, policy *genesisconfig.Policy) ConfigEdit {
+	return func(channelGroup *cb.ConfigGroup) error {
+		group, err := subGroup(channelGroup, groupPath...)
+		if err != nil {
+			return err
+		}
+
+		p, err := newPolicy(policy)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid policy '%s'", policyName)
+		}
+
+		modPolicy := channelconfig.AdminsPolicyKey
+		if existing, ok := group.Policies[policyName]; ok {
+			modPolicy = existing.ModPolicy
+		}
+
+		if group.Policies == nil {
+			group.Policies = make(map[string]*cb.ConfigPolicy)
+		}
+		group.Policies[policyName] = &cb.ConfigPolicy{
+			Policy:    p,
+			ModPolicy: modPolicy,
+		}
+		return nil
+	}
+}
+
+// RemovePolicy removes the named policy from the group at the given path. The path is relative
+// to the channel group, e.g. []string{"Application", "Org1MSP"}.
+func RemovePolicy(groupPath []string, policyName string) ConfigEdit {
+	return func(channelGroup *cb.ConfigGroup) error {
+		group, err := subGroup(channelGroup, groupPath...)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := group.Policies[policyName]; !ok {
+			return errors.Errorf("policy '%s' does not exist", policyName)
+		}
+
+		delete(group.Policies, policyName)
+		return nil
+	}
+}
+
+func subGroup(channelGroup *cb.ConfigGroup, path ...string) (*cb.ConfigGroup, error) {
+	group := channelGroup
+	for i, name := range path {
+		next, ok := group.Groups[name]
+		if !ok {
+			return nil, errors.Errorf("group '%s' does not exist in config", configPath(path[:i+1]))
+		}
+		group = next
+	}
+	return group, nil
+}
+
+func configPath(path []string) string {
+	p := "/" + channelconfig.ChannelGroupKey
+	for _, name := range path {
+		p += "/" + name
+	}
+	return p
+}
+
+func setValue(group *cb.ConfigGroup, value channelconfig.ConfigValue, defaultModPolicy string) error {
+	v, err := protoutil.Marshal(value.Value())
+	if err != nil {
+		return errors.Wrapf(err, "could not marshal value %s", value.Key())
+	}
+
+	modPolicy := defaultModPolicy
+	if existing, ok := group.Values[value.Key()]; ok {
+		modPolicy = existing.ModPolicy
+	}
+
+	if group.Values == nil {
+		group.Values = make(map[string]*cb.ConfigValue)
+	}
+	group.Values[value.Key()] = &cb.ConfigValue{
+		Value:     v,
+		ModPolicy: modPolicy,
+	}
+	return nil
+}
+
+func newPolicy(policy *genesisconfig.Policy) (*cb.Policy, error) {
+	if policy == nil {
+		return nil, errors.New("policy is nil")
+	}
+
+	switch policy.Type {
+	case encoder.ImplicitMetaPolicyType:
+		imp, err := policies.ImplicitMetaFromString(policy.Rule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid implicit meta policy rule '%s'", policy.Rule)
+		}
+		return &cb.Policy{
+			Type:  int32(cb.Policy_IMPLICIT_META),
+			Value: protoutil.MarshalOrPanic(imp),
+		}, nil
+	case encoder.SignaturePolicyType:
+		sp, err := cauthdsl.FromString(policy.Rule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid signature policy rule '%s'", policy.Rule)
+		}
+		return &cb.Policy{
+			Type:  int32(cb.Policy_SIGNATURE),
+			Value: protoutil.MarshalOrPanic(sp),
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown policy type: %s", policy.Type)
+	}
+}
+
+func genesisToLocalOrganization(org *genesisconfig.Organization) (*localconfig.Organization, error) {
+	b, err := json.Marshal(org)
+	if err != nil {
+		return nil, err
+	}
+	c := &localconfig.Organization{}
+	err = json.Unmarshal(b, c)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveMSPConfigs(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,236 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtxgen
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	ab "github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/configtxgen/genesisconfig"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/core/mocks"
+)
+
+func TestCreateConfigUpdateFromBlock(t *testing.T) {
+	block := mockConfigBlock()
+
+	t.Run("Add and remove application org", func(t *testing.T) {
+		mspDir, cleanup := newTestMspDir(t, "org3.example.com")
+		defer cleanup()
+
+		org := &genesisconfig.Organization{
+			Name:     "Org3MSP",
+			ID:       "Org3MSP",
+			MSPDir:   mspDir,
+			MSPType:  "bccsp",
+			Policies: orgPolicies("Org3MSP"),
+		}
+
+		envelope, err := CreateConfigUpdateFromBlock(block, AddApplicationOrg(org), RemoveApplicationOrg("Org1MSP"))
+		require.NoError(t, err)
+
+		configUpdate := unmarshalConfigUpdate(t, envelope)
+		require.Equal(t, "mychannel", configUpdate.ChannelId)
+
+		appWrite := configUpdate.WriteSet.Groups[channelconfig.ApplicationGroupKey]
+		require.NotNil(t, appWrite)
+		require.Equal(t, uint64(1), appWrite.Version)
+		require.Contains(t, appWrite.Groups, "Org3MSP")
+		require.NotContains(t, appWrite.Groups, "Org1MSP")
+		require.Contains(t, appWrite.Groups, "Org2MSP")
+	})
+
+	t.Run("Add existing application org", func(t *testing.T) {
+		_, err := CreateConfigUpdateFromBlock(block, AddApplicationOrg(&genesisconfig.Organization{Name: "Org1MSP"}))
+		require.EqualError(t, err, "could not apply config edit: org 'Org1MSP' already exists in the application group")
+	})
+
+	t.Run("Remove unknown application org", func(t *testing.T) {
+		_, err := CreateConfigUpdateFromBlock(block, RemoveApplicationOrg("Org9MSP"))
+		require.EqualError(t, err, "could not apply config edit: org 'Org9MSP' does not exist in the application group")
+	})
+
+	t.Run("Set batch size", func(t *testing.T) {
+		envelope, err := CreateConfigUpdateFromBlock(block, SetBatchSize(genesisconfig.BatchSize{
+			MaxMessageCount:   50,
+			AbsoluteMaxBytes:  1024 * 1024,
+			PreferredMaxBytes: 512 * 1024,
+		}))
+		require.NoError(t, err)
+
+		configUpdate := unmarshalConfigUpdate(t, envelope)
+		value := configUpdate.WriteSet.Groups[channelconfig.OrdererGroupKey].Values[channelconfig.BatchSizeKey]
+		require.NotNil(t, value)
+
+		batchSize := &ab.BatchSize{}
+		require.NoError(t, proto.Unmarshal(value.Value, batchSize))
+		require.Equal(t, uint32(50), batchSize.MaxMessageCount)
+		require.Equal(t, uint32(512*1024), batchSize.PreferredMaxBytes)
+	})
+
+	t.Run("Set orderer addresses", func(t *testing.T) {
+		envelope, err := CreateConfigUpdateFromBlock(block, SetOrdererAddresses([]string{"orderer1:7050", "orderer2:7050"}))
+		require.NoError(t, err)
+
+		configUpdate := unmarshalConfigUpdate(t, envelope)
+		value := configUpdate.WriteSet.Values[channelconfig.OrdererAddressesKey]
+		require.NotNil(t, value)
+
+		addresses := &cb.OrdererAddresses{}
+		require.NoError(t, proto.Unmarshal(value.Value, addresses))
+		require.Equal(t, []string{"orderer1:7050", "orderer2:7050"}, addresses.Addresses)
+
+		_, err = CreateConfigUpdateFromBlock(block, SetOrdererAddresses(nil))
+		require.EqualError(t, err, "could not apply config edit: at least one orderer address is required")
+	})
+
+	t.Run("Set orderer org endpoints", func(t *testing.T) {
+		envelope, err := CreateConfigUpdateFromBlock(block, SetOrdererOrgEndpoints("OrdererMSP", []string{"orderer1:7050"}))
+		require.NoError(t, err)
+
+		configUpdate := unmarshalConfigUpdate(t, envelope)
+		value := configUpdate.WriteSet.Groups[channelconfig.OrdererGroupKey].Groups["OrdererMSP"].Values[channelconfig.EndpointsKey]
+		require.NotNil(t, value)
+		require.Equal(t, uint64(0), value.Version)
+		require.Equal(t, channelconfig.AdminsPolicyKey, value.ModPolicy)
+
+		_, err = CreateConfigUpdateFromBlock(block, SetOrdererOrgEndpoints("Org9MSP", []string{"orderer1:7050"}))
+		require.EqualError(t, err, "could not apply config edit: group '/Channel/Orderer/Org9MSP' does not exist in config")
+	})
+
+	t.Run("Set and remove policy", func(t *testing.T) {
+		path := []string{channelconfig.ApplicationGroupKey, "Org1MSP"}
+		envelope, err := CreateConfigUpdateFromBlock(block,
+			SetPolicy(path, "Endorsement", &genesisconfig.Policy{Type: "Signature", Rule: "OR('Org1MSP.peer')"}),
+			RemovePolicy(path, "Writers"),
+		)
+		require.NoError(t, err)
+
+		configUpdate := unmarshalConfigUpdate(t, envelope)
+		orgWrite := configUpdate.WriteSet.Groups[channelconfig.ApplicationGroupKey].Groups["Org1MSP"]
+		require.NotNil(t, orgWrite)
+		require.Contains(t, orgWrite.Policies, "Endorsement")
+		require.NotContains(t, orgWrite.Policies, "Writers")
+		require.Equal(t, channelconfig.AdminsPolicyKey, orgWrite.Policies["Endorsement"].ModPolicy)
+
+		_, err = CreateConfigUpdateFromBlock(block, SetPolicy(path, "Endorsement", &genesisconfig.Policy{Type: "Signature", Rule: "OR("}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid policy 'Endorsement'")
+
+		_, err = CreateConfigUpdateFromBlock(block, SetPolicy(path, "Endorsement", &genesisconfig.Policy{Type: "Unknown", Rule: "ANY Readers"}))
+		require.EqualError(t, err, "could not apply config edit: invalid policy 'Endorsement': unknown policy type: Unknown")
+
+		_, err = CreateConfigUpdateFromBlock(block, RemovePolicy(path, "Unknown"))
+		require.EqualError(t, err, "could not apply config edit: policy 'Unknown' does not exist")
+	})
+
+	t.Run("No edits", func(t *testing.T) {
+		_, err := CreateConfigUpdateFromBlock(block)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "could not compute update")
+	})
+
+	t.Run("Not a config block", func(t *testing.T) {
+		_, err := CreateConfigUpdateFromBlock(mocks.NewSimpleMockBlock(), RemoveApplicationOrg("Org1MSP"))
+		require.Error(t, err)
+	})
+}
+
+func TestCreateConfigUpdate(t *testing.T) {
+	config, err := ConfigFromBlock(mockConfigBlock())
+	require.NoError(t, err)
+
+	_, err = CreateConfigUpdate("", config, RemoveApplicationOrg("Org1MSP"))
+	require.EqualError(t, err, "channel ID is required")
+
+	_, err = CreateConfigUpdate("mychannel", &cb.Config{}, RemoveApplicationOrg("Org1MSP"))
+	require.EqualError(t, err, "current config has no channel group")
+
+	envelope, err := CreateConfigUpdate("mychannel", config, RemoveApplicationOrg("Org1MSP"))
+	require.NoError(t, err)
+	require.Equal(t, "mychannel", unmarshalConfigUpdate(t, envelope).ChannelId)
+
+	// The original config must not be modified by the edits
+	require.Contains(t, config.ChannelGroup.Groups[channelconfig.ApplicationGroupKey].Groups, "Org1MSP")
+}
+
+func mockConfigBlock() *cb.Block {
+	builder := &mocks.MockConfigBlockBuilder{
+		MockConfigGroupBuilder: mocks.MockConfigGroupBuilder{
+			ModPolicy:      channelconfig.AdminsPolicyKey,
+			OrdererAddress: "localhost:7050",
+			MSPNames:       []string{"Org1MSP", "Org2MSP"},
+		},
+		ChannelID:       "mychannel",
+		Index:           0,
+		LastConfigIndex: 0,
+	}
+	return builder.Build()
+}
+
+func unmarshalConfigUpdate(t *testing.T, envelope *cb.ConfigUpdateEnvelope) *cb.ConfigUpdate {
+	configUpdate := &cb.ConfigUpdate{}
+	require.NoError(t, proto.Unmarshal(envelope.ConfigUpdate, configUpdate))
+	return configUpdate
+}
+
+func orgPolicies(mspID string) map[string]*genesisconfig.Policy {
+	return map[string]*genesisconfig.Policy{
+		"Admins":  {Type: "Signature", Rule: "OR('" + mspID + ".admin')"},
+		"Readers": {Type: "Signature", Rule: "OR('" + mspID + ".member')"},
+		"Writers": {Type: "Signature", Rule: "OR('" + mspID + ".member')"},
+	}
+}
+
+// newTestMspDir creates a minimal MSP directory containing a self-signed CA certificate,
+// which is also used as the admin certificate
+func newTestMspDir(t *testing.T, commonName string) (string, func()) {
+	dir, err := ioutil.TempDir("", "msp")
+	require.NoError(t, err)
+
+	certPEM := newTestCertPEM(t, commonName)
+	for _, sub := range []string{"cacerts", "admincerts"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0750))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, sub, "cert.pem"), certPEM, 0640))
+	}
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func newTestCertPEM(t *testing.T, commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	"github.com/trustbloc/fabric-lib-go-ext/pkg/protos/bft"
 )
 
 // TopLevel consists of the structs used by the configtxgen tool.
@@ -72,10 +73,14 @@ type Organization struct {
 	MSPType  string             `yaml:"MSPType"`
 	Policies map[string]*Policy `yaml:"Policies"`
 
+	// MSP is the in-memory alternative to MSPDir
+	MSP *MSPDefinition `yaml:"MSP"`
+
 	// Note: Viper deserialization does not seem to care for
 	// embedding of types, so we use one organization struct
 	// for both orderers and applications.
-	AnchorPeers []*AnchorPeer `yaml:"AnchorPeers"`
+	AnchorPeers      []*AnchorPeer `yaml:"AnchorPeers"`
+	OrdererEndpoints []string      `yaml:"OrdererEndpoints"`
 
 	// AdminPrincipal is deprecated and may be removed in a future release
 	// it was used for modifying the default policy generation, but policies
@@ -87,6 +92,36 @@ type Organization struct {
 	SkipAsForeign bool
 }
 
+// MSPDefinition holds the MSP material of an organization in memory. When it is set on an organization,
+// the MSP configuration is built from it and the MSPDir of the organization is not read. Certificates
+// and revocation lists are PEM encoded.
+type MSPDefinition struct {
+	RootCerts                     [][]byte        `yaml:"RootCerts"`
+	IntermediateCerts             [][]byte        `yaml:"IntermediateCerts"`
+	Admins                        [][]byte        `yaml:"Admins"`
+	RevocationList                [][]byte        `yaml:"RevocationList"`
+	TLSRootCerts                  [][]byte        `yaml:"TLSRootCerts"`
+	TLSIntermediateCerts          [][]byte        `yaml:"TLSIntermediateCerts"`
+	OrganizationalUnitIdentifiers []*OUIdentifier `yaml:"OrganizationalUnitIdentifiers"`
+	NodeOUs                       *NodeOUs        `yaml:"NodeOUs"`
+}
+
+// OUIdentifier identifies an organizational unit by the certificate of the CA which issues
+// its identities and the name of the unit.
+type OUIdentifier struct {
+	Certificate                  []byte `yaml:"Certificate"`
+	OrganizationalUnitIdentifier string `yaml:"OrganizationalUnitIdentifier"`
+}
+
+// NodeOUs defines the organizational units which classify the identities of an MSP.
+type NodeOUs struct {
+	Enable              bool          `yaml:"Enable"`
+	ClientOUIdentifier  *OUIdentifier `yaml:"ClientOUIdentifier"`
+	PeerOUIdentifier    *OUIdentifier `yaml:"PeerOUIdentifier"`
+	AdminOUIdentifier   *OUIdentifier `yaml:"AdminOUIdentifier"`
+	OrdererOUIdentifier *OUIdentifier `yaml:"OrdererOUIdentifier"`
+}
+
 // AnchorPeer encodes the necessary fields to identify an anchor peer.
 type AnchorPeer struct {
 	Host string `yaml:"Host"`
@@ -106,8 +141,37 @@ type Orderer struct {
 	MaxChannels   uint64                   `yaml:"MaxChannels"`
 	Capabilities  map[string]bool          `yaml:"Capabilities"`
 	Policies      map[string]*Policy       `yaml:"Policies"`
+
+	// ConsenterMapping and SmartBFT are only used by the BFT consensus type
+	ConsenterMapping []*Consenter     `yaml:"ConsenterMapping"`
+	SmartBFT         *SmartBFTOptions `yaml:"SmartBFT"`
+}
+
+// Consenter represents a consenting node of a BFT ordering service. The certificates and the identity are the
+// paths of the PEM files which contain them.
+type Consenter struct {
+	ID            uint32 `yaml:"ID"`
+	Host          string `yaml:"Host"`
+	Port          uint32 `yaml:"Port"`
+	MSPID         string `yaml:"MSPID"`
+	ClientTLSCert string `yaml:"ClientTLSCert"`
+	ServerTLSCert string `yaml:"ServerTLSCert"`
+	Identity      string `yaml:"Identity"`
 }
 
+// SmartBFTOptions contains the options of a BFT ordering service, which are set as its consensus metadata
+type SmartBFTOptions = bft.Options
+
+// SmartBFTLeaderRotation defines whether the leader of a BFT ordering service is rotated
+type SmartBFTLeaderRotation = bft.Options_LeaderRotation
+
+// Leader rotation modes of a BFT ordering service
+const (
+	SmartBFTLeaderRotationUnspecified = bft.Options_ROTATION_UNSPECIFIED
+	SmartBFTLeaderRotationOff         = bft.Options_ROTATION_OFF
+	SmartBFTLeaderRotationOn          = bft.Options_ROTATION_ON
+)
+
 // BatchSize contains configuration affecting the size of batches.
 type BatchSize struct {
 	MaxMessageCount   uint32 `yaml:"MaxMessageCount"`
@@ -0,0 +1,395 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package genesisconfig
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/trustbloc/fabric-lib-go-ext/internal/github.com/hyperledger/fabric/common/policies"
+)
+
+const (
+	signaturePolicyType    = "Signature"
+	implicitMetaPolicyType = "ImplicitMeta"
+)
+
+var requiredPolicies = []string{"Admins", "Readers", "Writers"}
+
+var validMSPTypes = map[string]bool{"bccsp": true, "idemix": true}
+
+// ValidationError describes a problem with a field of a profile. Field is the path of the field relative
+// to the profile, e.g. Orderer.EtcdRaft.Consenters[2].ServerTlsCert.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors lists all the problems found in a profile
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks that a genesis block or channel creation transaction may be created from the profile.
+// It reports all the problems found, rather than only the first one, as ValidationErrors; nil is returned
+// if the profile is valid. Defaults are not filled in, so fields which are defaulted when loading a
+// configtx.yaml file must be set on profiles which are constructed programmatically.
+func Validate(profile *Profile) error {
+	v := &validator{}
+
+	if profile == nil {
+		v.addf("", "profile is nil")
+		return v.errs
+	}
+
+	if profile.Orderer == nil && profile.Application == nil {
+		v.addf("", "profile must define an Orderer or an Application section")
+	}
+
+	v.validatePolicies("Policies", profile.Policies)
+
+	if profile.Orderer != nil {
+		v.validateOrderer("Orderer", profile.Orderer)
+	}
+
+	if profile.Application != nil {
+		v.validateApplication("Application", profile.Application)
+	}
+
+	for _, name := range sortedKeys(profile.Consortiums) {
+		consortium := profile.Consortiums[name]
+		field := "Consortiums." + name
+		if consortium == nil {
+			v.addf(field, "consortium is nil")
+			continue
+		}
+		v.validateOrganizations(field+".Organizations", consortium.Organizations)
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) addf(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validateOrderer(field string, orderer *Orderer) {
+	if orderer.BatchTimeout <= 0 {
+		v.addf(field+".BatchTimeout", "must be positive")
+	}
+
+	batchSize := orderer.BatchSize
+	if batchSize.MaxMessageCount == 0 {
+		v.addf(field+".BatchSize.MaxMessageCount", "must be set")
+	}
+	if batchSize.AbsoluteMaxBytes == 0 {
+		v.addf(field+".BatchSize.AbsoluteMaxBytes", "must be set")
+	}
+	if batchSize.PreferredMaxBytes == 0 {
+		v.addf(field+".BatchSize.PreferredMaxBytes", "must be set")
+	} else if batchSize.PreferredMaxBytes > batchSize.AbsoluteMaxBytes {
+		v.addf(field+".BatchSize.PreferredMaxBytes", "must not be greater than AbsoluteMaxBytes (%d)", batchSize.AbsoluteMaxBytes)
+	}
+
+	switch orderer.OrdererType {
+	case "solo":
+	case "kafka":
+		if len(orderer.Kafka.Brokers) == 0 {
+			v.addf(field+".Kafka.Brokers", "must be set for orderer type kafka")
+		}
+		for i, broker := range orderer.Kafka.Brokers {
+			v.validateAddress(fmt.Sprintf("%s.Kafka.Brokers[%d]", field, i), broker)
+		}
+	case "etcdraft":
+		v.validateEtcdRaft(field+".EtcdRaft", orderer)
+	case "BFT":
+		v.validateBFT(field, orderer)
+	case "":
+		v.addf(field+".OrdererType", "must be set")
+	default:
+		v.addf(field+".OrdererType", "unknown orderer type: %s", orderer.OrdererType)
+	}
+
+	for i, address := range orderer.Addresses {
+		v.validateAddress(fmt.Sprintf("%s.Addresses[%d]", field, i), address)
+	}
+
+	v.validatePolicies(field+".Policies", orderer.Policies)
+	v.validateOrganizations(field+".Organizations", orderer.Organizations)
+}
+
+func (v *validator) validateEtcdRaft(field string, orderer *Orderer) {
+	if orderer.EtcdRaft == nil {
+		v.addf(field, "must be set for orderer type etcdraft")
+		return
+	}
+
+	if options := orderer.EtcdRaft.Options; options != nil {
+		if _, err := time.ParseDuration(options.TickInterval); err != nil {
+			v.addf(field+".Options.TickInterval", "invalid duration '%s'", options.TickInterval)
+		}
+		if options.HeartbeatTick == 0 {
+			v.addf(field+".Options.HeartbeatTick", "must be set")
+		}
+		if options.ElectionTick <= options.HeartbeatTick {
+			v.addf(field+".Options.ElectionTick", "must be greater than HeartbeatTick (%d)", options.HeartbeatTick)
+		}
+		if options.MaxInflightBlocks == 0 {
+			v.addf(field+".Options.MaxInflightBlocks", "must be set")
+		}
+	}
+
+	if len(orderer.EtcdRaft.Consenters) == 0 {
+		v.addf(field+".Consenters", "must not be empty")
+	}
+
+	for i, c := range orderer.EtcdRaft.Consenters {
+		consenterField := fmt.Sprintf("%s.Consenters[%d]", field, i)
+		if c == nil {
+			v.addf(consenterField, "consenter is nil")
+			continue
+		}
+		if c.Host == "" {
+			v.addf(consenterField+".Host", "must be set")
+		}
+		if c.Port == 0 {
+			v.addf(consenterField+".Port", "must be set")
+		}
+		if len(c.ClientTlsCert) == 0 {
+			v.addf(consenterField+".ClientTlsCert", "must be set")
+		}
+		if len(c.ServerTlsCert) == 0 {
+			v.addf(consenterField+".ServerTlsCert", "must be set")
+		}
+	}
+}
+
+func (v *validator) validateBFT(field string, orderer *Orderer) {
+	if orderer.SmartBFT == nil {
+		v.addf(field+".SmartBFT", "must be set for orderer type BFT")
+	} else {
+		options := orderer.SmartBFT
+		for _, f := range []struct{ name, value string }{
+			{"RequestBatchMaxInterval", options.RequestBatchMaxInterval},
+			{"RequestForwardTimeout", options.RequestForwardTimeout},
+			{"RequestComplainTimeout", options.RequestComplainTimeout},
+			{"RequestAutoRemoveTimeout", options.RequestAutoRemoveTimeout},
+			{"ViewChangeResendInterval", options.ViewChangeResendInterval},
+			{"ViewChangeTimeout", options.ViewChangeTimeout},
+			{"LeaderHeartbeatTimeout", options.LeaderHeartbeatTimeout},
+			{"CollectTimeout", options.CollectTimeout},
+			{"RequestPoolSubmitTimeout", options.RequestPoolSubmitTimeout},
+		} {
+			if f.value == "" {
+				continue
+			}
+			if _, err := time.ParseDuration(f.value); err != nil {
+				v.addf(field+".SmartBFT."+f.name, "invalid duration '%s'", f.value)
+			}
+		}
+	}
+
+	if len(orderer.ConsenterMapping) == 0 {
+		v.addf(field+".ConsenterMapping", "must not be empty")
+	}
+
+	ids := make(map[uint32]bool)
+	for i, c := range orderer.ConsenterMapping {
+		consenterField := fmt.Sprintf("%s.ConsenterMapping[%d]", field, i)
+		if c == nil {
+			v.addf(consenterField, "consenter is nil")
+			continue
+		}
+		if ids[c.ID] {
+			v.addf(consenterField+".ID", "duplicate consenter ID %d", c.ID)
+		}
+		ids[c.ID] = true
+		for _, f := range []struct{ name, value string }{
+			{"Host", c.Host}, {"MSPID", c.MSPID}, {"ClientTLSCert", c.ClientTLSCert},
+			{"ServerTLSCert", c.ServerTLSCert}, {"Identity", c.Identity},
+		} {
+			if f.value == "" {
+				v.addf(consenterField+"."+f.name, "must be set")
+			}
+		}
+		if c.Port == 0 {
+			v.addf(consenterField+".Port", "must be set")
+		}
+	}
+}
+
+func (v *validator) validateApplication(field string, application *Application) {
+	v.validatePolicies(field+".Policies", application.Policies)
+	v.validateOrganizations(field+".Organizations", application.Organizations)
+
+	for _, resource := range sortedKeys(application.ACLs) {
+		if application.ACLs[resource] == "" {
+			v.addf(field+".ACLs."+resource, "policy reference must be set")
+		}
+	}
+}
+
+func (v *validator) validateOrganizations(field string, orgs []*Organization) {
+	names := make(map[string]bool)
+	for i, org := range orgs {
+		orgField := fmt.Sprintf("%s[%d]", field, i)
+		if org == nil {
+			v.addf(orgField, "organization is nil")
+			continue
+		}
+
+		if org.Name == "" {
+			v.addf(orgField+".Name", "must be set")
+		} else if names[org.Name] {
+			v.addf(orgField+".Name", "duplicate organization name %s", org.Name)
+		}
+		names[org.Name] = true
+
+		if org.SkipAsForeign {
+			continue
+		}
+
+		if org.ID == "" {
+			v.addf(orgField+".ID", "must be set")
+		}
+		if org.MSP != nil {
+			v.validateMSPDefinition(orgField+".MSP", org)
+		} else if org.MSPDir == "" {
+			v.addf(orgField+".MSPDir", "must be set")
+		}
+		if !validMSPTypes[org.MSPType] {
+			v.addf(orgField+".MSPType", "must be bccsp or idemix but is '%s'", org.MSPType)
+		}
+
+		v.validatePolicies(orgField+".Policies", org.Policies)
+
+		for j, anchorPeer := range org.AnchorPeers {
+			anchorPeerField := fmt.Sprintf("%s.AnchorPeers[%d]", orgField, j)
+			if anchorPeer == nil {
+				v.addf(anchorPeerField, "anchor peer is nil")
+				continue
+			}
+			if anchorPeer.Host == "" {
+				v.addf(anchorPeerField+".Host", "must be set")
+			}
+			if anchorPeer.Port <= 0 {
+				v.addf(anchorPeerField+".Port", "must be positive")
+			}
+		}
+
+		for j, endpoint := range org.OrdererEndpoints {
+			v.validateAddress(fmt.Sprintf("%s.OrdererEndpoints[%d]", orgField, j), endpoint)
+		}
+	}
+}
+
+func (v *validator) validateMSPDefinition(field string, org *Organization) {
+	if org.MSPType != "bccsp" {
+		v.addf(field, "in-memory MSP definitions are only supported for MSP type bccsp")
+	}
+
+	def := org.MSP
+	if len(def.RootCerts) == 0 {
+		v.addf(field+".RootCerts", "must not be empty")
+	}
+
+	for _, f := range []struct {
+		name  string
+		certs [][]byte
+	}{
+		{"RootCerts", def.RootCerts},
+		{"IntermediateCerts", def.IntermediateCerts},
+		{"Admins", def.Admins},
+		{"RevocationList", def.RevocationList},
+		{"TLSRootCerts", def.TLSRootCerts},
+		{"TLSIntermediateCerts", def.TLSIntermediateCerts},
+	} {
+		for i, cert := range f.certs {
+			if block, _ := pem.Decode(cert); block == nil {
+				v.addf(fmt.Sprintf("%s.%s[%d]", field, f.name, i), "no PEM content")
+			}
+		}
+	}
+}
+
+func (v *validator) validatePolicies(field string, policyMap map[string]*Policy) {
+	for _, name := range requiredPolicies {
+		if policyMap[name] == nil {
+			v.addf(field+"."+name, "required policy is missing")
+		}
+	}
+
+	for _, name := range sortedKeys(policyMap) {
+		policy := policyMap[name]
+		if policy == nil {
+			continue
+		}
+
+		policyField := field + "." + name
+		switch policy.Type {
+		case implicitMetaPolicyType:
+			if _, err := policies.ImplicitMetaFromString(policy.Rule); err != nil {
+				v.addf(policyField+".Rule", "invalid implicit meta policy rule '%s': %s", policy.Rule, err)
+			}
+		case signaturePolicyType:
+			if _, err := cauthdsl.FromString(policy.Rule); err != nil {
+				v.addf(policyField+".Rule", "invalid signature policy rule '%s': %s", policy.Rule, err)
+			}
+		default:
+			v.addf(policyField+".Type", "unknown policy type: %s", policy.Type)
+		}
+	}
+}
+
+func (v *validator) validateAddress(field, address string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host == "" {
+		v.addf(field, "invalid address '%s', expected host:port", address)
+		return
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		v.addf(field, "invalid port in address '%s'", address)
+	}
+}
+
+// sortedKeys returns the keys of a map with string keys in sorted order, so that problems are reported
+// in a deterministic order
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,175 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package genesisconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("Valid profile", func(t *testing.T) {
+		require.NoError(t, Validate(validProfile()))
+	})
+
+	t.Run("All problems are reported", func(t *testing.T) {
+		profile := validProfile()
+		profile.Policies["Admins"].Rule = "OR('SampleOrg.admin'"
+		profile.Orderer.BatchTimeout = 0
+		profile.Orderer.BatchSize.PreferredMaxBytes = 2 * profile.Orderer.BatchSize.AbsoluteMaxBytes
+		profile.Orderer.EtcdRaft.Options.ElectionTick = 1
+		profile.Orderer.EtcdRaft.Consenters[2].ServerTlsCert = nil
+		profile.Orderer.Organizations[0].OrdererEndpoints = []string{"orderer.example.com"}
+		profile.Application.Organizations = append(profile.Application.Organizations, &Organization{Name: "Org1MSP", SkipAsForeign: true})
+		profile.Application.Organizations[0].MSPType = ""
+		profile.Application.Organizations[0].AnchorPeers = []*AnchorPeer{{Host: "peer0.org1.example.com"}}
+		delete(profile.Application.Organizations[0].Policies, "Writers")
+		profile.Application.Policies["Endorsement"] = &Policy{Type: "Unknown"}
+		profile.Consortiums = map[string]*Consortium{"SampleConsortium": {Organizations: []*Organization{{ID: "Org2MSP", MSPType: "bccsp"}}}}
+
+		err := Validate(profile)
+		require.Error(t, err)
+
+		errs, ok := err.(ValidationErrors)
+		require.True(t, ok)
+
+		var fields []string
+		for _, e := range errs {
+			fields = append(fields, e.Field)
+		}
+		require.Equal(t, []string{
+			"Policies.Admins.Rule",
+			"Orderer.BatchTimeout",
+			"Orderer.BatchSize.PreferredMaxBytes",
+			"Orderer.EtcdRaft.Options.ElectionTick",
+			"Orderer.EtcdRaft.Consenters[2].ServerTlsCert",
+			"Orderer.Organizations[0].OrdererEndpoints[0]",
+			"Application.Policies.Endorsement.Type",
+			"Application.Organizations[0].MSPType",
+			"Application.Organizations[0].Policies.Writers",
+			"Application.Organizations[0].AnchorPeers[0].Port",
+			"Application.Organizations[1].Name",
+			"Consortiums.SampleConsortium.Organizations[0].Name",
+			"Consortiums.SampleConsortium.Organizations[0].MSPDir",
+			"Consortiums.SampleConsortium.Organizations[0].Policies.Admins",
+			"Consortiums.SampleConsortium.Organizations[0].Policies.Readers",
+			"Consortiums.SampleConsortium.Organizations[0].Policies.Writers",
+		}, fields)
+
+		require.Contains(t, err.Error(), "Orderer.EtcdRaft.Consenters[2].ServerTlsCert: must be set; ")
+		require.Contains(t, err.Error(), "Application.Organizations[1].Name: duplicate organization name Org1MSP")
+	})
+
+	t.Run("Orderer types", func(t *testing.T) {
+		profile := validProfile()
+		profile.Orderer.OrdererType = "kafka"
+		profile.Orderer.Kafka.Brokers = []string{"kafka0:9092", "kafka1"}
+		require.EqualError(t, Validate(profile), "Orderer.Kafka.Brokers[1]: invalid address 'kafka1', expected host:port")
+
+		profile.Orderer.OrdererType = "BFT"
+		profile.Orderer.SmartBFT = &SmartBFTOptions{RequestBatchMaxInterval: "50"}
+		profile.Orderer.ConsenterMapping = []*Consenter{
+			{ID: 1, Host: "orderer1", Port: 7050, MSPID: "OrdererMSP", ClientTLSCert: "tls.pem", ServerTLSCert: "tls.pem", Identity: "cert.pem"},
+			{ID: 1, Host: "orderer2", Port: 7050, MSPID: "OrdererMSP", ClientTLSCert: "tls.pem", ServerTLSCert: "tls.pem"},
+		}
+		require.EqualError(t, Validate(profile), "Orderer.SmartBFT.RequestBatchMaxInterval: invalid duration '50'; "+
+			"Orderer.ConsenterMapping[1].ID: duplicate consenter ID 1; Orderer.ConsenterMapping[1].Identity: must be set")
+
+		profile.Orderer.OrdererType = "unknown"
+		require.EqualError(t, Validate(profile), "Orderer.OrdererType: unknown orderer type: unknown")
+	})
+
+	t.Run("In-memory MSP definitions", func(t *testing.T) {
+		profile := validProfile()
+		org := profile.Application.Organizations[0]
+		org.MSPDir = ""
+		org.MSP = &MSPDefinition{
+			RootCerts: [][]byte{[]byte("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n")},
+		}
+		require.NoError(t, Validate(profile))
+
+		org.MSPType = "idemix"
+		org.MSP.Admins = [][]byte{[]byte("admin.pem")}
+		require.EqualError(t, Validate(profile), "Application.Organizations[0].MSP: in-memory MSP definitions are only supported "+
+			"for MSP type bccsp; Application.Organizations[0].MSP.Admins[0]: no PEM content")
+
+		org.MSPType = "bccsp"
+		org.MSP.RootCerts = nil
+		org.MSP.Admins = nil
+		require.EqualError(t, Validate(profile), "Application.Organizations[0].MSP.RootCerts: must not be empty")
+	})
+
+	t.Run("Missing sections", func(t *testing.T) {
+		require.EqualError(t, Validate(nil), "profile is nil")
+
+		profile := validProfile()
+		profile.Orderer = nil
+		profile.Application = nil
+		require.EqualError(t, Validate(profile), "profile must define an Orderer or an Application section")
+	})
+}
+
+func validProfile() *Profile {
+	return &Profile{
+		Policies: implicitMetaPolicies(),
+		Orderer: &Orderer{
+			OrdererType:  "etcdraft",
+			Addresses:    []string{"orderer.example.com:7050"},
+			BatchTimeout: 2 * time.Second,
+			BatchSize: BatchSize{
+				MaxMessageCount:   500,
+				AbsoluteMaxBytes:  10 * 1024 * 1024,
+				PreferredMaxBytes: 2 * 1024 * 1024,
+			},
+			EtcdRaft: &etcdraft.ConfigMetadata{
+				Consenters: []*etcdraft.Consenter{
+					{Host: "orderer0", Port: 7050, ClientTlsCert: []byte("tls.pem"), ServerTlsCert: []byte("tls.pem")},
+					{Host: "orderer1", Port: 7050, ClientTlsCert: []byte("tls.pem"), ServerTlsCert: []byte("tls.pem")},
+					{Host: "orderer2", Port: 7050, ClientTlsCert: []byte("tls.pem"), ServerTlsCert: []byte("tls.pem")},
+				},
+				Options: &etcdraft.Options{
+					TickInterval:      "500ms",
+					ElectionTick:      10,
+					HeartbeatTick:     1,
+					MaxInflightBlocks: 5,
+				},
+			},
+			Organizations: []*Organization{organization("OrdererMSP")},
+			Policies:      implicitMetaPolicies(),
+		},
+		Application: &Application{
+			Organizations: []*Organization{organization("Org1MSP")},
+			Policies:      implicitMetaPolicies(),
+			ACLs:          map[string]string{"peer/Propose": "/Channel/Application/Writers"},
+		},
+	}
+}
+
+func organization(mspID string) *Organization {
+	return &Organization{
+		Name:    mspID,
+		ID:      mspID,
+		MSPDir:  "msp",
+		MSPType: "bccsp",
+		Policies: map[string]*Policy{
+			"Admins":  {Type: "Signature", Rule: "OR('" + mspID + ".admin')"},
+			"Readers": {Type: "Signature", Rule: "OR('" + mspID + ".member')"},
+			"Writers": {Type: "Signature", Rule: "OR('" + mspID + ".member')"},
+		},
+	}
+}
+
+func implicitMetaPolicies() map[string]*Policy {
+	return map[string]*Policy{
+		"Admins":  {Type: "ImplicitMeta", Rule: "MAJORITY Admins"},
+		"Readers": {Type: "ImplicitMeta", Rule: "ANY Readers"},
+		"Writers": {Type: "ImplicitMeta", Rule: "ANY Writers"},
+	}
+}
@@ -70,6 +70,7 @@ type MockConfigGroupBuilder struct {
 // MockConfigBlockBuilder is used to build a mock Chain configuration block
 type MockConfigBlockBuilder struct {
 	MockConfigGroupBuilder
+	ChannelID       string
 	Index           uint64
 	LastConfigIndex uint64
 }
@@ -157,7 +158,8 @@ func (b *MockConfigBlockBuilder) buildHeader() *common.Header {
 
 func (b *MockConfigBlockBuilder) buildChannelHeader() *common.ChannelHeader {
 	return &common.ChannelHeader{
-		Type: int32(common.HeaderType_CONFIG),
+		Type:      int32(common.HeaderType_CONFIG),
+		ChannelId: b.ChannelID,
 	}
 }
 
@@ -0,0 +1,172 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mocks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+var serialNumber int64
+
+// MockCA is a certificate authority which issues X.509 identities for a mock MSP
+type MockCA struct {
+	MSPID   string
+	Cert    *x509.Certificate
+	CertPEM []byte
+	key     *ecdsa.PrivateKey
+}
+
+// MockSigningIdentity is an X.509 identity issued by a MockCA which is able to sign messages
+type MockSigningIdentity struct {
+	MSPID   string
+	Cert    *x509.Certificate
+	CertPEM []byte
+	key     *ecdsa.PrivateKey
+}
+
+// NewMockCA creates a self-signed CA for the given MSP
+func NewMockCA(mspID string) *MockCA {
+	key := newKey()
+
+	template := &x509.Certificate{
+		SerialNumber:          nextSerialNumber(),
+		Subject:               pkix.Name{CommonName: "ca." + mspID, Organization: []string{mspID}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	cert, certPEM := createCert(template, template, &key.PublicKey, key)
+
+	return &MockCA{
+		MSPID:   mspID,
+		Cert:    cert,
+		CertPEM: certPEM,
+		key:     key,
+	}
+}
+
+// NewIdentity issues a signing identity with the given common name and organizational units
+func (ca *MockCA) NewIdentity(commonName string, ous ...string) *MockSigningIdentity {
+	key := newKey()
+
+	template := &x509.Certificate{
+		SerialNumber: nextSerialNumber(),
+		Subject:      pkix.Name{CommonName: commonName, OrganizationalUnit: ous},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	cert, certPEM := createCert(template, ca.Cert, &key.PublicKey, ca.key)
+
+	return &MockSigningIdentity{
+		MSPID:   ca.MSPID,
+		Cert:    cert,
+		CertPEM: certPEM,
+		key:     key,
+	}
+}
+
+// MSPConfig returns the config of an MSP which trusts the CA, with the given admins.
+// If nodeOUs is true, the client, peer, admin and orderer NodeOUs are enabled.
+func (ca *MockCA) MSPConfig(nodeOUs bool, admins ...*MockSigningIdentity) *msp.MSPConfig {
+	return &msp.MSPConfig{
+		Type:   0,
+		Config: marshalOrPanic(ca.FabricMSPConfig(nodeOUs, admins...)),
+	}
+}
+
+// FabricMSPConfig returns the fabric config of an MSP which trusts the CA, with the given admins.
+// If nodeOUs is true, the client, peer, admin and orderer NodeOUs are enabled.
+func (ca *MockCA) FabricMSPConfig(nodeOUs bool, admins ...*MockSigningIdentity) *msp.FabricMSPConfig {
+	config := &msp.FabricMSPConfig{
+		Name:      ca.MSPID,
+		RootCerts: [][]byte{ca.CertPEM},
+		CryptoConfig: &msp.FabricCryptoConfig{
+			SignatureHashFamily:            "SHA2",
+			IdentityIdentifierHashFunction: "SHA256",
+		},
+	}
+
+	for _, admin := range admins {
+		config.Admins = append(config.Admins, admin.CertPEM)
+	}
+
+	if nodeOUs {
+		config.FabricNodeOus = &msp.FabricNodeOUs{
+			Enable:              true,
+			ClientOuIdentifier:  &msp.FabricOUIdentifier{Certificate: ca.CertPEM, OrganizationalUnitIdentifier: "client"},
+			PeerOuIdentifier:    &msp.FabricOUIdentifier{Certificate: ca.CertPEM, OrganizationalUnitIdentifier: "peer"},
+			AdminOuIdentifier:   &msp.FabricOUIdentifier{Certificate: ca.CertPEM, OrganizationalUnitIdentifier: "admin"},
+			OrdererOuIdentifier: &msp.FabricOUIdentifier{Certificate: ca.CertPEM, OrganizationalUnitIdentifier: "orderer"},
+		}
+	}
+
+	return config
+}
+
+// Serialize returns the serialized identity
+func (id *MockSigningIdentity) Serialize() ([]byte, error) {
+	return marshalOrPanic(&msp.SerializedIdentity{Mspid: id.MSPID, IdBytes: id.CertPEM}), nil
+}
+
+// Sign signs the SHA256 hash of the message. As with Fabric signers, S is normalized to the lower half of the order.
+func (id *MockSigningIdentity) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	r, s, err := ecdsa.Sign(rand.Reader, id.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	halfOrder := new(big.Int).Rsh(id.key.Params().N, 1)
+	if s.Cmp(halfOrder) > 0 {
+		s.Sub(id.key.Params().N, s)
+	}
+
+	return asn1.Marshal(struct{ R, S *big.Int }{r, s})
+}
+
+func newKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func createCert(template, parent *x509.Certificate, pub *ecdsa.PublicKey, signer *ecdsa.PrivateKey) (*x509.Certificate, []byte) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		panic(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func nextSerialNumber() *big.Int {
+	return big.NewInt(time.Now().UnixNano() + atomic.AddInt64(&serialNumber, 1))
+}
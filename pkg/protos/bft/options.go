@@ -0,0 +1,78 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bft
+
+import (
+	"fmt"
+
+	proto "github.com/golang/protobuf/proto"
+)
+
+// Options_LeaderRotation defines whether the leader is rotated by the BFT library
+type Options_LeaderRotation int32
+
+const (
+	Options_ROTATION_UNSPECIFIED Options_LeaderRotation = 0
+	Options_ROTATION_OFF         Options_LeaderRotation = 1
+	Options_ROTATION_ON          Options_LeaderRotation = 2
+)
+
+var Options_LeaderRotation_name = map[int32]string{
+	0: "ROTATION_UNSPECIFIED",
+	1: "ROTATION_OFF",
+	2: "ROTATION_ON",
+}
+
+var Options_LeaderRotation_value = map[string]int32{
+	"ROTATION_UNSPECIFIED": 0,
+	"ROTATION_OFF":         1,
+	"ROTATION_ON":          2,
+}
+
+func (x Options_LeaderRotation) String() string {
+	if name, ok := Options_LeaderRotation_name[int32(x)]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", x)
+}
+
+// Options is serialized and set as the value of ConsensusType.Metadata in
+// a channel configuration when the ConsensusType.Type is set to "BFT".
+// It matches the Options message of orderer/smartbft/configuration.proto.
+type Options struct {
+	RequestBatchMaxCount      uint64                 `protobuf:"varint,1,opt,name=request_batch_max_count,json=requestBatchMaxCount,proto3" json:"request_batch_max_count,omitempty"`
+	RequestBatchMaxBytes      uint64                 `protobuf:"varint,2,opt,name=request_batch_max_bytes,json=requestBatchMaxBytes,proto3" json:"request_batch_max_bytes,omitempty"`
+	RequestBatchMaxInterval   string                 `protobuf:"bytes,3,opt,name=request_batch_max_interval,json=requestBatchMaxInterval,proto3" json:"request_batch_max_interval,omitempty"`
+	IncomingMessageBufferSize uint64                 `protobuf:"varint,4,opt,name=incoming_message_buffer_size,json=incomingMessageBufferSize,proto3" json:"incoming_message_buffer_size,omitempty"`
+	RequestPoolSize           uint64                 `protobuf:"varint,5,opt,name=request_pool_size,json=requestPoolSize,proto3" json:"request_pool_size,omitempty"`
+	RequestForwardTimeout     string                 `protobuf:"bytes,6,opt,name=request_forward_timeout,json=requestForwardTimeout,proto3" json:"request_forward_timeout,omitempty"`
+	RequestComplainTimeout    string                 `protobuf:"bytes,7,opt,name=request_complain_timeout,json=requestComplainTimeout,proto3" json:"request_complain_timeout,omitempty"`
+	RequestAutoRemoveTimeout  string                 `protobuf:"bytes,8,opt,name=request_auto_remove_timeout,json=requestAutoRemoveTimeout,proto3" json:"request_auto_remove_timeout,omitempty"`
+	ViewChangeResendInterval  string                 `protobuf:"bytes,9,opt,name=view_change_resend_interval,json=viewChangeResendInterval,proto3" json:"view_change_resend_interval,omitempty"`
+	ViewChangeTimeout         string                 `protobuf:"bytes,10,opt,name=view_change_timeout,json=viewChangeTimeout,proto3" json:"view_change_timeout,omitempty"`
+	LeaderHeartbeatTimeout    string                 `protobuf:"bytes,11,opt,name=leader_heartbeat_timeout,json=leaderHeartbeatTimeout,proto3" json:"leader_heartbeat_timeout,omitempty"`
+	LeaderHeartbeatCount      uint64                 `protobuf:"varint,12,opt,name=leader_heartbeat_count,json=leaderHeartbeatCount,proto3" json:"leader_heartbeat_count,omitempty"`
+	CollectTimeout            string                 `protobuf:"bytes,13,opt,name=collect_timeout,json=collectTimeout,proto3" json:"collect_timeout,omitempty"`
+	SyncOnStart               bool                   `protobuf:"varint,14,opt,name=sync_on_start,json=syncOnStart,proto3" json:"sync_on_start,omitempty"`
+	SpeedUpViewChange         bool                   `protobuf:"varint,15,opt,name=speed_up_view_change,json=speedUpViewChange,proto3" json:"speed_up_view_change,omitempty"`
+	LeaderRotation            Options_LeaderRotation `protobuf:"varint,16,opt,name=leader_rotation,json=leaderRotation,proto3,enum=fabriclibgoext.bft.Options_LeaderRotation" json:"leader_rotation,omitempty"`
+	DecisionsPerLeader        uint64                 `protobuf:"varint,17,opt,name=decisions_per_leader,json=decisionsPerLeader,proto3" json:"decisions_per_leader,omitempty"`
+	RequestMaxBytes           uint64                 `protobuf:"varint,18,opt,name=request_max_bytes,json=requestMaxBytes,proto3" json:"request_max_bytes,omitempty"`
+	RequestPoolSubmitTimeout  string                 `protobuf:"bytes,19,opt,name=request_pool_submit_timeout,json=requestPoolSubmitTimeout,proto3" json:"request_pool_submit_timeout,omitempty"`
+	XXX_NoUnkeyedLiteral      struct{}               `json:"-"`
+	XXX_unrecognized          []byte                 `json:"-"`
+	XXX_sizecache             int32                  `json:"-"`
+}
+
+func (m *Options) Reset()         { *m = Options{} }
+func (m *Options) String() string { return proto.CompactTextString(m) }
+func (*Options) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterEnum("fabriclibgoext.bft.Options_LeaderRotation", Options_LeaderRotation_name, Options_LeaderRotation_value)
+	proto.RegisterType((*Options)(nil), "fabriclibgoext.bft.Options")
+}
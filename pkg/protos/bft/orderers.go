@@ -0,0 +1,107 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package bft contains the configuration messages of BFT ordering services, which were introduced by Fabric 3.x and
+// are missing from the pinned fabric-protos-go. Field numbers and names match the upstream definitions so that the
+// messages are wire and JSON compatible with them, but they are registered under local names so that they do not
+// conflict with the upstream messages once fabric-protos-go is upgraded.
+package bft
+
+import (
+	proto "github.com/golang/protobuf/proto"
+)
+
+// Orderers is encoded into the configuration transaction as the configuration item of type Orderers.
+// It is a value of the /Channel/Orderer group for BFT ordering services.
+type Orderers struct {
+	ConsenterMapping     []*Consenter `protobuf:"bytes,1,rep,name=consenter_mapping,json=consenterMapping,proto3" json:"consenter_mapping,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Orderers) Reset()         { *m = Orderers{} }
+func (m *Orderers) String() string { return proto.CompactTextString(m) }
+func (*Orderers) ProtoMessage()    {}
+
+func (m *Orderers) GetConsenterMapping() []*Consenter {
+	if m != nil {
+		return m.ConsenterMapping
+	}
+	return nil
+}
+
+// Consenter represents a consenting node (i.e. replica).
+type Consenter struct {
+	Id                   uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Host                 string   `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	Port                 uint32   `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	MspId                string   `protobuf:"bytes,4,opt,name=msp_id,json=mspId,proto3" json:"msp_id,omitempty"`
+	Identity             []byte   `protobuf:"bytes,5,opt,name=identity,proto3" json:"identity,omitempty"`
+	ClientTlsCert        []byte   `protobuf:"bytes,6,opt,name=client_tls_cert,json=clientTlsCert,proto3" json:"client_tls_cert,omitempty"`
+	ServerTlsCert        []byte   `protobuf:"bytes,7,opt,name=server_tls_cert,json=serverTlsCert,proto3" json:"server_tls_cert,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Consenter) Reset()         { *m = Consenter{} }
+func (m *Consenter) String() string { return proto.CompactTextString(m) }
+func (*Consenter) ProtoMessage()    {}
+
+func (m *Consenter) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Consenter) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *Consenter) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *Consenter) GetMspId() string {
+	if m != nil {
+		return m.MspId
+	}
+	return ""
+}
+
+func (m *Consenter) GetIdentity() []byte {
+	if m != nil {
+		return m.Identity
+	}
+	return nil
+}
+
+func (m *Consenter) GetClientTlsCert() []byte {
+	if m != nil {
+		return m.ClientTlsCert
+	}
+	return nil
+}
+
+func (m *Consenter) GetServerTlsCert() []byte {
+	if m != nil {
+		return m.ServerTlsCert
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Orderers)(nil), "fabriclibgoext.bft.Orderers")
+	proto.RegisterType((*Consenter)(nil), "fabriclibgoext.bft.Consenter")
+}
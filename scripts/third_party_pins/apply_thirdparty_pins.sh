@@ -19,11 +19,17 @@ else
 fi
 
 scripts/third_party_pins/fabric/apply_upstream.sh
+scripts/third_party_pins/fabric/apply_patches.sh
 
 # The command above just copies a subset of Fabric files to /internal directory
 # and applies proper headers. The rest of the process, described below, is about
 # pathcing Fabric files so they compile and work locally.
 
+# Changes which add features on top of pinned files are kept as patches in
+# scripts/third_party_pins/fabric/patches, and the second command above re-applies
+# them. When a new patch is added to that directory, it must not be part of the patch
+# commit described below, so the two are not replayed twice.
+
 # The first time upstream was patched in this repo, everything had to be done
 # by hand. Each next time upstream is updated, we start by replaying the changes
 # from the last patch, and proceed by resolving any conflicts and making any other
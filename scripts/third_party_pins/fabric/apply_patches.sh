@@ -0,0 +1,21 @@
+#!/bin/bash
+#
+# Copyright SecureKey Technologies Inc. All Rights Reserved.
+#
+# SPDX-License-Identifier: Apache-2.0
+#
+
+# This script re-applies the changes this project carries on top of pinned Fabric files.
+# Each patch in the patches directory holds one change, and patches are applied in the
+# order of their file names, so a patch may build on the ones which precede it.
+# Note: A patch which no longer applies must be regenerated against the new upstream files
+
+set -e
+
+PATCHES_PATH="${PATCHES_PATH:-scripts/third_party_pins/fabric/patches}"
+
+for i in ${PATCHES_PATH}/*.patch
+do
+    echo "Applying patch $i ..."
+    git apply --3way $i
+done